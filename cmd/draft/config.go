@@ -24,7 +24,11 @@ var (
 	containerBuilder   = configKey{name: "container-builder", description: "How to build the container (supported values: docker, acrbuild)"}
 	resourceGroupName  = configKey{name: "resource-group-name", description: "The Azure resource group of the container registry (for Azure registries only)"}
 	disablePushWarning = configKey{name: "disable-push-warning", description: "Suppresses warning if no registry set"}
-	configKeys         = []configKey{registry, containerBuilder, resourceGroupName, disablePushWarning}
+	storageEngine      = configKey{name: "storage-engine", description: "Where build records are stored (supported values: configmap, secret, local)"}
+	historyKeep        = configKey{name: "history-keep", description: "Number of most recent builds kept after each draft up"}
+	historyOlderThan   = configKey{name: "history-older-than", description: "Only remove builds older than this age after each draft up (e.g. 30d)"}
+	historyPruneHelm   = configKey{name: "history-prune-helm", description: "Also remove Helm release revisions beyond history-keep after each draft up (true or false)"}
+	configKeys         = []configKey{registry, containerBuilder, resourceGroupName, disablePushWarning, storageEngine, historyKeep, historyOlderThan, historyPruneHelm}
 )
 
 // DraftConfig is the configuration stored in $DRAFT_HOME/config.toml
@@ -2,6 +2,9 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -9,18 +12,38 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/action"
 
 	"github.com/Azure/draft/pkg/draft/draftpath"
+	"github.com/Azure/draft/pkg/draft/tunnel"
+	"github.com/Azure/draft/pkg/draft/workspace"
 	"github.com/Azure/draft/pkg/local"
+	"github.com/Azure/draft/pkg/storage"
 )
 
 const (
 	connectDesc = `This command creates a local environment for you to test your app. It will give you a localhost url that you can use to see your application working and it will print out logs from your application. This command must be run in the root of your application directory.
+
+With --service, the ports of each Service of the application's release are forwarded to a pod backing the Service.
+
+With --detach, the connection keeps running in the background. Background connections are
+listed by 'draft connect list' and stopped by 'draft connect stop'.
+
+With --all, every application listed in the workspace file is connected at once. Without a workspace file, the applications of every environment given with -e are connected.
+
+Local ports are assigned once per application and reused by later connections.
+
+With --proxy, a local HTTP reverse proxy routes http://<app>.localhost:<proxy-port> to the
+first forwarded port of each application, and http://<service>.<app>.localhost:<proxy-port>
+to each Service connected with --service. These URLs stay the same when a connection is
+re-established on another local port. Requests are logged with their status and latency.
 `
 )
 
@@ -33,15 +56,39 @@ var (
 )
 
 type connectCmd struct {
-	out      io.Writer
-	logLines int64
+	out           io.Writer
+	logLines      int64
+	environments  []string
+	services      bool
+	all           bool
+	workspaceFile string
+	proxy         bool
+	proxyPort     int
+	ports         *local.PortAssignments
+	router        *local.Proxy
+}
+
+// exportedTarget is the environment and local ports of a connected target in export mode,
+// or the error connecting to it. Every target is exported once.
+type exportedTarget struct {
+	target *connectTarget
+	env    map[string]string
+	ports  []string
+	err    error
+}
+
+// connectTarget is a single connection to an application pod, or to a pod backing one
+// of the application's Services.
+type connectTarget struct {
+	app         *local.App
+	environment string
+	service     string
+	kubeContext string
+	out         io.Writer
 }
 
 func newConnectCmd(out io.Writer) *cobra.Command {
-	var (
-		cc                 = &connectCmd{out: out}
-		runningEnvironment string
-	)
+	cc := &connectCmd{out: out}
 
 	cmd := &cobra.Command{
 		Use:   "connect",
@@ -51,127 +98,473 @@ func newConnectCmd(out io.Writer) *cobra.Command {
 			if detach {
 				return cc.detach()
 			}
-			return cc.run(runningEnvironment)
+			return cc.run()
 		},
 	}
+	cmd.AddCommand(
+		newConnectListCmd(out),
+		newConnectStopCmd(out),
+	)
 
 	f := cmd.Flags()
 	f.Int64Var(&cc.logLines, "tail", 5, "lines of recent log lines to display")
-	f.StringVarP(&runningEnvironment, environmentFlagName, environmentFlagShorthand, defaultDraftEnvironment(), environmentFlagUsage)
+	f.StringSliceVarP(&cc.environments, environmentFlagName, environmentFlagShorthand, []string{defaultDraftEnvironment()}, environmentFlagUsage)
 	f.StringVarP(&targetContainer, "container", "c", "", "name of the container to connect to")
 	f.StringSliceVarP(&overridePorts, "override-port", "p", []string{}, "specify a local port to connect to, in the form <local>:<remote>")
 	f.BoolVarP(&dryRun, "dry-run", "", false, "when this flag is used, draft connect will wait to find a ready pod then exit")
 	f.BoolVarP(&detach, "detach", "", false, "detach from the connection while preserving the tunnel")
+	f.BoolVar(&cc.services, "service", false, "connect to the Services of the release instead of the application pod")
+	f.BoolVar(&cc.all, "all", false, "connect every application of the workspace file, or of every environment given with -e")
+	f.StringVar(&cc.workspaceFile, "workspace", workspace.DefaultFilename, "path to the workspace file used by --all")
+	f.BoolVar(&cc.proxy, "proxy", false, "route <app>.localhost to the connected applications with a local HTTP reverse proxy")
+	f.IntVar(&cc.proxyPort, "proxy-port", 8888, "local port of the reverse proxy started by --proxy")
 	f.BoolVarP(&export, "export", "", false, "export connection environment in detached state (hidden)")
 	f.MarkHidden("export")
 
 	return cmd
 }
 
-func (cn *connectCmd) run(runningEnvironment string) (err error) {
-	deployedApp, err := local.DeployedApplication(draftToml, runningEnvironment)
+func (cn *connectCmd) run() (err error) {
+	if cn.ports, err = local.LoadPortAssignments(draftpath.Home(homePath()).Ports()); err != nil {
+		return fmt.Errorf("could not read assigned ports: %v", err)
+	}
+	targets, err := cn.targets()
 	if err != nil {
 		return err
 	}
+	if len(targets) == 0 {
+		return errors.New("nothing to connect to")
+	}
 
-	client, config, err := getKubeClient(kubeContext)
-	if err != nil {
-		return err
+	if cn.proxy && !dryRun {
+		var log io.Writer = &prefixWriter{mu: &sync.Mutex{}, out: cn.out, prefix: "[proxy] "}
+		if export {
+			log = ioutil.Discard
+		}
+		cn.router = local.NewProxy(log)
+		if err := cn.router.Listen(cn.proxyPort); err != nil {
+			return err
+		}
+		defer cn.router.Close()
 	}
 
-	var ports []string
-	if len(deployedApp.OverridePorts) != 0 {
-		ports = deployedApp.OverridePorts
+	stop := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		close(done)
+	}()
+
+	// in export mode, the environment of all connections is printed at once and the
+	// connection is recorded in the registry of detached connections.
+	exported := make(chan exportedTarget, len(targets))
+	if export {
+		registry := local.NewRegistry(draftpath.Home(homePath()).Connections())
+		defer registry.Unregister(os.Getpid())
+		go func() {
+			exportEnv := make(map[string]string)
+			detached := &local.DetachedConnection{PID: os.Getpid(), Started: time.Now()}
+			if start, err := processStartTime(detached.PID); err == nil {
+				detached.ProcessStart = start
+			} else {
+				debug("could not read the start time of the connection: %v", err)
+			}
+			var failed []exportedTarget
+			for range targets {
+				e := <-exported
+				if e.err != nil {
+					failed = append(failed, e)
+					continue
+				}
+				for k, v := range e.env {
+					exportEnv[k] = v
+				}
+				detached.Apps = append(detached.Apps, local.DetachedApp{
+					Name:        e.target.app.Name,
+					Namespace:   e.target.app.Namespace,
+					Environment: e.target.environment,
+					Service:     e.target.service,
+					Ports:       e.ports,
+				})
+			}
+			if len(detached.Apps) > 0 {
+				if err := registry.Register(detached); err != nil {
+					debug("could not register detached connection: %v", err)
+				}
+			}
+			exportConnectEnv(exportEnv)
+			// the parent process only reads stdout, where a comment is valid in every shell.
+			for _, e := range failed {
+				fmt.Fprintf(os.Stdout, "# could not connect to %s: %v\n", e.target.name(), e.err)
+			}
+			os.Stdout.Close()
+		}()
 	}
 
-	// --override-port takes precedence
-	if len(overridePorts) != 0 {
-		ports = overridePorts
+	if len(targets) == 1 {
+		return cn.connect(targets[0], done, exported)
 	}
 
-	buildID, err := getLatestBuildID(deployedApp.Name)
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []string
+	)
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t *connectTarget) {
+			defer wg.Done()
+			if err := cn.connect(t, done, exported); err != nil {
+				fmt.Fprintf(t.out, "Error: %v\n", err)
+				mu.Lock()
+				failed = append(failed, t.name())
+				mu.Unlock()
+			}
+		}(t)
+	}
+	wg.Wait()
+	if len(failed) > 0 {
+		return fmt.Errorf("could not connect to %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// targets returns the connections to establish.
+func (cn *connectCmd) targets() ([]*connectTarget, error) {
+	type appRef struct{ draftToml, environment string }
+	var refs []appRef
+	if cn.all {
+		w, err := workspace.Load(cn.workspaceFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			for _, app := range w.Apps {
+				env := app.Environment
+				if env == "" {
+					env = cn.environments[0]
+				}
+				refs = append(refs, appRef{app.DraftToml(), env})
+			}
+		} else if len(cn.environments) < 2 {
+			return nil, fmt.Errorf("--all requires a workspace file (%s not found) or several environments given with -e", cn.workspaceFile)
+		}
+	}
+	if len(refs) == 0 {
+		for _, env := range cn.environments {
+			refs = append(refs, appRef{draftToml, env})
+		}
+	}
+
+	var targets []*connectTarget
+	for _, ref := range refs {
+		app, err := local.DeployedApplication(ref.draftToml, ref.environment)
+		if err != nil {
+			return nil, err
+		}
+		kctx, err := resolveKubeContext(ref.environment, app.KubeContexts)
+		if err != nil {
+			return nil, err
+		}
+		target := &connectTarget{app: app, environment: ref.environment, kubeContext: kctx}
+		if !cn.services {
+			targets = append(targets, target)
+			continue
+		}
+		services, err := releaseServices(app, kctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, svc := range services {
+			t := *target
+			t.service = svc
+			targets = append(targets, &t)
+		}
+	}
+
+	// prefix the output of each connection when connecting to several targets at once.
+	var mu sync.Mutex
+	for _, t := range targets {
+		t.out = cn.out
+		if len(targets) > 1 {
+			t.out = &prefixWriter{mu: &mu, out: cn.out, prefix: fmt.Sprintf("[%s] ", t.name())}
+		}
+	}
+	return targets, nil
+}
+
+func (t *connectTarget) name() string {
+	if t.service != "" {
+		return t.app.Name + "/" + t.service
+	}
+	return t.app.Name
+}
+
+// releaseServices returns the names of the Services in the application's release.
+func releaseServices(app *local.App, kubeContext string) ([]string, error) {
+	cfg, err := getHelmConfig(kubeContext, app.Namespace)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	rls, err := action.NewGet(cfg).Run(app.Name)
+	if err != nil {
+		return nil, fmt.Errorf("could not get release %q: %v", app.Name, err)
 	}
+	services := releaseResources(rls.Manifest)["Service"]
+	if len(services) == 0 {
+		return nil, fmt.Errorf("release %q has no Services", app.Name)
+	}
+	return services, nil
+}
+
+// connect connects to the target until the user interrupts the connection. Whenever the
+// connected pod goes away, e.g. after a restart or a rollout of a new build, a new pod
+// is connected on the same local ports.
+func (cn *connectCmd) connect(t *connectTarget, done <-chan struct{}, exported chan<- exportedTarget) (err error) {
+	// in export mode, a target which fails before its connection is exported is exported
+	// with the error, so the environment of the other targets is still printed.
+	sent := false
+	exportTarget := func(e exportedTarget) {
+		sent = true
+		exported <- e
+	}
+	defer func() {
+		if export && err != nil && !sent {
+			exported <- exportedTarget{target: t, err: err}
+		}
+	}()
 
-	connection, err := deployedApp.Connect(client, config, targetContainer, ports, buildID)
+	client, config, err := getKubeClient(t.kubeContext)
 	if err != nil {
 		return err
 	}
 
-	if dryRun {
-		return
+	// --override-port takes precedence over the ports in draft.toml, which take
+	// precedence over the ports assigned to earlier connections.
+	ports := cn.ports.Get(t.app, t.service)
+	assigned := len(ports) != 0
+	if len(t.app.OverridePorts) != 0 && t.service == "" {
+		ports, assigned = t.app.OverridePorts, false
+	}
+	if len(overridePorts) != 0 {
+		ports, assigned = overridePorts, false
+	}
+
+	var buildID string
+	if t.service == "" {
+		if buildID, err = latestBuildID(t.app, t.environment); err != nil {
+			return err
+		}
 	}
 
+	for reconnect := false; ; reconnect = true {
+		var connection *local.Connection
+		if t.service != "" {
+			connection, err = t.app.ConnectService(client, config, t.service, ports)
+		} else {
+			connection, err = t.app.Connect(client, config, targetContainer, ports, buildID)
+		}
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			return nil
+		}
+		lost, err := cn.serve(t, connection, done, exportTarget, reconnect, assigned)
+		if err != nil || !lost {
+			return err
+		}
+		if !export {
+			fmt.Fprintf(t.out, "Lost connection to pod %s, reconnecting...\n", connection.PodName)
+		}
+		// keep the local ports of the previous connection.
+		ports, assigned = connection.PortMappings(), false
+		if t.service == "" {
+			// the new pod may come from a build made elsewhere, so the storage engine is
+			// asked first, then the builds made on this machine.
+			if latest, err := storedLatestBuildID(t.app, t.environment); err == nil {
+				buildID = latest
+			} else if latest, err := getLatestBuildID(t.app.Name); err == nil {
+				buildID = latest
+			}
+		}
+	}
+}
+
+// serve forwards the ports of a connection and streams the logs of its containers until
+// the user interrupts the connection or the connection's pod goes away, in which case
+// lost is true.
+func (cn *connectCmd) serve(t *connectTarget, connection *local.Connection, done <-chan struct{}, exportTarget func(exportedTarget), reconnect, assigned bool) (lost bool, err error) {
 	var connectionMessage = "Your connection is still active.\n"
 
 	exportEnv := make(map[string]string)
 
+	if reconnect && !export {
+		fmt.Fprintf(t.out, "Reconnected to pod %s\n", connection.PodName)
+	}
+
 	// output all local ports first - easier to spot
+	routed := false
 	for _, cc := range connection.ContainerConnections {
-		for _, t := range cc.Tunnels {
-			if err = t.ForwardPort(); err != nil {
-				return err
+		for _, tun := range cc.Tunnels {
+			if err = forwardPort(tun, reconnect, assigned); err != nil {
+				return false, err
+			}
+			defer tun.Close()
+			// the proxy routes to the first forwarded port.
+			var proxyURL string
+			if cn.router != nil && !routed {
+				host := local.ProxyHostname(t.app.Name, t.service)
+				cn.router.Route(host, tun.Local)
+				proxyURL = fmt.Sprintf("http://%s:%d", host, cn.proxyPort)
+				routed = true
 			}
 			if export {
 				var (
-					application = sanitize(deployedApp.Name)
+					application = sanitize(t.app.Name)
 					container   = sanitize(cc.ContainerName)
 					prefix      = fmt.Sprintf("%s_%s", application, container)
 				)
+				if t.service != "" {
+					prefix = fmt.Sprintf("%s_%s", application, sanitize(t.service))
+				}
 				exportEnv[prefix+"_SERVICE_HOST"] = fmt.Sprintf("localhost")
-				exportEnv[prefix+"_SERVICE_PORT"] = fmt.Sprintf("%#v", t.Local)
+				exportEnv[prefix+"_SERVICE_PORT"] = fmt.Sprintf("%#v", tun.Local)
+				if proxyURL != "" {
+					exportEnv[prefix+"_PROXY_URL"] = proxyURL
+				}
 			} else {
-				m := fmt.Sprintf("Connect to %v:%v on localhost:%#v\n", cc.ContainerName, t.Remote, t.Local)
+				target := cc.ContainerName
+				if t.service != "" {
+					target = fmt.Sprintf("service %s (%s)", t.service, cc.ContainerName)
+				}
+				m := fmt.Sprintf("Connect to %v:%v on localhost:%#v\n", target, tun.Remote, tun.Local)
+				if proxyURL != "" {
+					m = fmt.Sprintf("Connect to %v:%v on localhost:%#v or %s\n", target, tun.Remote, tun.Local, proxyURL)
+				}
 				connectionMessage += m
-				fmt.Fprintf(cn.out, m)
+				fmt.Fprintf(t.out, m)
 			}
 		}
 	}
+	if err := cn.ports.Set(t.app, t.service, connection.PortMappings()); err != nil {
+		debug("could not save assigned ports: %v", err)
+	}
 
-	stop := make(chan os.Signal, 1)
-	done := make(chan struct{})
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-stop
-		close(done)
-	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lostC, err := connection.Lost(ctx, t.app.Namespace)
+	if err != nil {
+		return false, err
+	}
 
 	if export {
-		exportConnectEnv(exportEnv)
-		os.Stdout.Close()
-		<-done
-		return nil
+		if !reconnect {
+			exportTarget(exportedTarget{target: t, env: exportEnv, ports: connection.PortMappings()})
+		}
+		select {
+		case <-done:
+			return false, nil
+		case <-lostC:
+			return true, nil
+		}
 	}
 
 	for _, cc := range connection.ContainerConnections {
-		readCloser, err := connection.RequestLogStream(deployedApp.Namespace, cc.ContainerName, cn.logLines)
+		readCloser, err := connection.RequestLogStream(t.app.Namespace, cc.ContainerName, cn.logLines)
 		if err != nil {
-			return err
+			return false, err
 		}
 		defer readCloser.Close()
-		go writeContainerLogs(cn.out, readCloser, cc.ContainerName)
+		go writeContainerLogs(t.out, readCloser, cc.ContainerName)
 	}
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
-			fmt.Fprintf(cn.out, connectionMessage)
+			fmt.Fprintf(t.out, connectionMessage)
+		case <-lostC:
+			return true, nil
 		case <-done:
+			return false, nil
+		}
+	}
+}
+
+// forwardPort opens a tunnel. When reconnecting, the local port may still be held by
+// the previous tunnel for a moment, so opening the tunnel is retried. A local port
+// assigned to an earlier connection is given up if it is taken by another process.
+func forwardPort(t *tunnel.Tunnel, reconnect, assigned bool) (err error) {
+	attempts := 1
+	if reconnect {
+		attempts = 10
+	}
+	for i := 0; i < attempts; i++ {
+		if err = t.ForwardPort(); err == nil {
 			return nil
 		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	if assigned && !reconnect {
+		t.Local = 0
+		return t.ForwardPort()
+	}
+	return err
+}
+
+// prefixWriter prefixes every line written to it, so the output of concurrent
+// connections can be told apart. Writers sharing mu never interleave lines.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if _, err := fmt.Fprintf(w.out, "%s%s", w.prefix, w.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
 	}
 }
 
 func (cn *connectCmd) detach() error {
 	args := []string{"connect", "--export"}
+	for _, env := range cn.environments {
+		args = append(args, "-e", env)
+	}
+	if kubeContext != "" {
+		args = append(args, "--kube-context", kubeContext)
+	}
+	if kubeConfig != "" {
+		args = append(args, "--kubeconfig", kubeConfig)
+	}
 	for _, port := range overridePorts {
 		args = append(args, "-p", port)
 	}
 	if targetContainer != "" {
 		args = append(args, "-c", targetContainer)
 	}
+	if cn.services {
+		args = append(args, "--service")
+	}
+	if cn.all {
+		args = append(args, "--all", "--workspace", cn.workspaceFile)
+	}
+	if cn.proxy {
+		args = append(args, "--proxy", "--proxy-port", strconv.Itoa(cn.proxyPort))
+	}
 	cmd := exec.Command(os.Args[0], args...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -215,3 +608,34 @@ func getLatestBuildID(appName string) (string, error) {
 }
 
 func sanitize(name string) string { return strings.Replace(strings.ToUpper(name), "-", "_", -1) }
+
+// latestBuildID returns the ID of the latest build of the application, looking up the
+// build records in the storage engine if the application was never built on this machine.
+func latestBuildID(app *local.App, environment string) (string, error) {
+	if buildID, err := getLatestBuildID(app.Name); err == nil {
+		return buildID, nil
+	}
+	return storedLatestBuildID(app, environment)
+}
+
+// storedLatestBuildID returns the ID of the latest build of the application recorded in the
+// storage engine of its kube context.
+func storedLatestBuildID(app *local.App, environment string) (string, error) {
+	kctx, err := resolveKubeContext(environment, app.KubeContexts)
+	if err != nil {
+		return "", err
+	}
+	store, err := newStore("", kctx, storageNamespace(app.StorageNamespace, app.Namespace))
+	if err != nil {
+		return "", err
+	}
+	builds, err := store.GetBuilds(context.Background(), app.Name)
+	if err != nil {
+		return "", err
+	}
+	if len(builds) == 0 {
+		return "", fmt.Errorf("could not find the latest build ID of your application. Try `draft up` first")
+	}
+	storage.SortByCreatedAt(builds)
+	return builds[len(builds)-1].GetBuildID(), nil
+}
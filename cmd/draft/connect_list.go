@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/draft/pkg/draft/draftpath"
+	"github.com/Azure/draft/pkg/local"
+)
+
+const connectListDesc = `List the connections running in the background, started with 'draft connect --detach'.
+
+Connections whose process is gone are removed from the list.`
+
+type connectListCmd struct {
+	out io.Writer
+}
+
+func newConnectListCmd(out io.Writer) *cobra.Command {
+	lc := &connectListCmd{out: out}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list connections running in the background",
+		Long:  connectListDesc,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return lc.run()
+		},
+	}
+	return cmd
+}
+
+func (lc *connectListCmd) run() error {
+	connections, err := detachedConnections()
+	if err != nil {
+		return err
+	}
+	if len(connections) == 0 {
+		fmt.Fprintln(lc.out, "No connections are running in the background.")
+		return nil
+	}
+	fmt.Fprintln(lc.out, formatConnections(connections, time.Now()))
+	return nil
+}
+
+// detachedConnections returns the detached connections that are still running.
+func detachedConnections() ([]*local.DetachedConnection, error) {
+	registry := local.NewRegistry(draftpath.Home(homePath()).Connections())
+	connections, err := registry.List(connectionAlive)
+	if err != nil {
+		return nil, fmt.Errorf("could not read detached connections: %v", err)
+	}
+	return connections, nil
+}
+
+// connectionAlive returns whether the process of a detached connection is still running.
+// A running process with the same PID which started at another time reused the PID of the
+// connection. Connections registered without a start time are identified by PID only.
+// When the start time of the process cannot be read, the connection is taken for dead, so
+// that no other process is signalled.
+func connectionAlive(c *local.DetachedConnection) bool {
+	if !processAlive(c.PID) {
+		return false
+	}
+	if c.ProcessStart == "" {
+		return true
+	}
+	start, err := processStartTime(c.PID)
+	return err == nil && start == c.ProcessStart
+}
+
+func formatConnections(connections []*local.DetachedConnection, now time.Time) string {
+	table := uitable.New()
+	table.AddRow("PID", "APP", "ENVIRONMENT", "PORTS", "STARTED")
+	for _, c := range connections {
+		for _, app := range c.Apps {
+			name := app.Name
+			if app.Service != "" {
+				name = fmt.Sprintf("%s (service %s)", app.Name, app.Service)
+			}
+			table.AddRow(c.PID, name, app.Environment, strings.Join(app.Ports, ", "), fmt.Sprintf("%s ago", now.Sub(c.Started).Round(time.Second)))
+		}
+	}
+	return table.String()
+}
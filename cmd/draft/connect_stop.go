@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/draft/pkg/draft/draftpath"
+	"github.com/Azure/draft/pkg/local"
+)
+
+const connectStopDesc = `Stop the connections running in the background, started with 'draft connect --detach'.
+
+Without an argument, every connection is stopped. With the name of an application, only the
+connections to that application are stopped.`
+
+// connectStopTimeout is how long draft connect stop waits for a connection to shut down.
+const connectStopTimeout = 5 * time.Second
+
+type connectStopCmd struct {
+	out io.Writer
+	app string
+}
+
+func newConnectStopCmd(out io.Writer) *cobra.Command {
+	sc := &connectStopCmd{out: out}
+	cmd := &cobra.Command{
+		Use:   "stop [app]",
+		Short: "stop connections running in the background",
+		Long:  connectStopDesc,
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				sc.app = args[0]
+			}
+			return sc.run()
+		},
+	}
+	return cmd
+}
+
+func (sc *connectStopCmd) run() error {
+	connections, err := detachedConnections()
+	if err != nil {
+		return err
+	}
+	registry := local.NewRegistry(draftpath.Home(homePath()).Connections())
+	stopped := 0
+	for _, c := range connections {
+		if sc.app != "" && !c.HasApp(sc.app) {
+			continue
+		}
+		// the process is identified again right before it is signalled, as it may have
+		// exited and its PID been reused since the connections were listed.
+		if connectionAlive(c) {
+			if err := stopProcess(c.PID); err != nil {
+				return fmt.Errorf("could not stop connection %d: %v", c.PID, err)
+			}
+		}
+		if !waitForExit(c, connectStopTimeout) {
+			return fmt.Errorf("connection %d did not stop within %s", c.PID, connectStopTimeout)
+		}
+		// the connection unregisters itself on shutdown; make sure it is gone if it could not.
+		if err := registry.Unregister(c.PID); err != nil {
+			return err
+		}
+		fmt.Fprintf(sc.out, "Stopped connection %d\n", c.PID)
+		stopped++
+	}
+	if stopped == 0 {
+		if sc.app != "" {
+			fmt.Fprintf(sc.out, "No connections to %s are running in the background.\n", sc.app)
+		} else {
+			fmt.Fprintln(sc.out, "No connections are running in the background.")
+		}
+	}
+	return nil
+}
+
+// waitForExit waits until the process of the connection has exited, or the timeout expires.
+func waitForExit(c *local.DetachedConnection, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for connectionAlive(c) {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/draft/pkg/local"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var (
+		out bytes.Buffer
+		mu  sync.Mutex
+	)
+	api := &prefixWriter{mu: &mu, out: &out, prefix: "[api] "}
+	auth := &prefixWriter{mu: &mu, out: &out, prefix: "[auth] "}
+
+	api.Write([]byte("Connect to api:8080 "))
+	auth.Write([]byte("Connect to auth:9000 on localhost:9000\n"))
+	api.Write([]byte("on localhost:8080\n[api]: listening\n"))
+
+	expected := "[auth] Connect to auth:9000 on localhost:9000\n[api] Connect to api:8080 on localhost:8080\n[api] [api]: listening\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestFormatConnections(t *testing.T) {
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	connections := []*local.DetachedConnection{
+		{
+			PID:     100,
+			Started: now.Add(-90 * time.Second),
+			Apps: []local.DetachedApp{
+				{Name: "api", Environment: "development", Ports: []string{"8080:8080"}},
+				{Name: "auth", Environment: "staging", Service: "auth-svc", Ports: []string{"9000:80", "9001:443"}},
+			},
+		},
+	}
+
+	lines := strings.Split(formatConnections(connections, now), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %q", lines)
+	}
+	for i, want := range [][]string{
+		{"PID", "APP", "ENVIRONMENT", "PORTS", "STARTED"},
+		{"100", "api", "development", "8080:8080", "1m30s ago"},
+		{"100", "auth (service auth-svc)", "staging", "9000:80, 9001:443", "1m30s ago"},
+	} {
+		for _, field := range want {
+			if !strings.Contains(lines[i], field) {
+				t.Errorf("expected row %d %q to contain %q", i, lines[i], field)
+			}
+		}
+	}
+}
+
+func TestConnectionAlive(t *testing.T) {
+	start, err := processStartTime(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start == "" {
+		t.Fatal("expected the start time of the process")
+	}
+	for _, tc := range []struct {
+		processStart string
+		alive        bool
+	}{
+		{start, true},
+		{"", true},
+		{"reused", false},
+	} {
+		c := &local.DetachedConnection{PID: os.Getpid(), ProcessStart: tc.processStart}
+		if alive := connectionAlive(c); alive != tc.alive {
+			t.Errorf("expected a connection started at %q to be alive: %v, got %v", tc.processStart, tc.alive, alive)
+		}
+	}
+}
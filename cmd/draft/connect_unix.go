@@ -4,7 +4,12 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
 )
 
 func exportConnectEnv(exportEnv map[string]string) {
@@ -12,3 +17,34 @@ func exportConnectEnv(exportEnv map[string]string) {
 		fmt.Fprintf(os.Stdout, " export %s=%q\n", envVar, envVal)
 	}
 }
+
+// processAlive returns whether the process with the given PID is still running.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// stopProcess asks the process with the given PID to terminate.
+func stopProcess(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
+
+// processStartTime returns the start time of the process with the given PID, in the
+// format of the operating system. It is read from /proc where available, and from ps.
+func processStartTime(pid int) (string, error) {
+	if b, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid)); err == nil {
+		// the start time is the 22nd field; the 2nd field, the command, may hold spaces
+		// and is enclosed in parentheses.
+		stat := string(b)
+		fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+		if len(fields) > 19 {
+			return fields[19], nil
+		}
+		return "", fmt.Errorf("unexpected format of /proc/%d/stat", pid)
+	}
+	out, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
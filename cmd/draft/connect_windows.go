@@ -5,10 +5,53 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"syscall"
 )
 
+const processQueryLimitedInformation = 0x1000
+
 func exportConnectEnv(exportEnv map[string]string) {
 	for envVar, envVal := range exportEnv {
 		fmt.Fprintf(os.Stdout, "$env:%s=%q\n", envVar, envVal)
 	}
 }
+
+// processAlive returns whether the process with the given PID is still running.
+func processAlive(pid int) bool {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	// STILL_ACTIVE
+	return code == 259
+}
+
+// stopProcess terminates the process with the given PID. Windows has no equivalent of
+// SIGTERM for console processes, so the process is killed.
+func stopProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
+
+// processStartTime returns the creation time of the process with the given PID.
+func processStartTime(pid int) (string, error) {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return "", err
+	}
+	defer syscall.CloseHandle(h)
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(h, &creation, &exit, &kernel, &user); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(creation.Nanoseconds(), 10), nil
+}
@@ -7,13 +7,16 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"unicode"
 
 	"github.com/BurntSushi/toml"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/term"
 
+	"github.com/Azure/draft/pkg/detect"
 	"github.com/Azure/draft/pkg/draft/draftpath"
 	"github.com/Azure/draft/pkg/draft/manifest"
 	"github.com/Azure/draft/pkg/draft/pack"
@@ -25,6 +28,16 @@ import (
 const (
 	draftToml  = "draft.toml"
 	createDesc = `This command transforms the local directory to be deployable via 'draft up'.
+
+When stdin is a terminal, or with --interactive, draft create shows the detected languages
+and asks which pack to use, then asks for the name of the application, the values of the
+placeholders of the pack such as the port, the registry, the namespace and the environments
+to generate, and lists the files it will write before writing them. Use --interactive=false
+to create the application without questions.
+
+With --scan, the subdirectories holding an application, identified by a Dockerfile or a
+manifest such as go.mod or package.json, are each transformed into a Draft application, and
+a draft-workspace.toml file listing them is written so 'draft up --all' deploys them together.
 `
 )
 
@@ -35,15 +48,19 @@ var ErrNoLanguageDetected = errors.New("no languages were detected")
 type createCmd struct {
 	appName        string
 	out            io.Writer
+	in             io.Reader
 	pack           string
 	home           draftpath.Home
 	dest           string
 	repositoryName string
+	scanApps       bool
+	interactive    bool
 }
 
-func newCreateCmd(out io.Writer) *cobra.Command {
+func newCreateCmd(out io.Writer, in io.Reader) *cobra.Command {
 	cc := &createCmd{
 		out: out,
+		in:  in,
 	}
 
 	cmd := &cobra.Command{
@@ -54,6 +71,9 @@ func newCreateCmd(out io.Writer) *cobra.Command {
 			if len(args) > 0 {
 				cc.dest = args[0]
 			}
+			if !cmd.Flags().Changed("interactive") {
+				cc.interactive = !cc.scanApps && term.IsTerminal(cc.in)
+			}
 			return cc.run()
 		},
 		PreRun: func(cmd *cobra.Command, args []string) {
@@ -67,11 +87,17 @@ func newCreateCmd(out io.Writer) *cobra.Command {
 	f := cmd.Flags()
 	f.StringVarP(&cc.appName, "app", "a", "", "name of the Helm release. By default, this is a randomly generated name")
 	f.StringVarP(&cc.pack, "pack", "p", "", "the named Draft starter pack to scaffold the app with")
+	f.BoolVar(&cc.scanApps, "scan", false, "scaffold every application found in the subdirectories and write a workspace file listing them")
+	f.BoolVarP(&cc.interactive, "interactive", "i", false, "ask which pack to use and how to configure the application (default: true if stdin is a terminal)")
 
 	return cmd
 }
 
 func (c *createCmd) run() error {
+	if c.scanApps {
+		return c.scan()
+	}
+
 	var err error
 	mfest := manifest.New()
 
@@ -90,6 +116,10 @@ func (c *createCmd) run() error {
 		return nil
 	}
 
+	if c.interactive {
+		return c.runInteractive(mfest)
+	}
+
 	if c.pack != "" {
 		// --pack was explicitly defined, so we can just lazily use that here. No detection required.
 		packsFound, err := pack.Find(c.home.Packs(), c.pack)
@@ -101,7 +131,14 @@ func (c *createCmd) run() error {
 
 		} else if len(packsFound) == 1 {
 			packSrc := packsFound[0]
-			if err = pack.CreateFrom(c.dest, packSrc, c.appName); err != nil {
+			apps, err := detect.Dir(c.destDir())
+			if err != nil {
+				fmt.Fprintf(c.out, "--> Could not detect the framework of the application: %v\n", err)
+			}
+			// the runtime version and start command of an application in another language
+			// than the pack's do not apply; only the port is detected then.
+			app := detect.ForPack(apps, filepath.Base(packSrc))
+			if err = pack.CreateFrom(c.dest, packSrc, c.appName, placeholderValues(c.destDir(), app)); err != nil {
 				return err
 			}
 
@@ -111,15 +148,21 @@ func (c *createCmd) run() error {
 
 	} else {
 		// pack detection time
-		packPath, err := doPackDetection(c.home, c.out)
+		packPath, app, err := doPackDetection(c.home, c.destDir(), c.out)
 		if err != nil {
 			return err
 		}
-		err = pack.CreateFrom(c.dest, packPath, c.appName)
+		err = pack.CreateFrom(c.dest, packPath, c.appName, placeholderValues(c.destDir(), app))
 		if err != nil {
 			return err
 		}
 	}
+	return c.writeManifest(mfest)
+}
+
+// writeManifest writes the draft.toml of the application, and a default .draftignore if it
+// has none.
+func (c *createCmd) writeManifest(mfest *manifest.Manifest) error {
 	tomlFile := filepath.Join(c.dest, draftToml)
 	draftToml, err := os.OpenFile(tomlFile, os.O_RDWR|os.O_CREATE, 0644)
 	if err != nil {
@@ -143,6 +186,14 @@ func (c *createCmd) run() error {
 	return nil
 }
 
+// destDir returns the directory of the application, defaulting to the current directory.
+func (c *createCmd) destDir() string {
+	if c.dest == "" {
+		return "."
+	}
+	return c.dest
+}
+
 func (c *createCmd) normalizeApplicationName() {
 	if c.appName == "" {
 		return
@@ -171,39 +222,99 @@ func (c *createCmd) normalizeApplicationName() {
 }
 
 // doPackDetection performs pack detection across all the packs available in $(draft home)/packs in
-// alphabetical order, returning the pack dirpath and any errors that occurred during the pack detection.
-func doPackDetection(home draftpath.Home, out io.Writer) (string, error) {
-	langs, err := linguist.ProcessDir(".")
-	log.Debugf("linguist.ProcessDir('.') result:\n\nError: %v", err)
+// alphabetical order, returning the pack dirpath, the application detected for the language of the
+// pack, if any, and any errors that occurred during the pack detection.
+//
+// When the framework of the application is detected, the pack variant for the framework, such as
+// python-django, is preferred over the pack for the language.
+func doPackDetection(home draftpath.Home, dir string, out io.Writer) (string, *detect.App, error) {
+	langs, err := linguist.ProcessDir(dir)
+	log.Debugf("linguist.ProcessDir(%q) result:\n\nError: %v", dir, err)
 	if err != nil {
-		return "", fmt.Errorf("there was an error detecting the language: %s", err)
+		return "", nil, fmt.Errorf("there was an error detecting the language: %s", err)
 	}
 	for _, lang := range langs {
 		log.Debugf("%s:\t%f (%s)", lang.Language, lang.Percent, lang.Color)
 	}
 	if len(langs) == 0 {
-		return "", ErrNoLanguageDetected
+		return "", nil, ErrNoLanguageDetected
+	}
+	apps, err := detect.Dir(dir)
+	if err != nil {
+		fmt.Fprintf(out, "--> Could not detect the framework of the application: %v\n", err)
 	}
 	for _, lang := range langs {
 		detectedLang := linguist.Alias(lang)
 		fmt.Fprintf(out, "--> Draft detected %s (%f%%)\n", detectedLang.Language, detectedLang.Percent)
-		for _, repository := range repo.FindRepositories(home.Packs()) {
-			packDir := filepath.Join(repository.Dir, repo.PackDirName)
-			packs, err := ioutil.ReadDir(packDir)
+		names, app := packCandidates(detectedLang, apps)
+		if app != nil && app.Framework != "" {
+			fmt.Fprintf(out, "--> Draft detected the %s framework from %s\n", app.Framework, app.Manifest)
+		}
+		for _, name := range names {
+			packPath, err := findPack(home, name)
 			if err != nil {
-				return "", fmt.Errorf("there was an error reading %s: %v", packDir, err)
+				return "", nil, err
 			}
-			for _, file := range packs {
-				if file.IsDir() {
-					if strings.Compare(strings.ToLower(detectedLang.Language), strings.ToLower(file.Name())) == 0 {
-						packPath := filepath.Join(packDir, file.Name())
-						log.Debugf("pack path: %s", packPath)
-						return packPath, nil
-					}
-				}
+			if packPath != "" {
+				log.Debugf("pack path: %s", packPath)
+				return packPath, app, nil
 			}
 		}
 		fmt.Fprintf(out, "--> Could not find a pack for %s. Trying to find the next likely language match...\n", detectedLang.Language)
 	}
-	return "", ErrNoLanguageDetected
+	return "", nil, ErrNoLanguageDetected
+}
+
+// packCandidates returns the names of the packs suited to a language detected by linguist, most
+// specific first, along with the application detected for the language, if any.
+func packCandidates(lang *linguist.Language, apps []*detect.App) ([]string, *detect.App) {
+	if app := detect.ForLanguage(apps, lang.Language); app != nil {
+		return app.PackNames(), app
+	}
+	return []string{strings.ToLower(lang.Language)}, nil
+}
+
+// findPack returns the path of the first pack named name, matched case-insensitively, across
+// the pack repositories, or "" if there is none.
+func findPack(home draftpath.Home, name string) (string, error) {
+	for _, repository := range repo.FindRepositories(home.Packs()) {
+		packPath, err := findPackIn(repository, name)
+		if err != nil || packPath != "" {
+			return packPath, err
+		}
+	}
+	return "", nil
+}
+
+// findPackIn returns the path of the pack named name, matched case-insensitively, in a pack
+// repository, or "" if there is none.
+func findPackIn(repository repo.Repository, name string) (string, error) {
+	packDir := filepath.Join(repository.Dir, repo.PackDirName)
+	packs, err := ioutil.ReadDir(packDir)
+	if err != nil {
+		return "", fmt.Errorf("there was an error reading %s: %v", packDir, err)
+	}
+	for _, file := range packs {
+		if file.IsDir() && strings.EqualFold(name, file.Name()) {
+			return filepath.Join(packDir, file.Name()), nil
+		}
+	}
+	return "", nil
+}
+
+// placeholderValues returns the values of the pack placeholders detected for the application in
+// dir. app may be nil if no application was detected from a manifest, in which case only the port
+// exposed by an existing Dockerfile or found in the source code is known.
+func placeholderValues(dir string, app *detect.App) map[string]string {
+	values := make(map[string]string)
+	port := detect.Port(dir)
+	if app != nil {
+		port = app.Port
+		values[pack.RuntimeVersionPlaceholder] = app.RuntimeVersion
+		values[pack.StartCommandPlaceholder] = app.StartCommand
+	}
+	if port != 0 {
+		values[pack.PortPlaceholder] = strconv.Itoa(port)
+	}
+	return values
 }
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/draft/pkg/detect"
+	"github.com/Azure/draft/pkg/draft/manifest"
+	"github.com/Azure/draft/pkg/draft/pack"
+	"github.com/Azure/draft/pkg/draft/pack/repo"
+	"github.com/Azure/draft/pkg/linguist"
+	"github.com/Azure/draft/pkg/osutil"
+)
+
+// packChoice is a pack offered by draft create --interactive.
+type packChoice struct {
+	path  string
+	label string
+	// app is the application detected for the language of the pack, if any.
+	app *detect.App
+}
+
+// runInteractive asks which pack to scaffold the application with, the name of the
+// application, the values of the placeholders of the pack, the registry, the namespace and
+// the environments to generate, then previews the files and writes them once confirmed.
+func (c *createCmd) runInteractive(mfest *manifest.Manifest) error {
+	p := newPrompter(c.in, c.out)
+	dir := c.destDir()
+
+	choice, err := c.choosePack(p, dir)
+	if err != nil {
+		return err
+	}
+
+	env := mfest.Environments[manifest.DefaultEnvironmentName]
+	defaultName := c.appName
+	if defaultName == "" {
+		defaultName = env.Name
+	}
+	name, err := p.ask("Application name", defaultName)
+	if err != nil {
+		return err
+	}
+	c.appName = name
+	c.normalizeApplicationName()
+	env.Name = c.appName
+
+	placeholders, err := pack.Placeholders(choice.path)
+	if err != nil {
+		return err
+	}
+	values, err := askPlaceholders(p, placeholders, placeholderValues(dir, choice.app))
+	if err != nil {
+		return err
+	}
+
+	globalRegistry := globalConfig[registry.name]
+	if env.Registry, err = p.ask("Registry to push images to", globalRegistry); err != nil {
+		return err
+	}
+	if env.Registry == globalRegistry {
+		// leave it to the global configuration.
+		env.Registry = ""
+	}
+	if env.Namespace, err = p.ask("Namespace to deploy to", env.Namespace); err != nil {
+		return err
+	}
+	answer, err := p.ask("Environments to generate, separated by commas", manifest.DefaultEnvironmentName)
+	if err != nil {
+		return err
+	}
+	mfest.Environments = make(map[string]*manifest.Environment)
+	for _, envName := range environmentNames(answer) {
+		e := *env
+		mfest.Environments[envName] = &e
+	}
+
+	files, err := c.previewFiles(choice.path, values)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out, "--> Draft will write:")
+	for _, f := range files {
+		fmt.Fprintf(c.out, "    %s\n", f)
+	}
+	ok, err := p.confirm("Write these files?", true)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintln(c.out, "--> No files were written")
+		return nil
+	}
+
+	if err := pack.CreateFrom(c.dest, choice.path, c.appName, values); err != nil {
+		return err
+	}
+	return c.writeManifest(mfest)
+}
+
+// choosePack offers the packs matching the languages detected in dir first, then the other
+// packs of every pack repository. With --pack, the named pack is picked without asking.
+func (c *createCmd) choosePack(p *prompter, dir string) (*packChoice, error) {
+	apps, err := detect.Dir(dir)
+	if err != nil {
+		fmt.Fprintf(c.out, "--> Could not detect the framework of the application: %v\n", err)
+	}
+	if c.pack != "" {
+		packsFound, err := pack.Find(c.home.Packs(), c.pack)
+		if err != nil {
+			return nil, err
+		}
+		if len(packsFound) == 0 {
+			return nil, fmt.Errorf("No packs found with name %s", c.pack)
+		} else if len(packsFound) > 1 {
+			return nil, fmt.Errorf("Multiple packs named %s found: %v", c.pack, packsFound)
+		}
+		return &packChoice{path: packsFound[0], app: detect.ForPack(apps, filepath.Base(packsFound[0]))}, nil
+	}
+
+	langs, err := linguist.ProcessDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error detecting the language: %s", err)
+	}
+	for _, lang := range langs {
+		fmt.Fprintf(c.out, "--> Draft detected %s (%f%%)\n", lang.Language, lang.Percent)
+	}
+
+	choices, err := packChoices(repo.FindRepositories(c.home.Packs()), langs, apps)
+	if err != nil {
+		return nil, err
+	}
+	if len(choices) == 0 {
+		return nil, errors.New("no packs found. Try running:\n\t$ draft pack-repo update")
+	}
+	options := make([]string, len(choices))
+	for i, choice := range choices {
+		options[i] = choice.label
+	}
+	i, err := p.choose("Pack to scaffold the application with", options)
+	if err != nil {
+		return nil, err
+	}
+	return choices[i], nil
+}
+
+// packChoices lists the packs of the repositories, the packs matching the detected languages
+// first, from the most to the least used language.
+func packChoices(repositories []repo.Repository, langs []*linguist.Language, apps []*detect.App) ([]*packChoice, error) {
+	var (
+		detected []*packChoice
+		others   []*packChoice
+		seen     = make(map[string]bool)
+	)
+	for _, repository := range repositories {
+		for _, lang := range langs {
+			// the original name of the language is shown, so the alias goes on a copy.
+			alias := *lang
+			names, app := packCandidates(linguist.Alias(&alias), apps)
+			for _, name := range names {
+				packPath, err := findPackIn(repository, name)
+				if err != nil {
+					return nil, err
+				}
+				if packPath == "" || seen[packPath] {
+					continue
+				}
+				seen[packPath] = true
+				label := fmt.Sprintf("%s/%s (detected %s", repository.Name, filepath.Base(packPath), lang.Language)
+				if app != nil && app.Framework != "" && name != names[len(names)-1] {
+					label += ", " + app.Framework
+				}
+				detected = append(detected, &packChoice{path: packPath, label: label + ")", app: app})
+			}
+		}
+	}
+	for _, repository := range repositories {
+		names, err := repository.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			packPath := filepath.Join(repository.Dir, repo.PackDirName, filepath.Base(name))
+			if !seen[packPath] {
+				seen[packPath] = true
+				others = append(others, &packChoice{path: packPath, label: name})
+			}
+		}
+	}
+	return append(detected, others...), nil
+}
+
+// askPlaceholders asks for the value of every placeholder declared by a pack, suggesting the
+// detected value, else the default of the placeholder.
+func askPlaceholders(p *prompter, placeholders map[string]pack.Placeholder, detected map[string]string) (map[string]string, error) {
+	names := make([]string, 0, len(placeholders))
+	for name := range placeholders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(map[string]string)
+	for _, name := range names {
+		placeholder := placeholders[name]
+		def := detected[name]
+		if def == "" {
+			def = placeholder.Default
+		}
+		question := name
+		if placeholder.Description != "" {
+			question = fmt.Sprintf("%s (%s)", name, placeholder.Description)
+		}
+		for {
+			value, err := p.ask(question, def)
+			if err != nil {
+				return nil, err
+			}
+			if value != "" {
+				values[name] = value
+				break
+			}
+			fmt.Fprintf(p.out, "--> A value is required for %s\n", name)
+		}
+	}
+	return values, nil
+}
+
+// environmentNames splits a comma-separated list of environments, dropping blanks and
+// duplicates, and defaults to the default environment.
+func environmentNames(answer string) []string {
+	var (
+		names []string
+		seen  = make(map[string]bool)
+	)
+	for _, name := range strings.Split(answer, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return []string{manifest.DefaultEnvironmentName}
+	}
+	return names
+}
+
+// previewFiles lists the files draft create would write with a pack, rendering the pack in a
+// temporary directory. Files kept as they already exist are marked as such.
+func (c *createCmd) previewFiles(packPath string, values map[string]string) ([]string, error) {
+	tmp, err := ioutil.TempDir("", "draft-create")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	if err := pack.CreateFrom(tmp, packPath, c.appName, values); err != nil {
+		return nil, err
+	}
+
+	var files []string
+	err = filepath.Walk(tmp, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(tmp, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, draftToml)
+	if exists, err := osutil.Exists(filepath.Join(c.dest, ignoreFileName)); err == nil && !exists {
+		files = append(files, ignoreFileName)
+	}
+	sort.Strings(files)
+
+	for i, f := range files {
+		exists, err := osutil.Exists(filepath.Join(c.dest, f))
+		if err != nil {
+			return nil, err
+		}
+		if exists && f != draftToml {
+			files[i] = f + " (exists, kept)"
+		}
+	}
+	return files, nil
+}
+
+// prompter asks the questions of draft create --interactive.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{in: bufio.NewReader(in), out: out}
+}
+
+// ask asks a question, returning the trimmed answer, or def if the answer is blank.
+func (p *prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.out, "? %s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "? %s: ", question)
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		fmt.Fprintln(p.out)
+		return "", fmt.Errorf("no answer to %q: %v", question, err)
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// choose asks to pick one of options, numbered from 1, and returns the index of the option
+// picked. The first option is the default.
+func (p *prompter) choose(question string, options []string) (int, error) {
+	for i, option := range options {
+		fmt.Fprintf(p.out, "  %d) %s\n", i+1, option)
+	}
+	for {
+		answer, err := p.ask(question, "1")
+		if err != nil {
+			return 0, err
+		}
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(options) {
+			return i - 1, nil
+		}
+		fmt.Fprintf(p.out, "--> Pick a number between 1 and %d\n", len(options))
+	}
+}
+
+// confirm asks a yes or no question.
+func (p *prompter) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		answer, err := p.ask(question, hint)
+		if err != nil {
+			return false, err
+		}
+		if answer == hint {
+			return def, nil
+		}
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(p.out, "--> Answer yes or no")
+	}
+}
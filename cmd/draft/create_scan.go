@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/Azure/draft/pkg/detect"
+	"github.com/Azure/draft/pkg/draft/workspace"
+	"github.com/Azure/draft/pkg/osutil"
+)
+
+// reInvalidAppNameChars matches the characters replaced by dashes in the names of the
+// applications found by draft create --scan.
+var reInvalidAppNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// scan scaffolds every application found below the directory, identified by a Dockerfile or
+// a manifest such as go.mod or package.json, then writes a workspace file listing them so
+// 'draft up --all' deploys them together. Applications already in the workspace file are kept.
+func (c *createCmd) scan() error {
+	if c.appName != "" {
+		return errors.New("--app cannot be used with --scan: applications are named after their directory")
+	}
+	if c.interactive {
+		return errors.New("--interactive cannot be used with --scan")
+	}
+	dir := c.destDir()
+	roots, err := detect.Roots(dir)
+	if err != nil {
+		return fmt.Errorf("there was an error looking for applications: %v", err)
+	}
+	if len(roots) == 0 {
+		return fmt.Errorf("no applications found below %s", dir)
+	}
+
+	wsFile := filepath.Join(dir, workspace.DefaultFilename)
+	var w workspace.Workspace
+	if _, err := toml.DecodeFile(wsFile, &w); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not read %s: %v", wsFile, err)
+	}
+	names := make(map[string]bool)
+	paths := make(map[string]bool)
+	for _, app := range w.Apps {
+		names[app.Name] = true
+		path := app.Path
+		if path == "" {
+			path = app.Name
+		}
+		paths[filepath.Clean(filepath.FromSlash(path))] = true
+	}
+
+	var failed []string
+	for _, root := range roots {
+		if paths[root] {
+			fmt.Fprintf(c.out, "--> %s is already in %s\n", root, workspace.DefaultFilename)
+			continue
+		}
+		name := scanAppName(root, names)
+		appDir := filepath.Join(dir, root)
+		exists, err := osutil.Exists(filepath.Join(appDir, draftToml))
+		if err != nil {
+			return err
+		}
+		if exists {
+			fmt.Fprintf(c.out, "--> Adding %s in %s, which already has a %s\n", name, root, draftToml)
+		} else {
+			fmt.Fprintf(c.out, "--> Creating %s in %s\n", name, root)
+			app := *c
+			app.dest = appDir
+			app.appName = name
+			app.scanApps = false
+			if err := app.run(); err != nil {
+				fmt.Fprintf(c.out, "--> Could not create %s: %v\n", name, err)
+				failed = append(failed, root)
+				continue
+			}
+		}
+		w.Apps = append(w.Apps, &workspace.App{Name: name, Path: filepath.ToSlash(root)})
+	}
+
+	if err := w.Save(wsFile); err != nil {
+		return fmt.Errorf("could not write %s: %v", wsFile, err)
+	}
+	fmt.Fprintf(c.out, "--> Wrote %s with %d applications. Deploy them together with `draft up --all`\n", wsFile, len(w.Apps))
+	if len(failed) > 0 {
+		return fmt.Errorf("could not create the applications in %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// scanAppName names the application in root after its directory, or after its path if
+// another application has the name already, and marks the name as taken.
+func scanAppName(root string, taken map[string]bool) string {
+	normalize := func(s string) string {
+		return strings.Trim(reInvalidAppNameChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	}
+	name := normalize(filepath.Base(root))
+	if name == "" || taken[name] {
+		name = normalize(filepath.ToSlash(root))
+	}
+	if name == "" {
+		name = "app"
+	}
+	for base, i := name, 2; taken[name]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	taken[name] = true
+	return name
+}
@@ -6,11 +6,18 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
 
+	"github.com/BurntSushi/toml"
+
+	"github.com/Azure/draft/pkg/detect"
 	"github.com/Azure/draft/pkg/draft/draftpath"
+	"github.com/Azure/draft/pkg/draft/manifest"
+	"github.com/Azure/draft/pkg/draft/pack"
+	"github.com/Azure/draft/pkg/draft/workspace"
 	"github.com/Azure/draft/pkg/testing/helpers"
 )
 
@@ -23,7 +30,8 @@ func TestCreate(t *testing.T) {
 		src         string
 		expectedErr error
 	}{
-		{filepath.Join("testdata", "create", "src", "empty"), nil},
+		// languages are detected in the application directory, where there are none.
+		{filepath.Join("testdata", "create", "src", "empty"), ErrNoLanguageDetected},
 		{filepath.Join("testdata", "create", "src", "html-but-actually-go"), nil},
 		{filepath.Join("testdata", "create", "src", "simple-go"), nil},
 		{filepath.Join("testdata", "create", "src", "simple-go-with-draftignore"), nil},
@@ -87,6 +95,208 @@ func TestNormalizeApplicationName(t *testing.T) {
 }
 
 // tempDir create and clean a temporary directory to work in our tests
+func TestCreateScan(t *testing.T) {
+	dir, teardown := tempDir(t, "draft-create-scan")
+	defer teardown()
+
+	files := map[string]string{
+		filepath.Join("services", "api", "go.mod"):    "module example.com/api\n\ngo 1.14\n",
+		filepath.Join("services", "api", "main.go"):   "package main\n\nfunc main() {}\n",
+		filepath.Join("web", "api", "Dockerfile"):     "FROM nginx\n",
+		filepath.Join("web", "api", draftToml):        "[environments.development]\nname = \"web\"\n",
+		filepath.Join("web", "api", "charts", "keep"): "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	create := &createCmd{
+		out:      &out,
+		pack:     "go",
+		home:     draftpath.Home(filepath.Join("testdata", "drafthome")),
+		dest:     dir,
+		scanApps: true,
+	}
+	if err := create.run(); err != nil {
+		t.Fatalf("draft create --scan returned an unexpected error: %v\n%s", err, out.String())
+	}
+
+	w, err := workspace.Load(filepath.Join(dir, workspace.DefaultFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*workspace.App{
+		{Name: "api", Path: filepath.Join(dir, "services", "api")},
+		{Name: "web-api", Path: filepath.Join(dir, "web", "api")},
+	}
+	if !reflect.DeepEqual(w.Apps, expected) {
+		t.Errorf("expected workspace apps %v, got %v", expected, w.Apps)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "services", "api", draftToml)); err != nil {
+		t.Errorf("expected a %s for the api: %v", draftToml, err)
+	}
+
+	// scanning again keeps the applications of the workspace.
+	out.Reset()
+	if err := create.run(); err != nil {
+		t.Fatalf("draft create --scan returned an unexpected error: %v\n%s", err, out.String())
+	}
+	if w, err = workspace.Load(filepath.Join(dir, workspace.DefaultFilename)); err != nil || len(w.Apps) != 2 {
+		t.Errorf("expected the workspace to keep its 2 applications, got %v and %v", w, err)
+	}
+}
+
+func TestScanAppName(t *testing.T) {
+	taken := map[string]bool{"api": true}
+	testCases := []struct {
+		root     string
+		expected string
+	}{
+		{filepath.Join("services", "Auth_Service"), "auth-service"},
+		{filepath.Join("services", "api"), "services-api"},
+		{"api", "api-2"},
+	}
+	for _, tc := range testCases {
+		if got := scanAppName(tc.root, taken); got != tc.expected {
+			t.Errorf("expected %s to be named %q, got %q", tc.root, tc.expected, got)
+		}
+	}
+}
+
+func TestPlaceholderValues(t *testing.T) {
+	dir, teardown := tempDir(t, "draft-create-placeholders")
+	defer teardown()
+	if err := ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM node\nEXPOSE 4000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		app      *detect.App
+		expected map[string]string
+	}{
+		{nil, map[string]string{pack.PortPlaceholder: "4000"}},
+		{
+			&detect.App{RuntimeVersion: "14", StartCommand: "npm start", Port: 3000},
+			map[string]string{
+				pack.PortPlaceholder:           "3000",
+				pack.RuntimeVersionPlaceholder: "14",
+				pack.StartCommandPlaceholder:   "npm start",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		if got := placeholderValues(dir, tc.app); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("expected placeholder values %v, got %v", tc.expected, got)
+		}
+	}
+}
+
+func TestCreateInteractive(t *testing.T) {
+	testCases := []struct {
+		name    string
+		answers string
+		written bool
+	}{
+		// pack, name, registry, namespace, environments and confirmation.
+		{"confirmed", "\nMyApp\nexample.azurecr.io\n\ndevelopment, staging\n\n", true},
+		{"declined", "1\nmyapp\n\n\n\nno\n", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, teardown := tempDir(t, "draft-create-interactive")
+			defer teardown()
+			helpers.CopyTree(t, filepath.Join("testdata", "create", "src", "simple-go"), dir)
+
+			var out bytes.Buffer
+			create := &createCmd{
+				out:         &out,
+				in:          strings.NewReader(tc.answers),
+				home:        draftpath.Home(filepath.Join("testdata", "drafthome")),
+				dest:        dir,
+				interactive: true,
+			}
+			if err := create.run(); err != nil {
+				t.Fatalf("draft create --interactive returned an unexpected error: %v\n%s", err, out.String())
+			}
+			for _, s := range []string{"github.com/Azure/draft/go (detected Go)", "--> Draft will write:", "Dockerfile", draftToml} {
+				if !strings.Contains(out.String(), s) {
+					t.Errorf("expected %q in the output, got:\n%s", s, out.String())
+				}
+			}
+
+			var mfest manifest.Manifest
+			_, err := toml.DecodeFile(filepath.Join(dir, draftToml), &mfest)
+			if !tc.written {
+				if !os.IsNotExist(err) {
+					t.Errorf("expected no %s to be written, got %v", draftToml, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(mfest.Environments) != 2 || mfest.Environments["staging"] == nil {
+				t.Fatalf("expected the development and staging environments, got %v", mfest.Environments)
+			}
+			env := mfest.Environments[manifest.DefaultEnvironmentName]
+			if env.Name != "myapp" || env.Registry != "example.azurecr.io" || env.Namespace != manifest.DefaultNamespace {
+				t.Errorf("expected the answers in %s, got %+v", draftToml, env)
+			}
+			if _, err := os.Stat(filepath.Join(dir, pack.ChartsDir, "myapp")); err != nil {
+				t.Errorf("expected the chart to be named after the application: %v", err)
+			}
+		})
+	}
+}
+
+func TestAskPlaceholders(t *testing.T) {
+	placeholders := map[string]pack.Placeholder{
+		pack.PortPlaceholder:           {Description: "the port the application listens on", Default: "8080"},
+		pack.RuntimeVersionPlaceholder: {Default: "1.14"},
+		"image-tag":                    {Description: "the tag of the image"},
+	}
+	detected := map[string]string{pack.PortPlaceholder: "3000"}
+	// image-tag is asked again as it has no default, then port and runtime-version in order.
+	var out bytes.Buffer
+	p := newPrompter(strings.NewReader("\nlatest\n\n1.15\n"), &out)
+	values, err := askPlaceholders(p, placeholders, detected)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	expected := map[string]string{"image-tag": "latest", pack.PortPlaceholder: "3000", pack.RuntimeVersionPlaceholder: "1.15"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected values %v, got %v", expected, values)
+	}
+	if !strings.Contains(out.String(), "port (the port the application listens on) [3000]") {
+		t.Errorf("expected the description and detected value in the question, got:\n%s", out.String())
+	}
+
+	if _, err := askPlaceholders(newPrompter(strings.NewReader(""), &out), placeholders, nil); err == nil {
+		t.Error("expected an error without answers")
+	}
+}
+
+func TestEnvironmentNames(t *testing.T) {
+	testCases := map[string][]string{
+		"":                       {manifest.DefaultEnvironmentName},
+		"staging":                {"staging"},
+		" dev, staging,,dev ":    {"dev", "staging"},
+		"development,production": {"development", "production"},
+	}
+	for answer, expected := range testCases {
+		if got := environmentNames(answer); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %q to give %v, got %v", answer, expected, got)
+		}
+	}
+}
+
 func tempDir(t *testing.T, description string) (string, func()) {
 	t.Helper()
 	path, err := ioutil.TempDir("", description)
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/action"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/Azure/draft/pkg/draft/debugger"
+	"github.com/Azure/draft/pkg/draft/manifest"
+	"github.com/Azure/draft/pkg/draft/pack"
+	"github.com/Azure/draft/pkg/draft/tunnel"
+	"github.com/Azure/draft/pkg/kube/podutil"
+	"github.com/Azure/draft/pkg/linguist"
+	"github.com/Azure/draft/pkg/local"
+	"github.com/Azure/draft/pkg/osutil"
+)
+
+const debugDesc = `Debug the application running in the cluster.
+
+The latest build of the application is redeployed with a debug overlay: Go programs run
+under delve, Node.js with --inspect, Python with debugpy and Java with a JDWP agent. The
+debugger port is forwarded to localhost, and instructions to attach an IDE are printed.
+
+The overlay is chosen from the language of the application, as detected by 'draft create',
+unless --language is given. Packs may provide a debug config, copied to .draft-debug.toml
+by 'draft create', to customize the overlay:
+
+	language = "go"
+	port = 2345
+	command = ["dlv", "exec", "/go/bin/app", "--headless", "--listen=:2345", "--api-version=2"]
+	[env]
+	GOTRACEBACK = "all"
+
+In command, {{program}} and {{args}} stand for the program and the arguments of the
+original command of the container.
+
+The application is redeployed without the overlay when the command is interrupted. If
+draft debug could not do so, e.g. as it was killed, the next 'draft debug' or 'draft up'
+does.
+`
+
+// debugTemplateAnnotation is the Deployment annotation holding the pod template of the
+// Deployment before the debug overlay was applied, so it can be restored even if draft
+// debug is killed.
+const debugTemplateAnnotation = "draft.sh/debug-template"
+
+type debugCmd struct {
+	out       io.Writer
+	env       string
+	container string
+	language  string
+	localPort int
+}
+
+func newDebugCmd(out io.Writer) *cobra.Command {
+	dc := &debugCmd{out: out}
+	cmd := &cobra.Command{
+		Use:   "debug",
+		Short: "attach a debugger to the application running in the cluster",
+		Long:  debugDesc,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return dc.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&dc.container, "container", "c", "", "name of the container to debug (default: the first container of the pod)")
+	f.StringVar(&dc.language, "language", "", fmt.Sprintf("language of the debug overlay (%s)", strings.Join(debugger.Languages(), "|")))
+	f.IntVarP(&dc.localPort, "port", "p", 0, "local port to forward the debugger to (default: the debugger port)")
+	f.StringVarP(&dc.env, environmentFlagName, environmentFlagShorthand, defaultDraftEnvironment(), environmentFlagUsage)
+	return cmd
+}
+
+func (d *debugCmd) run() error {
+	mfst, err := manifest.Load(draftToml)
+	if err != nil {
+		return err
+	}
+	env, ok := mfst.Environments[d.env]
+	if !ok {
+		return fmt.Errorf("Environment %v not found", d.env)
+	}
+	app, err := local.DeployedApplication(draftToml, d.env)
+	if err != nil {
+		return err
+	}
+	overlay, err := d.overlay()
+	if err != nil {
+		return err
+	}
+	image, err := imageCommand(env.Dockerfile)
+	if err != nil {
+		return err
+	}
+
+	kctx, err := resolveKubeContext(d.env, app.KubeContexts)
+	if err != nil {
+		return err
+	}
+	clientset, config, err := getKubeClient(kctx)
+	if err != nil {
+		return err
+	}
+	cfg, err := getHelmConfig(kctx, app.Namespace)
+	if err != nil {
+		return err
+	}
+	rls, err := action.NewGet(cfg).Run(app.Name)
+	if err != nil {
+		return fmt.Errorf("could not get release %q: %v", app.Name, err)
+	}
+
+	ctx := context.Background()
+	if err := restoreDebugOverlays(ctx, clientset, app, d.out); err != nil {
+		return err
+	}
+	deployments := clientset.AppsV1().Deployments(app.Namespace)
+	deployment, err := appDeployment(ctx, clientset, app, releaseResources(rls.Manifest)["Deployment"])
+	if err != nil {
+		return err
+	}
+
+	session := time.Now().UTC().Format("20060102150405")
+	original, err := json.Marshal(deployment.Spec.Template)
+	if err != nil {
+		return err
+	}
+	if err := overlay.Apply(&deployment.Spec.Template, d.container, image, session); err != nil {
+		return err
+	}
+	if deployment.Annotations == nil {
+		deployment.Annotations = make(map[string]string)
+	}
+	deployment.Annotations[debugger.Annotation] = session
+	deployment.Annotations[debugTemplateAnnotation] = string(original)
+	fmt.Fprintf(d.out, "Redeploying %s with the %s debug overlay...\n", deployment.Name, overlay.Language)
+	if _, err := deployments.Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("could not update deployment %s: %v", deployment.Name, err)
+	}
+	defer func() {
+		fmt.Fprintf(d.out, "Redeploying %s without the debug overlay\n", deployment.Name)
+		if err := restoreDeployment(ctx, clientset, app.Namespace, deployment.Name); err != nil {
+			fmt.Fprintf(d.out, "WARNING: could not restore deployment %s, run draft up to redeploy it: %v\n", deployment.Name, err)
+		}
+	}()
+
+	pod, err := podutil.GetPod(app.Namespace, local.DraftLabelKey, app.Name, debugger.Annotation, session, clientset)
+	if err != nil {
+		return err
+	}
+	localPort := d.localPort
+	if localPort == 0 {
+		localPort = overlay.Port
+	}
+	t := tunnel.NewWithLocalTunnel(clientset.CoreV1().RESTClient(), config, app.Namespace, pod.Name, overlay.Port, localPort)
+	if err := t.ForwardPort(); err != nil {
+		return err
+	}
+	defer t.Close()
+
+	fmt.Fprintf(d.out, "Debugger of pod %s listening on localhost:%d\n", pod.Name, t.Local)
+	instructions, err := overlay.AttachInstructions(t.Local)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(d.out, instructions)
+	fmt.Fprintln(d.out, "Press Ctrl+C to stop debugging.")
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	select {
+	case <-stop:
+	case <-t.Done():
+		fmt.Fprintf(d.out, "Lost connection to pod %s\n", pod.Name)
+	}
+	return nil
+}
+
+// overlay returns the debug overlay given by --language, the debug config of the
+// application or the detected language, in that order.
+func (d *debugCmd) overlay() (*debugger.Overlay, error) {
+	if d.language != "" {
+		o, ok := debugger.ForLanguage(d.language)
+		if !ok {
+			return nil, fmt.Errorf("there is no debug overlay for %s (available: %s)", d.language, strings.Join(debugger.Languages(), ", "))
+		}
+		return o, nil
+	}
+	exists, err := osutil.Exists(pack.TargetDebugFileName)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return debugger.Load(pack.TargetDebugFileName)
+	}
+
+	langs, err := linguist.ProcessDir(".")
+	if err != nil {
+		return nil, fmt.Errorf("there was an error detecting the language: %s", err)
+	}
+	for _, lang := range langs {
+		detectedLang := linguist.Alias(lang)
+		if o, ok := debugger.ForLanguage(detectedLang.Language); ok {
+			fmt.Fprintf(d.out, "--> Draft detected %s (%f%%)\n", detectedLang.Language, detectedLang.Percent)
+			return o, nil
+		}
+	}
+	return nil, fmt.Errorf("no debug overlay for the languages of the application; use --language or add %s", pack.TargetDebugFileName)
+}
+
+// imageCommand returns the command of the image built from the Dockerfile.
+func imageCommand(dockerfile string) (debugger.ImageCommand, error) {
+	if dockerfile == "" {
+		dockerfile = manifest.DefaultDockerfile
+	}
+	f, err := os.Open(dockerfile)
+	if os.IsNotExist(err) {
+		return debugger.ImageCommand{}, nil
+	} else if err != nil {
+		return debugger.ImageCommand{}, err
+	}
+	defer f.Close()
+	return debugger.ParseDockerfile(f)
+}
+
+// appDeployment returns the Deployment of the release running the application pods.
+func appDeployment(ctx context.Context, clientset kubernetes.Interface, app *local.App, names []string) (*appsv1.Deployment, error) {
+	var first *appsv1.Deployment
+	for _, name := range names {
+		d, err := clientset.AppsV1().Deployments(app.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if d.Spec.Template.Labels[local.DraftLabelKey] == app.Name {
+			return d, nil
+		}
+		if first == nil {
+			first = d
+		}
+	}
+	if first == nil {
+		return nil, fmt.Errorf("release %q has no Deployments", app.Name)
+	}
+	return first, nil
+}
+
+// restoreDeployment resets the pod template of a Deployment to the one it had before the
+// debug overlay was applied. A Deployment without a debug overlay is left unchanged.
+func restoreDeployment(ctx context.Context, clientset kubernetes.Interface, namespace, name string) error {
+	deployments := clientset.AppsV1().Deployments(namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		d, err := deployments.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		original, ok := d.Annotations[debugTemplateAnnotation]
+		if !ok {
+			return nil
+		}
+		var tmpl v1.PodTemplateSpec
+		if err := json.Unmarshal([]byte(original), &tmpl); err != nil {
+			return fmt.Errorf("invalid %s annotation: %v", debugTemplateAnnotation, err)
+		}
+		d.Spec.Template = tmpl
+		delete(d.Annotations, debugger.Annotation)
+		delete(d.Annotations, debugTemplateAnnotation)
+		_, err = deployments.Update(ctx, d, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// restoreDebugOverlays restores the Deployments of the application left with a debug
+// overlay by a draft debug which could not restore them.
+func restoreDebugOverlays(ctx context.Context, clientset kubernetes.Interface, app *local.App, out io.Writer) error {
+	list, err := clientset.AppsV1().Deployments(app.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, d := range list.Items {
+		if _, ok := d.Annotations[debugTemplateAnnotation]; !ok || d.Spec.Template.Labels[local.DraftLabelKey] != app.Name {
+			continue
+		}
+		fmt.Fprintf(out, "Redeploying %s without the debug overlay of an interrupted draft debug\n", d.Name)
+		if err := restoreDeployment(ctx, clientset, app.Namespace, d.Name); err != nil {
+			return fmt.Errorf("could not restore deployment %s: %v", d.Name, err)
+		}
+	}
+	return nil
+}
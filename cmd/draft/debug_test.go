@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/Azure/draft/pkg/draft/debugger"
+	"github.com/Azure/draft/pkg/local"
+)
+
+func TestDebugDeployment(t *testing.T) {
+	deployment := func(name, draftLabel string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "dev"},
+			Spec: appsv1.DeploymentSpec{
+				Template: v1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{local.DraftLabelKey: draftLabel}},
+					Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app", Command: []string{"node", "server.js"}}}},
+				},
+			},
+		}
+	}
+	clientset := fake.NewSimpleClientset(deployment("cache", ""), deployment("web", "web"))
+	app := &local.App{Name: "web", Namespace: "dev"}
+	ctx := context.Background()
+
+	d, err := appDeployment(ctx, clientset, app, []string{"cache", "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != "web" {
+		t.Fatalf("expected the deployment of the application pods, got %s", d.Name)
+	}
+	if _, err := appDeployment(ctx, clientset, app, nil); err == nil {
+		t.Error("expected an error for a release without deployments")
+	}
+
+	// apply an overlay as draft debug does, then restore the original pod template, as
+	// the next draft debug or draft up does if draft debug was killed.
+	original, err := json.Marshal(d.Spec.Template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlay, _ := debugger.ForLanguage("javascript")
+	if err := overlay.Apply(&d.Spec.Template, "", debugger.ImageCommand{}, "session"); err != nil {
+		t.Fatal(err)
+	}
+	d.Annotations = map[string]string{debugger.Annotation: "session", debugTemplateAnnotation: string(original)}
+	deployments := clientset.AppsV1().Deployments("dev")
+	if _, err := deployments.Update(ctx, d, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := restoreDebugOverlays(ctx, clientset, app, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Redeploying web") {
+		t.Errorf("expected the deployment to be redeployed, got %q", out.String())
+	}
+	restored, err := deployments.Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := restored.Spec.Template.Annotations[debugger.Annotation]; ok {
+		t.Error("expected the debug annotation to be removed")
+	}
+	if env := restored.Spec.Template.Spec.Containers[0].Env; len(env) != 0 {
+		t.Errorf("expected the debug environment to be removed, got %v", env)
+	}
+	if _, ok := restored.Annotations[debugTemplateAnnotation]; ok {
+		t.Error("expected the original pod template annotation to be removed")
+	}
+
+	// restoring a deployment without a debug overlay leaves it unchanged.
+	if err := restoreDeployment(ctx, clientset, "dev", "cache"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -4,16 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
-	"os"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/net/context"
 	"helm.sh/helm/v3/pkg/action"
-	"helm.sh/helm/v3/pkg/cli"
 
 	"github.com/Azure/draft/pkg/local"
-	"github.com/Azure/draft/pkg/storage/kube/configmap"
 	"github.com/Azure/draft/pkg/tasks"
 )
 
@@ -50,59 +46,57 @@ func newDeleteCmd(out io.Writer) *cobra.Command {
 }
 
 func (d *deleteCmd) run(runningEnvironment string) error {
-
-	var name string
-
-	if d.appName != "" {
-		name = d.appName
-	} else {
-		deployedApp, err := local.DeployedApplication(draftToml, runningEnvironment)
-		if err != nil {
+	deployedApp, err := local.DeployedApplication(draftToml, runningEnvironment)
+	if err != nil {
+		if d.appName == "" {
 			return errors.New("Unable to detect app name\nPlease pass in the name of the application")
-
 		}
+		// the app was named explicitly, so fall back to the namespace of the current context.
+		deployedApp = &local.App{}
+	}
+	if d.appName != "" {
+		deployedApp.Name = d.appName
+	}
 
-		name = deployedApp.Name
+	kctx, err := resolveKubeContext(runningEnvironment, deployedApp.KubeContexts)
+	if err != nil {
+		return err
 	}
 
 	//TODO: replace with serverside call
-	if err := Delete(name); err != nil {
+	if err := Delete(deployedApp, kctx); err != nil {
 		return err
 	}
 
-	msg := "app '" + name + "' deleted"
+	msg := "app '" + deployedApp.Name + "' deleted"
 	fmt.Fprintln(d.out, msg)
 	return nil
 }
 
-// Delete uses the helm client to delete an app with the given name
+// Delete uses the helm client to delete an app from the cluster of the given kubeconfig context
 //
 // Returns an error if the command failed.
-func Delete(app string) error {
-	// set up helm client
-	client, _, err := getKubeClient(kubeContext)
+func Delete(app *local.App, kubeContext string) error {
+	// delete Draft storage for app
+	store, err := newStore("", kubeContext, storageNamespace(app.StorageNamespace, app.Namespace))
 	if err != nil {
-		return fmt.Errorf("Could not get a kube client: %s", err)
+		return err
 	}
-
-	// delete Draft storage for app
-	store := configmap.NewConfigMaps(client.CoreV1().ConfigMaps("default"))
-	if _, err := store.DeleteBuilds(context.Background(), app); err != nil {
+	builds, err := store.DeleteBuilds(context.Background(), app.Name)
+	if err != nil {
 		return err
 	}
+	deleteBuildLogs(store, app.Name, builds)
 
-	settings := cli.New()
-	actionConfig := new(action.Configuration)
-	// You can pass an empty string instead of settings.Namespace() to list
-	// all namespaces
-	if err := actionConfig.Init(settings.RESTClientGetter(), settings.Namespace(), os.Getenv("HELM_DRIVER"), log.Printf); err != nil {
+	actionConfig, err := getHelmConfig(kubeContext, app.Namespace)
+	if err != nil {
 		return err
 	}
 
 	uninstallAction := action.NewUninstall(actionConfig)
 
 	// delete helm release
-	_, err = uninstallAction.Run(app)
+	_, err = uninstallAction.Run(app.Name)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/draft/pkg/detect"
+	"github.com/Azure/draft/pkg/draft/draftpath"
+	"github.com/Azure/draft/pkg/draft/pack/repo"
+	"github.com/Azure/draft/pkg/linguist"
+)
+
+const detectDesc = `Detect the languages and the framework of an application, and the pack draft create
+would pick from each pack repository.
+
+The languages are detected from the files of the directory, and every file is listed with
+the reason of the decision: a linguist-language attribute in .gitattributes, the name or
+extension of the file, the interpreter of a shebang line or the classifier, which guesses
+the language from the contents. Skipped files are listed with the reason they were skipped.
+
+The framework, runtime version, start command and port are detected from the manifests of
+the application, such as go.mod or package.json.
+`
+
+type detectCmd struct {
+	out  io.Writer
+	home draftpath.Home
+	dir  string
+	fmt  string
+}
+
+func newDetectCmd(out io.Writer) *cobra.Command {
+	dc := &detectCmd{out: out, dir: "."}
+	cmd := &cobra.Command{
+		Use:   "detect [path]",
+		Short: "detect the languages, the framework and the matching packs of an application",
+		Long:  detectDesc,
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				dc.dir = args[0]
+			}
+			dc.home = draftpath.Home(homePath())
+			return dc.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&dc.fmt, "output", "o", "table", "prints the output in the specified format (json|table)")
+	return cmd
+}
+
+// detection is the outcome of draft detect, as shown in JSON output.
+type detection struct {
+	Languages []*linguist.Language `json:"languages"`
+	Files     []*linguist.File     `json:"files"`
+	Apps      []*detect.App        `json:"apps"`
+	Packs     []packMatch          `json:"packs"`
+}
+
+// packMatch is the pack draft create would pick from a pack repository.
+type packMatch struct {
+	Repository string `json:"repository"`
+	// Pack is the name of the pack, empty if the repository has no pack for the application.
+	Pack      string `json:"pack,omitempty"`
+	Language  string `json:"language,omitempty"`
+	Framework string `json:"framework,omitempty"`
+}
+
+func (dc *detectCmd) run() error {
+	d, err := detectDir(dc.home, dc.dir)
+	if err != nil {
+		return err
+	}
+	switch dc.fmt {
+	case "json":
+		output, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(dc.out, string(output))
+	case "table":
+		fmt.Fprintln(dc.out, formatDetection(d))
+	default:
+		return fmt.Errorf("unknown output format %q", dc.fmt)
+	}
+	return nil
+}
+
+// detectDir detects the languages and applications of dir, and the packs matching them in
+// every pack repository.
+func detectDir(home draftpath.Home, dir string) (*detection, error) {
+	langs, files, err := linguist.ProcessDirWithFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error detecting the language: %s", err)
+	}
+	apps, err := detect.Dir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error detecting the framework: %v", err)
+	}
+	d := &detection{Languages: langs, Files: files, Apps: apps, Packs: []packMatch{}}
+	if d.Files == nil {
+		d.Files = []*linguist.File{}
+	}
+	if d.Apps == nil {
+		d.Apps = []*detect.App{}
+	}
+	for _, repository := range repo.FindRepositories(home.Packs()) {
+		match, err := matchPack(repository, langs, apps)
+		if err != nil {
+			return nil, err
+		}
+		d.Packs = append(d.Packs, match)
+	}
+	return d, nil
+}
+
+// matchPack returns the pack draft create would pick from a pack repository, trying the
+// detected languages from the most to the least used.
+func matchPack(repository repo.Repository, langs []*linguist.Language, apps []*detect.App) (packMatch, error) {
+	match := packMatch{Repository: repository.Name}
+	for _, lang := range langs {
+		// Alias renames the language in place, which is not shown.
+		alias := *lang
+		names, app := packCandidates(linguist.Alias(&alias), apps)
+		for _, name := range names {
+			packPath, err := findPackIn(repository, name)
+			if err != nil {
+				return match, err
+			}
+			if packPath != "" {
+				match.Pack = filepath.Base(packPath)
+				match.Language = lang.Language
+				if app != nil && name != names[len(names)-1] {
+					match.Framework = app.Framework
+				}
+				return match, nil
+			}
+		}
+	}
+	return match, nil
+}
+
+func formatDetection(d *detection) string {
+	var b bytes.Buffer
+	section := func(title string, tbl *uitable.Table) {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "%s:\n", title)
+		b.Write(tbl.Bytes())
+	}
+	orElse := func(str, def string) string {
+		if str != "" {
+			return str
+		}
+		return def
+	}
+
+	tbl := uitable.New()
+	tbl.AddRow("LANGUAGE", "PERCENT", "BYTES", "COLOR")
+	for _, l := range d.Languages {
+		tbl.AddRow(l.Language, fmt.Sprintf("%.2f%%", l.Percent), l.Bytes, orElse(l.Color, "-"))
+	}
+	section("LANGUAGES", tbl)
+
+	tbl = uitable.New()
+	tbl.AddRow("FILE", "LANGUAGE", "BYTES", "REASON")
+	for _, f := range d.Files {
+		tbl.AddRow(f.Path, orElse(f.Language, "-"), f.Bytes, f.Reason)
+	}
+	section("FILES", tbl)
+
+	tbl = uitable.New()
+	tbl.AddRow("MANIFEST", "LANGUAGE", "FRAMEWORK", "RUNTIME", "PORT", "START COMMAND")
+	for _, a := range d.Apps {
+		port := "-"
+		if a.Port != 0 {
+			port = fmt.Sprint(a.Port)
+		}
+		tbl.AddRow(a.Manifest, a.Language, orElse(a.Framework, "-"), orElse(a.RuntimeVersion, "-"), port, orElse(a.StartCommand, "-"))
+	}
+	section("APPLICATIONS", tbl)
+
+	tbl = uitable.New()
+	tbl.AddRow("REPOSITORY", "PACK", "LANGUAGE", "FRAMEWORK")
+	for _, p := range d.Packs {
+		tbl.AddRow(p.Repository, orElse(p.Pack, "-"), orElse(p.Language, "-"), orElse(p.Framework, "-"))
+	}
+	section("PACKS", tbl)
+	return b.String()
+}
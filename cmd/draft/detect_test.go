@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Azure/draft/pkg/draft/draftpath"
+	"github.com/Azure/draft/pkg/linguist"
+)
+
+func TestDetectDir(t *testing.T) {
+	home := draftpath.Home(filepath.Join("testdata", "drafthome"))
+	d, err := detectDir(home, filepath.Join("testdata", "create", "src", "simple-go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Languages) != 1 || d.Languages[0].Language != "Go" || d.Languages[0].Bytes != 229 {
+		t.Errorf("expected 229 bytes of Go, got %v", d.Languages)
+	}
+	expectedFiles := []*linguist.File{{Path: "main.go", Language: "Go", Bytes: 229, Reason: linguist.ReasonFilename}}
+	if !reflect.DeepEqual(d.Files, expectedFiles) {
+		t.Errorf("expected files %v, got %v", expectedFiles, d.Files)
+	}
+	expectedPacks := []packMatch{{Repository: "github.com/Azure/draft", Pack: "go", Language: "Go"}}
+	if !reflect.DeepEqual(d.Packs, expectedPacks) {
+		t.Errorf("expected packs %v, got %v", expectedPacks, d.Packs)
+	}
+
+	if _, err := detectDir(home, filepath.Join("testdata", "does-not-exist")); err == nil {
+		t.Error("expected an error for a directory that does not exist")
+	}
+}
+
+func TestDetectCmd(t *testing.T) {
+	for _, format := range []string{"table", "json"} {
+		var out bytes.Buffer
+		dc := &detectCmd{
+			out:  &out,
+			home: draftpath.Home(filepath.Join("testdata", "drafthome")),
+			dir:  filepath.Join("testdata", "create", "src", "simple-go"),
+			fmt:  format,
+		}
+		if err := dc.run(); err != nil {
+			t.Fatal(err)
+		}
+		switch format {
+		case "json":
+			var d detection
+			if err := json.Unmarshal(out.Bytes(), &d); err != nil {
+				t.Errorf("expected valid JSON, got %v:\n%s", err, out.String())
+			}
+		case "table":
+			for _, s := range []string{"LANGUAGES:", "main.go", "filename", "PACKS:", "github.com/Azure/draft"} {
+				if !strings.Contains(out.String(), s) {
+					t.Errorf("expected %q in the output, got:\n%s", s, out.String())
+				}
+			}
+		}
+	}
+}
@@ -13,10 +13,12 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/action"
+	helmkube "helm.sh/helm/v3/pkg/kube"
+	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/kubernetes"
+	utilexec "k8s.io/client-go/util/exec"
 
 	"github.com/Azure/draft/pkg/draft/draftpath"
 )
@@ -31,6 +33,8 @@ var (
 	// flagDebug is a signal that the user wants additional output.
 	flagDebug   bool
 	kubeContext string
+	// kubeConfig is the path to the kubeconfig file. Overrides $KUBECONFIG
+	kubeConfig string
 	// draftHome depicts the home directory where all Draft config is stored.
 	draftHome string
 	// displayEmoji shows emoji in the console output
@@ -66,12 +70,13 @@ func newRootCmd(out io.Writer, in io.Reader) *cobra.Command {
 	p := cmd.PersistentFlags()
 	p.StringVar(&draftHome, "home", defaultDraftHome(), "location of your Draft config. Overrides $DRAFT_HOME")
 	p.BoolVar(&flagDebug, "debug", false, "enable verbose output")
-	p.StringVar(&kubeContext, "kube-context", "", "name of the kubeconfig context to use when talking to Tiller")
+	p.StringVar(&kubeContext, "kube-context", "", "name of the kubeconfig context to use")
+	p.StringVar(&kubeConfig, "kubeconfig", "", "path to the kubeconfig file. Overrides $KUBECONFIG")
 	p.BoolVar(&displayEmoji, "display-emoji", true, "display emoji in output")
 
 	cmd.AddCommand(
 		newConfigCmd(out),
-		newCreateCmd(out),
+		newCreateCmd(out, in),
 		newHomeCmd(out),
 		newInitCmd(out, in),
 		newUpCmd(out),
@@ -81,7 +86,15 @@ func newRootCmd(out io.Writer, in io.Reader) *cobra.Command {
 		newDeleteCmd(out),
 		newLogsCmd(out),
 		newHistoryCmd(out),
+		newStatusCmd(out),
+		newExecCmd(out),
+		newShellCmd(out),
+		newSyncCmd(out),
+		newDebugCmd(out),
+		newTasksCmd(out),
+		newDetectCmd(out),
 		newPackCmd(out),
+		newStorageCmd(out),
 	)
 
 	// Find and add plugins
@@ -108,10 +121,12 @@ func homePath() string {
 }
 
 // getKubeClient creates a Kubernetes config and client for a given kubeconfig context.
+//
+// An empty context selects the current context of the kubeconfig file.
 func getKubeClient(context string) (kubernetes.Interface, *rest.Config, error) {
-	config, err := clientcmd.BuildConfigFromFlags("", "")
+	config, err := helmkube.GetConfig(kubeConfig, context, "").ToRESTConfig()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, fmt.Errorf("could not get Kubernetes config for context %q: %s", context, err)
 	}
 	client, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -120,6 +135,26 @@ func getKubeClient(context string) (kubernetes.Interface, *rest.Config, error) {
 	return client, config, nil
 }
 
+// getHelmConfig creates a Helm action configuration for a given kubeconfig context
+// and namespace, sharing the same kubeconfig settings as getKubeClient.
+//
+// An empty namespace selects the namespace of the kubeconfig context.
+func getHelmConfig(context, namespace string) (*action.Configuration, error) {
+	config := new(action.Configuration)
+	getter := helmkube.GetConfig(kubeConfig, context, namespace)
+	if namespace == "" {
+		ns, _, err := getter.ToRawKubeConfigLoader().Namespace()
+		if err != nil {
+			return nil, fmt.Errorf("could not determine namespace for context %q: %s", context, err)
+		}
+		namespace = ns
+	}
+	if err := config.Init(getter, namespace, os.Getenv("HELM_DRIVER"), log.Debugf); err != nil {
+		return nil, fmt.Errorf("could not get Helm configuration for context %q: %s", context, err)
+	}
+	return config, nil
+}
+
 func debug(format string, args ...interface{}) {
 	if flagDebug {
 		format = fmt.Sprintf("[debug] %s\n", format)
@@ -136,6 +171,10 @@ func validateArgs(args, expectedArgs []string) error {
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
+		// propagate the exit status of commands executed in a container.
+		if exitErr, ok := err.(utilexec.ExitError); ok && exitErr.Exited() {
+			os.Exit(exitErr.ExitStatus())
+		}
 		os.Exit(1)
 	}
 }
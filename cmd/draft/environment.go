@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/Azure/draft/pkg/draft/manifest"
 )
@@ -20,3 +22,37 @@ func defaultDraftEnvironment() string {
 	}
 	return env
 }
+
+// resolveKubeContexts returns the kubeconfig contexts to target for an environment
+// declaring the given contexts. An empty context name stands for the current context.
+//
+// --kube-context takes precedence, but must be one of the declared contexts so an
+// environment bound to a cluster is never deployed somewhere else by accident.
+func resolveKubeContexts(environment string, declared []string) ([]string, error) {
+	if kubeContext == "" {
+		if len(declared) == 0 {
+			return []string{""}, nil
+		}
+		return declared, nil
+	}
+	if len(declared) == 0 {
+		return []string{kubeContext}, nil
+	}
+	for _, c := range declared {
+		if c == kubeContext {
+			return []string{kubeContext}, nil
+		}
+	}
+	return nil, fmt.Errorf("kube context %q is not declared for environment %q (declared: %s)", kubeContext, environment, strings.Join(declared, ", "))
+}
+
+// resolveKubeContext is like resolveKubeContexts for commands talking to a single cluster.
+// If the environment declares several contexts, the first one is used unless --kube-context
+// selects another.
+func resolveKubeContext(environment string, declared []string) (string, error) {
+	contexts, err := resolveKubeContexts(environment, declared)
+	if err != nil {
+		return "", err
+	}
+	return contexts[0], nil
+}
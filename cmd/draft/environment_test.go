@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -26,3 +27,36 @@ func TestDefaultEnvironment(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveKubeContexts(t *testing.T) {
+	defer func() {
+		kubeContext = ""
+	}()
+
+	testCases := []struct {
+		flag      string
+		declared  []string
+		expected  []string
+		expectErr bool
+	}{
+		{"", nil, []string{""}, false},
+		{"", []string{"dev"}, []string{"dev"}, false},
+		{"", []string{"east", "west"}, []string{"east", "west"}, false},
+		{"minikube", nil, []string{"minikube"}, false},
+		{"west", []string{"east", "west"}, []string{"west"}, false},
+		{"dev", []string{"prod"}, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("flag-%s", tc.flag), func(t *testing.T) {
+			kubeContext = tc.flag
+			result, err := resolveKubeContexts("production", tc.declared)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("Expected error %v, got %v", tc.expectErr, err)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected contexts %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/term"
+
+	"github.com/Azure/draft/pkg/local"
+)
+
+const execDesc = `Execute a command in a container of the application.
+
+The command runs in a ready pod of the latest build of the application, the same pod
+'draft connect' connects to. A terminal is allocated when stdin is a terminal.
+
+	$ draft exec -- ls -l /app
+	$ draft exec -c sidecar -- cat /etc/config.yaml
+`
+
+const shellDesc = `Open an interactive shell in a container of the application.
+
+bash is used if the container provides it, sh otherwise.
+`
+
+// shellCommand starts bash if available, falling back to sh.
+var shellCommand = []string{"/bin/sh", "-c", "command -v bash >/dev/null 2>&1 && exec bash || exec sh"}
+
+type execCmd struct {
+	in        io.Reader
+	out       io.Writer
+	errOut    io.Writer
+	env       string
+	container string
+	stdin     bool
+	tty       bool
+	command   []string
+}
+
+func newExecCmd(out io.Writer) *cobra.Command {
+	ec := &execCmd{in: os.Stdin, out: out, errOut: os.Stderr}
+	cmd := &cobra.Command{
+		Use:   "exec [-c container] -- command [args...]",
+		Short: "execute a command in a container of the application",
+		Long:  execDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("a command is required, e.g. draft exec -- ls")
+			}
+			ec.command = args
+			if !cmd.Flags().Changed("stdin") {
+				ec.stdin = term.IsTerminal(ec.in)
+			}
+			if !cmd.Flags().Changed("tty") {
+				ec.tty = ec.stdin && term.IsTerminal(ec.in) && term.IsTerminal(ec.out)
+			}
+			return ec.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&ec.container, "container", "c", "", "name of the container to execute the command in")
+	f.BoolVarP(&ec.stdin, "stdin", "i", false, "pass stdin to the command (default: true if stdin is a terminal)")
+	f.BoolVarP(&ec.tty, "tty", "t", false, "allocate a terminal for the command (default: true if stdin and stdout are terminals)")
+	f.StringVarP(&ec.env, environmentFlagName, environmentFlagShorthand, defaultDraftEnvironment(), environmentFlagUsage)
+	return cmd
+}
+
+func newShellCmd(out io.Writer) *cobra.Command {
+	ec := &execCmd{in: os.Stdin, out: out, errOut: os.Stderr, stdin: true, tty: true, command: shellCommand}
+	cmd := &cobra.Command{
+		Use:   "shell",
+		Short: "open an interactive shell in a container of the application",
+		Long:  shellDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !term.IsTerminal(ec.in) {
+				return errors.New("draft shell requires a terminal; use draft exec instead")
+			}
+			return ec.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&ec.container, "container", "c", "", "name of the container to open a shell in")
+	f.StringVarP(&ec.env, environmentFlagName, environmentFlagShorthand, defaultDraftEnvironment(), environmentFlagUsage)
+	return cmd
+}
+
+func (e *execCmd) run() error {
+	app, err := local.DeployedApplication(draftToml, e.env)
+	if err != nil {
+		return err
+	}
+	kctx, err := resolveKubeContext(e.env, app.KubeContexts)
+	if err != nil {
+		return err
+	}
+	clientset, config, err := getKubeClient(kctx)
+	if err != nil {
+		return err
+	}
+	buildID, err := latestBuildID(app, e.env)
+	if err != nil {
+		return err
+	}
+	pod, err := app.Pod(clientset, buildID)
+	if err != nil {
+		return err
+	}
+
+	opts := local.ExecOptions{
+		Container: e.container,
+		Command:   e.command,
+		Stdout:    e.out,
+		Stderr:    e.errOut,
+		TTY:       e.tty,
+	}
+	if e.stdin {
+		opts.Stdin = e.in
+	}
+	return app.Exec(clientset, config, pod.Name, opts)
+}
@@ -14,7 +14,6 @@ import (
 
 	"github.com/Azure/draft/pkg/local"
 	"github.com/Azure/draft/pkg/storage"
-	"github.com/Azure/draft/pkg/storage/kube/configmap"
 )
 
 const historyDesc = `Display the build history of a Draft application.`
@@ -39,6 +38,8 @@ func newHistoryCmd(out io.Writer) *cobra.Command {
 		},
 	}
 
+	cmd.AddCommand(newHistoryPruneCmd(out))
+
 	f := cmd.Flags()
 	f.Int64Var(&hc.max, "max", 256, "maximum number of results to include in history")
 	f.UintVar(&hc.colWidth, "col-width", 60, "specifies the max column width of output")
@@ -53,11 +54,14 @@ func (cmd *historyCmd) run() error {
 	if err != nil {
 		return err
 	}
-	client, _, err := getKubeClient(kubeContext)
+	kctx, err := resolveKubeContext(cmd.env, app.KubeContexts)
 	if err != nil {
-		return fmt.Errorf("Could not get a kube client: %v", err)
+		return err
+	}
+	store, err := newStore("", kctx, storageNamespace(app.StorageNamespace, app.Namespace))
+	if err != nil {
+		return err
 	}
-	store := configmap.NewConfigMaps(client.CoreV1().ConfigMaps("default"))
 
 	// get history from store
 	h, err := getHistory(context.Background(), store, app.Name, cmd.max)
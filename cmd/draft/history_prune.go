@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+	"helm.sh/helm/v3/pkg/release"
+
+	"github.com/Azure/draft/pkg/local"
+	"github.com/Azure/draft/pkg/storage"
+)
+
+const historyPruneDesc = `Remove old builds from the build history of a Draft application.
+
+The build records and their build logs are deleted. Builds are kept unless they fall outside
+the --keep most recent builds and, if given, are older than --older-than. With --helm, Helm
+release revisions beyond the --keep most recent ones are deleted as well.
+
+A retention policy applied after every 'draft up' can be configured with
+'draft config set history-keep <n>' and 'draft config set history-older-than <age>'.
+`
+
+type historyPruneCmd struct {
+	out       io.Writer
+	env       string
+	keep      int
+	olderThan string
+	helm      bool
+}
+
+func newHistoryPruneCmd(out io.Writer) *cobra.Command {
+	pc := &historyPruneCmd{out: out}
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "remove old builds from the build history",
+		Long:  historyPruneDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pc.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.IntVar(&pc.keep, "keep", 0, "number of most recent builds to keep")
+	f.StringVar(&pc.olderThan, "older-than", "", "only remove builds older than this age (e.g. 30d, 12h)")
+	f.BoolVar(&pc.helm, "helm", false, "also remove Helm release revisions beyond the --keep most recent ones")
+	f.StringVarP(&pc.env, environmentFlagName, environmentFlagShorthand, defaultDraftEnvironment(), environmentFlagUsage)
+	return cmd
+}
+
+func (cmd *historyPruneCmd) run() error {
+	if cmd.keep <= 0 && cmd.olderThan == "" {
+		return errors.New("at least one of --keep or --older-than is required")
+	}
+	if cmd.helm && cmd.keep <= 0 {
+		return errors.New("--helm requires --keep")
+	}
+	olderThan, err := parseAge(cmd.olderThan)
+	if err != nil {
+		return err
+	}
+	app, err := local.DeployedApplication(draftToml, cmd.env)
+	if err != nil {
+		return err
+	}
+	kctx, err := resolveKubeContext(cmd.env, app.KubeContexts)
+	if err != nil {
+		return err
+	}
+	policy := storage.RetentionPolicy{Keep: cmd.keep, OlderThan: olderThan}
+	return pruneHistory(cmd.out, app, kctx, policy, cmd.helm)
+}
+
+// pruneHistory removes the builds of app selected by the retention policy along with their
+// build logs. If helm is set, Helm release revisions beyond policy.Keep are removed too.
+func pruneHistory(out io.Writer, app *local.App, kubeContext string, policy storage.RetentionPolicy, helm bool) error {
+	store, err := newStore("", kubeContext, storageNamespace(app.StorageNamespace, app.Namespace))
+	if err != nil {
+		return err
+	}
+	pruned, err := storage.Prune(context.Background(), store, app.Name, policy)
+	deleteBuildLogs(store, app.Name, pruned)
+	if err != nil {
+		return fmt.Errorf("failed to prune build history of %q: %v", app.Name, err)
+	}
+	fmt.Fprintf(out, "removed %d build(s) of '%s'\n", len(pruned), app.Name)
+
+	if helm && policy.Keep > 0 {
+		n, err := pruneReleaseHistory(app, kubeContext, policy.Keep)
+		if err != nil {
+			return fmt.Errorf("failed to prune release history of %q: %v", app.Name, err)
+		}
+		fmt.Fprintf(out, "removed %d release revision(s) of '%s'\n", n, app.Name)
+	}
+	return nil
+}
+
+// deleteBuildLogs deletes the local and shared build logs of builds. Failures are not fatal.
+func deleteBuildLogs(store storage.Store, appName string, builds []*storage.Object) {
+	logStore, shared := store.(storage.LogStore)
+	for _, build := range builds {
+		if ref := build.GetLogsFileRef(); ref != "" {
+			if err := os.Remove(ref); err != nil && !os.IsNotExist(err) {
+				debug("could not remove build logs %s: %v", ref, err)
+			}
+		}
+		if shared {
+			if err := logStore.DeleteLogs(context.Background(), appName, build.GetBuildID()); err != nil {
+				debug("could not remove shared build logs of %s: %v", build.GetBuildID(), err)
+			}
+		}
+	}
+}
+
+// pruneReleaseHistory deletes the oldest revisions of the app's Helm release until keep
+// revisions remain. The deployed revision is never deleted.
+func pruneReleaseHistory(app *local.App, kubeContext string, keep int) (int, error) {
+	cfg, err := getHelmConfig(kubeContext, app.Namespace)
+	if err != nil {
+		return 0, err
+	}
+	revisions, err := cfg.Releases.History(app.Name)
+	if err != nil {
+		return 0, err
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Version < revisions[j].Version
+	})
+	var pruned int
+	for _, rls := range revisions {
+		if len(revisions)-pruned <= keep {
+			break
+		}
+		if rls.Info != nil && rls.Info.Status == release.StatusDeployed {
+			continue
+		}
+		if _, err := cfg.Releases.Delete(rls.Name, rls.Version); err != nil {
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// configuredRetentionPolicy returns the retention policy set in $DRAFT_HOME/config.toml and
+// whether Helm release revisions should be pruned too. ok is false if no policy is configured.
+func configuredRetentionPolicy() (policy storage.RetentionPolicy, helm, ok bool, err error) {
+	keep, hasKeep := globalConfig[historyKeep.name]
+	age, hasAge := globalConfig[historyOlderThan.name]
+	if !hasKeep && !hasAge {
+		return policy, false, false, nil
+	}
+	if hasKeep {
+		if policy.Keep, err = strconv.Atoi(keep); err != nil || policy.Keep < 1 {
+			return policy, false, false, fmt.Errorf("invalid %s %q: expected a positive number", historyKeep.name, keep)
+		}
+	}
+	if policy.OlderThan, err = parseAge(age); err != nil {
+		return policy, false, false, err
+	}
+	// without a number of builds to keep, only an age keeps the latest build from being pruned.
+	if policy.Keep == 0 && policy.OlderThan == 0 {
+		return policy, false, false, fmt.Errorf("invalid %s %q: expected a positive age", historyOlderThan.name, age)
+	}
+	helm, _ = strconv.ParseBool(globalConfig[historyPruneHelm.name])
+	return policy, helm, true, nil
+}
+
+// parseAge parses a duration such as "12h" or "30d". Days are not supported by
+// time.ParseDuration, so a "d" suffix is handled here.
+func parseAge(age string) (time.Duration, error) {
+	if age == "" {
+		return 0, nil
+	}
+	if days := strings.TrimSuffix(age, "d"); days != age {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", age)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(age)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", age)
+	}
+	return d, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/draft/pkg/storage"
+)
+
+func TestParseAge(t *testing.T) {
+	testCases := []struct {
+		age       string
+		expected  time.Duration
+		expectErr bool
+	}{
+		{"", 0, false},
+		{"30d", 30 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"xd", 0, true},
+		{"-1d", 0, true},
+		{"-5m", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.age, func(t *testing.T) {
+			d, err := parseAge(tc.age)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("Expected an error for %q", tc.age)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, d)
+			}
+		})
+	}
+}
+
+func TestConfiguredRetentionPolicy(t *testing.T) {
+	defer func(config DraftConfig) { globalConfig = config }(globalConfig)
+
+	testCases := []struct {
+		name      string
+		config    DraftConfig
+		expected  storage.RetentionPolicy
+		ok        bool
+		expectErr bool
+	}{
+		{"unset", DraftConfig{}, storage.RetentionPolicy{}, false, false},
+		{"keep", DraftConfig{"history-keep": "5"}, storage.RetentionPolicy{Keep: 5}, true, false},
+		{"older than", DraftConfig{"history-older-than": "30d"}, storage.RetentionPolicy{OlderThan: 30 * 24 * time.Hour}, true, false},
+		{"keep and older than", DraftConfig{"history-keep": "1", "history-older-than": "12h"}, storage.RetentionPolicy{Keep: 1, OlderThan: 12 * time.Hour}, true, false},
+		{"keep zero", DraftConfig{"history-keep": "0"}, storage.RetentionPolicy{}, false, true},
+		{"keep zero and older than", DraftConfig{"history-keep": "0", "history-older-than": "30d"}, storage.RetentionPolicy{}, false, true},
+		{"negative keep", DraftConfig{"history-keep": "-1"}, storage.RetentionPolicy{}, false, true},
+		{"zero age", DraftConfig{"history-older-than": "0d"}, storage.RetentionPolicy{}, false, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			globalConfig = tc.config
+			policy, _, ok, err := configuredRetentionPolicy()
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("Expected an error for %v", tc.config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tc.ok || policy != tc.expected {
+				t.Errorf("Expected %v (%v), got %v (%v)", tc.expected, tc.ok, policy, ok)
+			}
+		})
+	}
+}
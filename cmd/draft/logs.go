@@ -1,31 +1,55 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"time"
 
 	"github.com/Azure/draft/pkg/draft/draftpath"
 	"github.com/Azure/draft/pkg/local"
+	"github.com/Azure/draft/pkg/storage"
 	"github.com/hpcloud/tail"
 	"github.com/spf13/cobra"
 )
 
+// logsIdleTimeout is how long draft logs --tail follows the stored logs of a build that
+// writes none.
+const logsIdleTimeout = 5 * time.Minute
+
 const logsDesc = `This command outputs logs from the draft server to help debug builds.`
 
+const logsLongDesc = `This command outputs logs from the draft server to help debug builds.
+
+With --app, the runtime logs of every pod of the application are streamed instead,
+including those of pods created by later rollouts. Each line is prefixed with the
+name of the pod and container it came from.
+`
+
 var (
 	runningEnvironment string
 )
 
 type logsCmd struct {
 	out     io.Writer
+	app     *local.App
 	appName string
 	buildID string
 	line    uint
 	tail    bool
 	args    []string
 	home    draftpath.Home
+
+	runtime   bool
+	since     time.Duration
+	grep      string
+	container string
+	previous  bool
 }
 
 func newLogsCmd(out io.Writer) *cobra.Command {
@@ -37,22 +61,23 @@ func newLogsCmd(out io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "logs <build-id>",
 		Short:   logsDesc,
-		Long:    logsDesc,
+		Long:    logsLongDesc,
 		PreRunE: lc.complete,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			deployedApp, err := local.DeployedApplication(draftToml, runningEnvironment)
 			if err != nil {
 				return err
 			}
+			lc.app = deployedApp
 			lc.appName = deployedApp.Name
-			b, err := getLatestBuildID(lc.appName)
-			if err != nil {
-				return fmt.Errorf("cannot get latest build: %v", err)
-			}
-			lc.buildID = b
 
+			if lc.runtime {
+				return lc.appLogs()
+			}
 			if len(args) > 0 {
 				lc.buildID = args[0]
+			} else if lc.buildID, err = latestBuildID(deployedApp, runningEnvironment); err != nil {
+				return fmt.Errorf("cannot get latest build: %v", err)
 			}
 			return lc.run(cmd, args)
 		},
@@ -61,6 +86,11 @@ func newLogsCmd(out io.Writer) *cobra.Command {
 	f := cmd.Flags()
 	f.BoolVar(&lc.tail, "tail", false, "tail the logs file as it's being written")
 	f.UintVar(&lc.line, "line", 20, "line location to tail from (offset from end of file)")
+	f.BoolVar(&lc.runtime, "app", false, "stream the runtime logs of every application pod instead of build logs")
+	f.DurationVar(&lc.since, "since", 0, "with --app, only show logs newer than this duration (e.g. 5m)")
+	f.StringVar(&lc.grep, "grep", "", "with --app, only show log lines matching this regular expression")
+	f.StringVarP(&lc.container, "container", "c", "", "with --app, only show logs of this container")
+	f.BoolVar(&lc.previous, "previous", false, "with --app, show the logs of the previous instance of each container")
 	f.StringVarP(&runningEnvironment, environmentFlagName, environmentFlagShorthand, defaultDraftEnvironment(), environmentFlagUsage)
 	return cmd
 }
@@ -71,14 +101,75 @@ func (l *logsCmd) complete(_ *cobra.Command, args []string) error {
 }
 
 func (l *logsCmd) run(_ *cobra.Command, _ []string) error {
+	// build logs are read from the storage engine if the build ran on another machine.
+	if _, err := os.Stat(l.logsFile()); os.IsNotExist(err) {
+		store, err := l.logStore()
+		if err != nil {
+			return fmt.Errorf("could not read logs for %s: %v", l.buildID, err)
+		}
+		if l.tail {
+			return l.tailSharedLogs(store, int(l.line))
+		}
+		return l.dumpSharedLogs(store)
+	}
 	if l.tail {
 		return l.tailLogs(int64(l.line))
 	}
 	return l.dumpLogs()
 }
 
+// appLogs streams the runtime logs of the application pods.
+func (l *logsCmd) appLogs() error {
+	opts := local.LogOptions{
+		Container: l.container,
+		Since:     l.since,
+		Previous:  l.previous,
+		Follow:    !l.previous,
+	}
+	if l.grep != "" {
+		re, err := regexp.Compile(l.grep)
+		if err != nil {
+			return fmt.Errorf("invalid --grep expression: %v", err)
+		}
+		opts.Grep = re
+	}
+	kctx, err := resolveKubeContext(runningEnvironment, l.app.KubeContexts)
+	if err != nil {
+		return err
+	}
+	clientset, _, err := getKubeClient(kctx)
+	if err != nil {
+		return err
+	}
+	return l.app.StreamLogs(context.Background(), clientset, l.out, opts)
+}
+
+func (l *logsCmd) logsFile() string {
+	return filepath.Join(l.home.Logs(), l.appName, l.buildID)
+}
+
+func (l *logsCmd) store() (storage.Store, error) {
+	kctx, err := resolveKubeContext(runningEnvironment, l.app.KubeContexts)
+	if err != nil {
+		return nil, err
+	}
+	return newStore("", kctx, storageNamespace(l.app.StorageNamespace, l.app.Namespace))
+}
+
+func (l *logsCmd) logStore() (storage.LogStore, error) {
+	store, err := l.store()
+	if err != nil {
+		return nil, err
+	}
+	logStore, ok := store.(storage.LogStore)
+	if !ok {
+		return nil, errors.New("the configured storage engine does not store build logs")
+	}
+	return logStore, nil
+}
+
 func (l *logsCmd) dumpLogs() error {
-	f, err := os.Open(filepath.Join(l.home.Logs(), l.appName, l.buildID))
+	f, err := os.Open(l.logsFile())
 	if err != nil {
 		return fmt.Errorf("could not read logs for %s: %v", l.buildID, err)
 	}
@@ -87,8 +178,15 @@ func (l *logsCmd) dumpLogs() error {
 	return nil
 }
 
+func (l *logsCmd) dumpSharedLogs(store storage.LogStore) error {
+	if _, _, err := storage.CopyLogs(context.Background(), l.out, store, l.appName, l.buildID, storage.LogPosition{}); err != nil {
+		return fmt.Errorf("could not read logs for %s: %v", l.buildID, err)
+	}
+	return nil
+}
+
 func (l *logsCmd) tailLogs(offset int64) error {
-	t, err := tail.TailFile(filepath.Join(l.home.Logs(), l.appName, l.buildID), tail.Config{
+	t, err := tail.TailFile(l.logsFile(), tail.Config{
 		Location: &tail.SeekInfo{Offset: -offset, Whence: os.SEEK_END},
 		Logger:   tail.DiscardingLogger,
 		Follow:   true,
@@ -102,3 +200,57 @@ func (l *logsCmd) tailLogs(offset int64) error {
 	}
 	return t.Wait()
 }
+
+// tailSharedLogs prints the last lines of the stored build logs and follows the build
+// until its final log chunk is stored. It stops following once the build is stored, as the
+// build failed before storing its final chunk, or when no logs were written for
+// logsIdleTimeout.
+func (l *logsCmd) tailSharedLogs(store storage.LogStore, lines int) error {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	next, final, err := storage.CopyLogs(ctx, &buf, store, l.appName, l.buildID, storage.LogPosition{})
+	if err != nil {
+		return fmt.Errorf("could not read logs for %s: %v", l.buildID, err)
+	}
+	l.out.Write(lastLines(buf.Bytes(), lines))
+	builds, _ := store.(storage.Store)
+	lastWrite := time.Now()
+	for !final {
+		time.Sleep(storage.LogFlushInterval)
+		from := next
+		if next, final, err = storage.CopyLogs(ctx, l.out, store, l.appName, l.buildID, from); err != nil {
+			return err
+		}
+		if next != from {
+			lastWrite = time.Now()
+			continue
+		}
+		if builds != nil {
+			if _, err := builds.GetBuild(ctx, l.appName, l.buildID); err == nil {
+				// the build is over; print what it stored before it did.
+				_, _, err = storage.CopyLogs(ctx, l.out, store, l.appName, l.buildID, next)
+				return err
+			}
+		}
+		if time.Since(lastWrite) > logsIdleTimeout {
+			return fmt.Errorf("no logs were written for %s in %v, the build may have died", l.buildID, logsIdleTimeout)
+		}
+	}
+	return nil
+}
+
+// lastLines returns the last n lines of b.
+func lastLines(b []byte, n int) []byte {
+	end := len(b)
+	if end > 0 && b[end-1] == '\n' {
+		end--
+	}
+	for i := end - 1; i >= 0; i-- {
+		if b[i] == '\n' {
+			if n--; n == 0 {
+				return b[i+1:]
+			}
+		}
+	}
+	return b
+}
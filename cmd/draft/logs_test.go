@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestLastLines(t *testing.T) {
+	testCases := []struct {
+		name     string
+		logs     string
+		n        int
+		expected string
+	}{
+		{"fewer lines", "a\nb\n", 5, "a\nb\n"},
+		{"last lines", "a\nb\nc\n", 2, "b\nc\n"},
+		{"no trailing newline", "a\nb\nc", 1, "c"},
+		{"empty", "", 3, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := string(lastLines([]byte(tc.logs), tc.n)); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -129,7 +129,7 @@ func loadPlugins(baseCmd *cobra.Command, home draftpath.Home, out io.Writer, in
 func manuallyProcessArgs(args []string) ([]string, []string) {
 	known := []string{}
 	unknown := []string{}
-	kvargs := []string{"--host", "--kube-context", "--home"}
+	kvargs := []string{"--host", "--kube-context", "--kubeconfig", "--home"}
 	knownArg := func(a string) bool {
 		for _, pre := range kvargs {
 			if strings.HasPrefix(a, pre+"=") {
@@ -142,7 +142,7 @@ func manuallyProcessArgs(args []string) ([]string, []string) {
 		switch a := args[i]; a {
 		case "--debug":
 			known = append(known, a)
-		case "--host", "--kube-context", "--home":
+		case "--host", "--kube-context", "--kubeconfig", "--home":
 			known = append(known, a, args[i+1])
 			i++
 		default:
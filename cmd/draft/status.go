@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/Azure/draft/pkg/kube/podutil"
+	"github.com/Azure/draft/pkg/local"
+	"github.com/Azure/draft/pkg/storage"
+)
+
+const statusDesc = `Display the status of a deployed Draft application.
+
+The status includes the Helm release status and revision, the latest build, the readiness
+of the application's Deployments and of the pods running the latest build, the endpoints
+of its Services, the hosts of its Ingresses and recent warning events.
+`
+
+// maxStatusEvents is the maximum number of warning events displayed by draft status.
+const maxStatusEvents = 10
+
+type statusCmd struct {
+	out io.Writer
+	env string
+	fmt string
+}
+
+func newStatusCmd(out io.Writer) *cobra.Command {
+	sc := &statusCmd{out: out}
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "display the status of a deployed application",
+		Long:  statusDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return sc.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&sc.fmt, "output", "o", "table", "prints the output in the specified format (json|table|yaml)")
+	f.StringVarP(&sc.env, environmentFlagName, environmentFlagShorthand, defaultDraftEnvironment(), environmentFlagUsage)
+	return cmd
+}
+
+type appStatus struct {
+	Name        string             `json:"name"`
+	Namespace   string             `json:"namespace"`
+	Status      string             `json:"status"`
+	Revision    int                `json:"revision"`
+	BuildID     string             `json:"buildID,omitempty"`
+	Deployments []deploymentStatus `json:"deployments,omitempty"`
+	Pods        []podStatus        `json:"pods,omitempty"`
+	Services    []serviceStatus    `json:"services,omitempty"`
+	Ingresses   []ingressStatus    `json:"ingresses,omitempty"`
+	Warnings    []warningEvent     `json:"warnings,omitempty"`
+}
+
+type deploymentStatus struct {
+	Name     string `json:"name"`
+	Desired  int32  `json:"desired"`
+	Ready    int32  `json:"ready"`
+	UpToDate int32  `json:"upToDate"`
+}
+
+type podStatus struct {
+	Name     string `json:"name"`
+	Phase    string `json:"phase"`
+	Ready    bool   `json:"ready"`
+	Restarts int32  `json:"restarts"`
+}
+
+type serviceStatus struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	ClusterIP string   `json:"clusterIP"`
+	Ports     []string `json:"ports,omitempty"`
+	Endpoints []string `json:"endpoints,omitempty"`
+}
+
+type ingressStatus struct {
+	Name  string   `json:"name"`
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+type warningEvent struct {
+	Object  string    `json:"object"`
+	Reason  string    `json:"reason"`
+	Message string    `json:"message"`
+	Count   int32     `json:"count"`
+	Last    time.Time `json:"lastSeen"`
+}
+
+func (cmd *statusCmd) run() error {
+	app, err := local.DeployedApplication(draftToml, cmd.env)
+	if err != nil {
+		return err
+	}
+	kctx, err := resolveKubeContext(cmd.env, app.KubeContexts)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	status := &appStatus{Name: app.Name, Namespace: app.Namespace}
+
+	// release status
+	cfg, err := getHelmConfig(kctx, app.Namespace)
+	if err != nil {
+		return err
+	}
+	rls, err := action.NewStatus(cfg).Run(app.Name)
+	if err != nil {
+		return fmt.Errorf("could not get the status of release %q: %v", app.Name, err)
+	}
+	status.Revision = rls.Version
+	if rls.Info != nil {
+		status.Status = rls.Info.Status.String()
+	}
+
+	// latest build
+	store, err := newStore("", kctx, storageNamespace(app.StorageNamespace, app.Namespace))
+	if err != nil {
+		return err
+	}
+	if builds, err := store.GetBuilds(ctx, app.Name); err == nil && len(builds) > 0 {
+		storage.SortByCreatedAt(builds)
+		status.BuildID = builds[len(builds)-1].GetBuildID()
+	}
+
+	clientset, _, err := getKubeClient(kctx)
+	if err != nil {
+		return err
+	}
+	if err := collectKubeStatus(ctx, clientset, status, releaseResources(rls.Manifest)); err != nil {
+		return err
+	}
+
+	var output []byte
+	switch cmd.fmt {
+	case "yaml":
+		if output, err = yaml.Marshal(status); err != nil {
+			return err
+		}
+	case "json":
+		if output, err = json.Marshal(status); err != nil {
+			return err
+		}
+		var b bytes.Buffer
+		json.Indent(&b, output, "", "  ")
+		output = b.Bytes()
+	case "table":
+		output = formatStatus(status)
+	default:
+		return fmt.Errorf("unknown output format %q", cmd.fmt)
+	}
+	fmt.Fprintln(cmd.out, string(output))
+	return nil
+}
+
+// releaseResources returns the names of the resources in a release manifest, by kind.
+func releaseResources(manifest string) map[string][]string {
+	resources := make(map[string][]string)
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		var head releaseutil.SimpleHead
+		if err := yaml.Unmarshal([]byte(doc), &head); err != nil || head.Metadata == nil {
+			continue
+		}
+		resources[head.Kind] = append(resources[head.Kind], head.Metadata.Name)
+	}
+	for kind := range resources {
+		sort.Strings(resources[kind])
+	}
+	return resources
+}
+
+// servesIngressV1 reports whether the cluster serves Ingresses in networking.k8s.io/v1, which
+// clusters from Kubernetes 1.22 on serve exclusively.
+func servesIngressV1(clientset kubernetes.Interface) bool {
+	resources, err := clientset.Discovery().ServerResourcesForGroupVersion(networkingv1.SchemeGroupVersion.String())
+	if err != nil {
+		return false
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == "ingresses" {
+			return true
+		}
+	}
+	return false
+}
+
+// getIngress gets an Ingress from networking.k8s.io/v1 if the cluster serves it, else from
+// networking.k8s.io/v1beta1. The client has no networking.k8s.io/v1 Ingress type, but the
+// hosts of its rules are found at the same place as in v1beta1, so it is decoded as such.
+func getIngress(ctx context.Context, clientset kubernetes.Interface, servesV1 bool, ns, name string) (*networkingv1beta1.Ingress, error) {
+	if !servesV1 {
+		return clientset.NetworkingV1beta1().Ingresses(ns).Get(ctx, name, metav1.GetOptions{})
+	}
+	raw, err := clientset.NetworkingV1().RESTClient().Get().Namespace(ns).Resource("ingresses").Name(name).DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ing := &networkingv1beta1.Ingress{}
+	if err := json.Unmarshal(raw, ing); err != nil {
+		return nil, fmt.Errorf("could not decode Ingress %s: %v", name, err)
+	}
+	return ing, nil
+}
+
+// collectKubeStatus adds the status of the release's Deployments, Services and Ingresses,
+// of the pods running the build status.BuildID and recent warning events to status.
+func collectKubeStatus(ctx context.Context, clientset kubernetes.Interface, status *appStatus, resources map[string][]string) error {
+	ns := status.Namespace
+	involved := make(map[string]bool)
+
+	for _, name := range resources["Deployment"] {
+		d, err := clientset.AppsV1().Deployments(ns).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		ds := deploymentStatus{Name: d.Name, Ready: d.Status.ReadyReplicas, UpToDate: d.Status.UpdatedReplicas}
+		if d.Spec.Replicas != nil {
+			ds.Desired = *d.Spec.Replicas
+		}
+		status.Deployments = append(status.Deployments, ds)
+		involved[d.Name] = true
+	}
+
+	var annotations map[string]string
+	if status.BuildID != "" {
+		annotations = map[string]string{local.BuildIDKey: status.BuildID}
+	}
+	pods, err := podutil.ListPods(ns, map[string]string{local.DraftLabelKey: status.Name}, annotations, clientset)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		ps := podStatus{Name: pod.Name, Phase: string(pod.Status.Phase), Ready: podutil.IsPodReady(&pod)}
+		for _, cs := range pod.Status.ContainerStatuses {
+			ps.Restarts += cs.RestartCount
+		}
+		status.Pods = append(status.Pods, ps)
+		involved[pod.Name] = true
+	}
+
+	for _, name := range resources["Service"] {
+		svc, err := clientset.CoreV1().Services(ns).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		ss := serviceStatus{Name: svc.Name, Type: string(svc.Spec.Type), ClusterIP: svc.Spec.ClusterIP}
+		for _, p := range svc.Spec.Ports {
+			ss.Ports = append(ss.Ports, fmt.Sprintf("%d/%s", p.Port, p.Protocol))
+		}
+		if ep, err := clientset.CoreV1().Endpoints(ns).Get(ctx, name, metav1.GetOptions{}); err == nil {
+			for _, subset := range ep.Subsets {
+				for _, addr := range subset.Addresses {
+					for _, p := range subset.Ports {
+						ss.Endpoints = append(ss.Endpoints, fmt.Sprintf("%s:%d", addr.IP, p.Port))
+					}
+				}
+			}
+		}
+		status.Services = append(status.Services, ss)
+		involved[svc.Name] = true
+	}
+
+	ingressV1 := servesIngressV1(clientset)
+	for _, name := range resources["Ingress"] {
+		ing, err := getIngress(ctx, clientset, ingressV1, ns, name)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		is := ingressStatus{Name: ing.Name}
+		for _, rule := range ing.Spec.Rules {
+			if rule.Host != "" {
+				is.Hosts = append(is.Hosts, rule.Host)
+			}
+		}
+		status.Ingresses = append(status.Ingresses, is)
+	}
+
+	events, err := clientset.CoreV1().Events(ns).List(ctx, metav1.ListOptions{FieldSelector: "type=" + v1.EventTypeWarning})
+	if err != nil {
+		return err
+	}
+	for _, e := range events.Items {
+		if e.Type != v1.EventTypeWarning || !involvedInRelease(e.InvolvedObject.Name, involved, status.Deployments) {
+			continue
+		}
+		status.Warnings = append(status.Warnings, warningEvent{
+			Object:  strings.ToLower(e.InvolvedObject.Kind) + "/" + e.InvolvedObject.Name,
+			Reason:  e.Reason,
+			Message: e.Message,
+			Count:   e.Count,
+			Last:    e.LastTimestamp.Time,
+		})
+	}
+	sort.SliceStable(status.Warnings, func(i, j int) bool {
+		return status.Warnings[i].Last.After(status.Warnings[j].Last)
+	})
+	if len(status.Warnings) > maxStatusEvents {
+		status.Warnings = status.Warnings[:maxStatusEvents]
+	}
+	return nil
+}
+
+// involvedInRelease returns whether an event about the object with the given name concerns
+// the application. Events about replica sets and pods of older builds are attributed to the
+// deployment they are named after.
+func involvedInRelease(name string, involved map[string]bool, deployments []deploymentStatus) bool {
+	if involved[name] {
+		return true
+	}
+	for _, d := range deployments {
+		if strings.HasPrefix(name, d.Name+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+func formatStatus(s *appStatus) []byte {
+	var b bytes.Buffer
+	orElse := func(str, def string) string {
+		if str != "" {
+			return str
+		}
+		return def
+	}
+
+	tbl := uitable.New()
+	tbl.AddRow("NAME:", s.Name)
+	tbl.AddRow("NAMESPACE:", s.Namespace)
+	tbl.AddRow("STATUS:", s.Status)
+	tbl.AddRow("REVISION:", s.Revision)
+	tbl.AddRow("BUILD_ID:", orElse(s.BuildID, "-"))
+	b.Write(tbl.Bytes())
+
+	section := func(title string, tbl *uitable.Table) {
+		fmt.Fprintf(&b, "\n\n%s:\n", title)
+		b.Write(tbl.Bytes())
+	}
+	if len(s.Deployments) > 0 {
+		tbl := uitable.New()
+		tbl.AddRow("NAME", "READY", "UP-TO-DATE")
+		for _, d := range s.Deployments {
+			tbl.AddRow(d.Name, fmt.Sprintf("%d/%d", d.Ready, d.Desired), d.UpToDate)
+		}
+		section("DEPLOYMENTS", tbl)
+	}
+	tbl = uitable.New()
+	tbl.AddRow("NAME", "STATUS", "READY", "RESTARTS")
+	for _, p := range s.Pods {
+		tbl.AddRow(p.Name, p.Phase, p.Ready, p.Restarts)
+	}
+	section("PODS", tbl)
+	if len(s.Services) > 0 {
+		tbl := uitable.New()
+		tbl.AddRow("NAME", "TYPE", "CLUSTER-IP", "PORTS", "ENDPOINTS")
+		for _, svc := range s.Services {
+			tbl.AddRow(svc.Name, svc.Type, svc.ClusterIP, strings.Join(svc.Ports, ","), orElse(strings.Join(svc.Endpoints, ","), "<none>"))
+		}
+		section("SERVICES", tbl)
+	}
+	if len(s.Ingresses) > 0 {
+		tbl := uitable.New()
+		tbl.AddRow("NAME", "HOSTS")
+		for _, ing := range s.Ingresses {
+			tbl.AddRow(ing.Name, orElse(strings.Join(ing.Hosts, ","), "*"))
+		}
+		section("INGRESSES", tbl)
+	}
+	if len(s.Warnings) > 0 {
+		tbl := uitable.New()
+		tbl.MaxColWidth = 80
+		tbl.AddRow("LAST SEEN", "OBJECT", "REASON", "MESSAGE")
+		for _, e := range s.Warnings {
+			tbl.AddRow(time.Since(e.Last).Round(time.Second).String()+" ago", e.Object, e.Reason, e.Message)
+		}
+		section("WARNINGS", tbl)
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const statusTestManifest = `---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: app-web
+---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app-web
+`
+
+func TestReleaseResources(t *testing.T) {
+	expected := map[string][]string{
+		"Service":    {"app-web"},
+		"Deployment": {"app-web"},
+	}
+	if got := releaseResources(statusTestManifest); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected resources %v, got %v", expected, got)
+	}
+}
+
+func TestCollectKubeStatus(t *testing.T) {
+	replicas := int32(2)
+	ready := v1.PodCondition{Type: v1.PodReady, Status: v1.ConditionTrue}
+	pod := func(name, buildID string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				Labels:      map[string]string{"draft": "app"},
+				Annotations: map[string]string{"buildID": buildID},
+			},
+			Status: v1.PodStatus{
+				Phase:             v1.PodRunning,
+				Conditions:        []v1.PodCondition{ready},
+				ContainerStatuses: []v1.ContainerStatus{{Name: "app", RestartCount: 1}},
+			},
+		}
+	}
+	warning := func(name, kind, object string) *v1.Event {
+		return &v1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "default"},
+			InvolvedObject: v1.ObjectReference{Kind: kind, Name: object},
+			Type:           v1.EventTypeWarning,
+			Reason:         "BackOff",
+			LastTimestamp:  metav1.NewTime(time.Unix(1000, 0)),
+		}
+	}
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-web", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{ReadyReplicas: 1, UpdatedReplicas: 2},
+		},
+		pod("app-web-1", "01NEW"),
+		pod("app-web-2", "01OLD"),
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-web", Namespace: "default"},
+			Spec: v1.ServiceSpec{
+				Type:      v1.ServiceTypeClusterIP,
+				ClusterIP: "10.0.0.1",
+				Ports:     []v1.ServicePort{{Port: 80, Protocol: v1.ProtocolTCP}},
+			},
+		},
+		&v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-web", Namespace: "default"},
+			Subsets: []v1.EndpointSubset{{
+				Addresses: []v1.EndpointAddress{{IP: "10.1.0.4"}},
+				Ports:     []v1.EndpointPort{{Port: 8080}},
+			}},
+		},
+		warning("e1", "Pod", "app-web-2"),
+		warning("e2", "Pod", "other-1"),
+	)
+
+	status := &appStatus{Name: "app", Namespace: "default", BuildID: "01NEW"}
+	if err := collectKubeStatus(context.Background(), clientset, status, releaseResources(statusTestManifest)); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []deploymentStatus{{Name: "app-web", Desired: 2, Ready: 1, UpToDate: 2}}; !reflect.DeepEqual(status.Deployments, expected) {
+		t.Errorf("Expected deployments %v, got %v", expected, status.Deployments)
+	}
+	if expected := []podStatus{{Name: "app-web-1", Phase: "Running", Ready: true, Restarts: 1}}; !reflect.DeepEqual(status.Pods, expected) {
+		t.Errorf("Expected pods of the current build %v, got %v", expected, status.Pods)
+	}
+	expectedSvc := []serviceStatus{{Name: "app-web", Type: "ClusterIP", ClusterIP: "10.0.0.1", Ports: []string{"80/TCP"}, Endpoints: []string{"10.1.0.4:8080"}}}
+	if !reflect.DeepEqual(status.Services, expectedSvc) {
+		t.Errorf("Expected services %v, got %v", expectedSvc, status.Services)
+	}
+	if len(status.Warnings) != 1 || status.Warnings[0].Object != "pod/app-web-2" {
+		t.Errorf("Expected a single warning about pod/app-web-2, got %v", status.Warnings)
+	}
+}
+
+func TestServesIngressV1(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	if servesIngressV1(clientset) {
+		t.Error("Expected Ingresses to be read from networking.k8s.io/v1beta1 when v1 is not served")
+	}
+	clientset.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "networking.k8s.io/v1",
+		APIResources: []metav1.APIResource{{Name: "networkpolicies"}, {Name: "ingresses"}},
+	}}
+	if !servesIngressV1(clientset) {
+		t.Error("Expected Ingresses to be read from networking.k8s.io/v1 when it is served")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	helmkube "helm.sh/helm/v3/pkg/kube"
+
+	"github.com/Azure/draft/pkg/draft/draftpath"
+	"github.com/Azure/draft/pkg/draft/manifest"
+	"github.com/Azure/draft/pkg/storage"
+	"github.com/Azure/draft/pkg/storage/kube/configmap"
+	"github.com/Azure/draft/pkg/storage/kube/secret"
+	localstorage "github.com/Azure/draft/pkg/storage/local"
+)
+
+const (
+	storageHelp = `Manage the build records Draft stores for your applications.`
+
+	configMapStorageEngine = "configmap"
+	secretStorageEngine    = "secret"
+	localStorageEngine     = "local"
+)
+
+func newStorageCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "storage",
+		Short: "manage Draft build storage",
+		Long:  storageHelp,
+	}
+	cmd.AddCommand(
+		newStorageMigrateCmd(out),
+	)
+	return cmd
+}
+
+// newStore returns the storage engine selected by name. Cluster storage engines keep build
+// records in the given namespace of the cluster targeted by kubeContext; the local storage
+// engine keeps them in $DRAFT_HOME and never talks to the cluster.
+//
+// An empty name selects the storage engine configured in $DRAFT_HOME/config.toml.
+func newStore(name, kubeContext, namespace string) (storage.Store, error) {
+	if name == "" {
+		name = globalConfig[storageEngine.name]
+	}
+	if name == localStorageEngine {
+		if kubeContext == "" {
+			// reading the kubeconfig file does not talk to the cluster.
+			if raw, err := helmkube.GetConfig(kubeConfig, "", "").ToRawKubeConfigLoader().RawConfig(); err == nil {
+				kubeContext = raw.CurrentContext
+			}
+		}
+		return localstorage.NewStore(draftpath.Home(homePath()).Storage(), kubeContext, namespace), nil
+	}
+	if name != "" && name != configMapStorageEngine && name != secretStorageEngine {
+		return nil, fmt.Errorf("unknown storage engine %q", name)
+	}
+	client, _, err := getKubeClient(kubeContext)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get a kube client: %v", err)
+	}
+	if name == secretStorageEngine {
+		return secret.NewSecrets(client.CoreV1().Secrets(namespace)), nil
+	}
+	return configmap.NewConfigMaps(client.CoreV1().ConfigMaps(namespace)), nil
+}
+
+// storageNamespace returns the namespace build records are stored in. It defaults to the
+// namespace the application is deployed to.
+func storageNamespace(configured, namespace string) string {
+	switch {
+	case configured != "":
+		return configured
+	case namespace != "":
+		return namespace
+	default:
+		return manifest.DefaultNamespace
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+
+	"github.com/Azure/draft/pkg/local"
+	"github.com/Azure/draft/pkg/storage"
+)
+
+const storageMigrateDesc = `This command moves build records to the configured storage engine and namespace.
+
+Earlier versions of Draft stored build records as ConfigMaps in the "default" namespace.
+Records are now stored in the application's namespace (or the environment's storage-namespace)
+using the storage engine set with 'draft config set storage-engine'.
+`
+
+type storageMigrateCmd struct {
+	out           io.Writer
+	appName       string
+	env           string
+	all           bool
+	fromEngine    string
+	fromNamespace string
+	toNamespace   string
+}
+
+// appLister is implemented by storage engines able to enumerate the applications they store builds for.
+type appLister interface {
+	Apps(ctx context.Context) ([]string, error)
+}
+
+func newStorageMigrateCmd(out io.Writer) *cobra.Command {
+	mc := &storageMigrateCmd{out: out}
+	cmd := &cobra.Command{
+		Use:   "migrate [app]",
+		Short: "move build records to the configured storage",
+		Long:  storageMigrateDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				mc.appName = args[0]
+			}
+			return mc.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.BoolVar(&mc.all, "all", false, "migrate the build records of every application found in the source namespace")
+	f.StringVar(&mc.fromEngine, "from-engine", configMapStorageEngine, "storage engine to migrate build records from (configmap|secret|local)")
+	f.StringVar(&mc.fromNamespace, "from-namespace", "default", "namespace to migrate build records from")
+	f.StringVar(&mc.toNamespace, "to-namespace", "", "namespace to migrate build records to. Defaults to the environment's storage namespace")
+	f.StringVarP(&mc.env, environmentFlagName, environmentFlagShorthand, defaultDraftEnvironment(), environmentFlagUsage)
+	return cmd
+}
+
+func (m *storageMigrateCmd) run() error {
+	app, err := local.DeployedApplication(draftToml, m.env)
+	if err != nil {
+		if m.toNamespace == "" || (m.appName == "" && !m.all) {
+			return errors.New("Unable to detect the application\nPlease pass in the name of the application and --to-namespace")
+		}
+		app = &local.App{}
+	}
+	if m.appName != "" {
+		app.Name = m.appName
+	}
+	toNamespace := m.toNamespace
+	if toNamespace == "" {
+		toNamespace = storageNamespace(app.StorageNamespace, app.Namespace)
+	}
+
+	kctx, err := resolveKubeContext(m.env, app.KubeContexts)
+	if err != nil {
+		return err
+	}
+	from, err := newStore(m.fromEngine, kctx, m.fromNamespace)
+	if err != nil {
+		return err
+	}
+	toEngine := globalConfig[storageEngine.name]
+	if toEngine == "" {
+		toEngine = configMapStorageEngine
+	}
+	if m.fromEngine == toEngine && (toEngine == localStorageEngine || m.fromNamespace == toNamespace) {
+		return fmt.Errorf("build records are already stored in the %s storage engine in namespace %q", toEngine, toNamespace)
+	}
+	to, err := newStore(toEngine, kctx, toNamespace)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	apps := []string{app.Name}
+	if m.all {
+		lister, ok := from.(appLister)
+		if !ok {
+			return fmt.Errorf("storage engine %q cannot list applications", m.fromEngine)
+		}
+		if apps, err = lister.Apps(ctx); err != nil {
+			return fmt.Errorf("failed to list applications in namespace %q: %v", m.fromNamespace, err)
+		}
+	}
+
+	for _, name := range apps {
+		builds, err := storage.Migrate(ctx, from, to, name)
+		if err != nil {
+			return fmt.Errorf("failed to migrate build records of %q: %v", name, err)
+		}
+		fmt.Fprintf(m.out, "migrated %d build record(s) of '%s' from namespace %q to %q\n", len(builds), name, m.fromNamespace, toNamespace)
+	}
+	return nil
+}
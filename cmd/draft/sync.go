@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	dockerflags "github.com/docker/cli/cli/flags"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+	"k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
+
+	"github.com/Azure/draft/pkg/builder"
+	"github.com/Azure/draft/pkg/draft/manifest"
+	"github.com/Azure/draft/pkg/local"
+)
+
+const syncDesc = `Copy local files into the running application, without rebuilding the image.
+
+The files to copy are configured in the sync table of the environment in draft.toml:
+
+	[environments.development.sync]
+	container = "web"
+	restart = "kill -HUP 1"
+	[environments.development.sync.paths]
+	"src" = "/app/src"
+	"templates" = "/app/templates"
+
+Every file below a local path is copied into a ready pod of the latest build, then the
+restart command, if any, is run in the container.
+
+With --watch, files are copied as they change. Changes to files outside the sync paths
+rebuild and redeploy the application like 'draft up' does.
+`
+
+type syncCmd struct {
+	out       io.Writer
+	env       string
+	container string
+	watch     bool
+}
+
+func newSyncCmd(out io.Writer) *cobra.Command {
+	sc := &syncCmd{out: out}
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "copy local files into the running application",
+		Long:  syncDesc,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return sc.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&sc.container, "container", "c", "", "name of the container to copy files into (default: the container configured in draft.toml)")
+	f.BoolVarP(&sc.watch, "watch", "w", false, "keep copying files as they change, rebuilding on changes outside the sync paths")
+	f.StringVarP(&sc.env, environmentFlagName, environmentFlagShorthand, defaultDraftEnvironment(), environmentFlagUsage)
+	return cmd
+}
+
+func (sc *syncCmd) run() error {
+	mfst, err := manifest.Load(draftToml)
+	if err != nil {
+		return err
+	}
+	env, ok := mfst.Environments[sc.env]
+	if !ok {
+		return fmt.Errorf("Environment %v not found", sc.env)
+	}
+	if env.Sync == nil || len(env.Sync.Paths) == 0 {
+		return fmt.Errorf("no sync paths are configured for environment %q in %s", sc.env, draftToml)
+	}
+	appDir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	s := newFileSyncer(sc.out, appDir, sc.env, env)
+	if sc.container != "" {
+		s.container = sc.container
+	}
+
+	if !sc.watch {
+		files, err := s.rules.Files(appDir)
+		if err != nil {
+			return err
+		}
+		return s.sync(files)
+	}
+	return s.watch(context.Background(), watchDelay(env), func() error {
+		return runUp(sc.out, sc.env)
+	})
+}
+
+// runUp runs draft up for the environment, without watching for changes or connecting
+// to the application.
+func runUp(out io.Writer, environment string) error {
+	c := newUpCmdFor(&upCmd{
+		out:                 out,
+		dockerClientOptions: dockerflags.NewClientOptions(),
+		rebuild:             true,
+	})
+	if err := c.Flags().Set(environmentFlagName, environment); err != nil {
+		return err
+	}
+	c.PersistentPreRun(c, nil)
+	return c.RunE(c, nil)
+}
+
+// watchDelay returns how long to wait for further changes before acting on changed files.
+func watchDelay(env *manifest.Environment) time.Duration {
+	if env.WatchDelay > 0 {
+		return time.Duration(env.WatchDelay) * time.Second
+	}
+	return manifest.DefaultWatchDelaySeconds * time.Second
+}
+
+// fileSyncer copies files into the running application according to the sync table of
+// an environment.
+type fileSyncer struct {
+	out         io.Writer
+	app         *local.App
+	environment string
+	appDir      string
+	container   string
+	restart     string
+	rules       local.SyncRules
+
+	// the kube client is set up on first use, as an environment without sync paths
+	// never copies files.
+	clientset kubernetes.Interface
+	config    *restclient.Config
+}
+
+func newFileSyncer(out io.Writer, appDir, environment string, env *manifest.Environment) *fileSyncer {
+	s := &fileSyncer{
+		out:         out,
+		environment: environment,
+		appDir:      appDir,
+		app: &local.App{
+			Name:             env.Name,
+			Namespace:        env.Namespace,
+			KubeContexts:     env.Contexts(),
+			StorageNamespace: env.StorageNamespace,
+		},
+	}
+	if env.Sync != nil {
+		s.container = env.Sync.Container
+		s.restart = env.Sync.Restart
+		s.rules = local.NewSyncRules(env.Sync.Paths)
+	}
+	return s
+}
+
+// sync copies files, mapped to their container paths, into a ready pod of the latest build
+// and runs the restart command.
+func (s *fileSyncer) sync(files map[string]string) error {
+	if len(files) == 0 {
+		return errors.New("no files to sync")
+	}
+	if s.clientset == nil {
+		kctx, err := resolveKubeContext(s.environment, s.app.KubeContexts)
+		if err != nil {
+			return err
+		}
+		if s.clientset, s.config, err = getKubeClient(kctx); err != nil {
+			return err
+		}
+	}
+	buildID, err := latestBuildID(s.app, s.environment)
+	if err != nil {
+		return err
+	}
+	pod, err := s.app.Pod(s.clientset, buildID)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	if err := s.app.SyncFiles(s.clientset, s.config, pod.Name, s.container, s.appDir, files); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "Synced %d file(s) into pod %s (%s)\n", len(files), pod.Name, time.Since(start).Round(time.Millisecond))
+	if s.restart == "" {
+		return nil
+	}
+	fmt.Fprintf(s.out, "Running %q\n", s.restart)
+	return s.app.Exec(s.clientset, s.config, pod.Name, local.ExecOptions{
+		Container: s.container,
+		Command:   []string{"/bin/sh", "-c", s.restart},
+		Stdout:    s.out,
+		Stderr:    s.out,
+	})
+}
+
+// watch copies files into the running application as they change, and calls rebuild when
+// files outside the sync paths change. Errors are reported without ending the watch.
+func (s *fileSyncer) watch(ctx context.Context, delay time.Duration, rebuild func() error) error {
+	fmt.Fprintf(s.out, "Watching %s for changes...\n", s.appDir)
+	return builder.WatchFiles(ctx, s.appDir, delay, func(changed []string) error {
+		files, unmatched := s.rules.Resolve(changed)
+		if len(unmatched) > 0 {
+			fmt.Fprintf(s.out, "%s changed, rebuilding...\n", describeChanges(unmatched))
+			if err := rebuild(); err != nil {
+				fmt.Fprintf(s.out, "Error: %v\n", err)
+			}
+			return nil
+		}
+		if err := s.sync(files); err != nil {
+			fmt.Fprintf(s.out, "Error: %v\n", err)
+		}
+		return nil
+	})
+}
+
+func describeChanges(files []string) string {
+	const max = 3
+	if len(files) <= max {
+		return strings.Join(files, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(files[:max], ", "), len(files)-max)
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestDescribeChanges(t *testing.T) {
+	tests := []struct {
+		files    []string
+		expected string
+	}{
+		{[]string{"Dockerfile"}, "Dockerfile"},
+		{[]string{"Dockerfile", "package.json", "draft.toml"}, "Dockerfile, package.json, draft.toml"},
+		{[]string{"a", "b", "c", "d", "e"}, "a, b, c and 2 more"},
+	}
+	for _, tt := range tests {
+		if got := describeChanges(tt.files); got != tt.expected {
+			t.Errorf("describeChanges(%v): expected %q, got %q", tt.files, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/draft/pkg/tasks"
+)
+
+const tasksHelp = `Run and inspect the tasks of the application, defined in .draft-tasks.toml.`
+
+func newTasksCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tasks",
+		Short: "run and inspect the tasks of the application",
+		Long:  tasksHelp,
+	}
+	cmd.AddCommand(
+		newTasksListCmd(out),
+		newTasksRunCmd(out),
+	)
+	return cmd
+}
+
+// loadTasks reads the tasks file of the application.
+func loadTasks() (*tasks.Tasks, error) {
+	taskList, err := tasks.Load(tasksTOMLFile)
+	if err == tasks.ErrNoTaskFile {
+		return nil, fmt.Errorf("%s not found; tasks are defined in %s in the application directory", tasksTOMLFile, tasksTOMLFile)
+	}
+	return taskList, err
+}
+
+// taskResult is a task result as shown in JSON output.
+type taskResult struct {
+	Kind     string   `json:"kind"`
+	Name     string   `json:"name"`
+	Pod      string   `json:"pod,omitempty"`
+	Command  []string `json:"command,omitempty"`
+	Passed   bool     `json:"passed"`
+	Skipped  bool     `json:"skipped"`
+	ExitCode int      `json:"exitCode"`
+	Duration float64  `json:"durationSeconds"`
+	Output   string   `json:"output"`
+	Message  string   `json:"message,omitempty"`
+}
+
+func toTaskResults(results []tasks.Result) []taskResult {
+	list := make([]taskResult, 0, len(results))
+	for _, r := range results {
+		list = append(list, taskResult{
+			Kind:     tasks.KeyOf(r.Kind),
+			Name:     r.Name,
+			Pod:      r.Pod,
+			Command:  r.Command,
+			Passed:   r.Pass,
+			Skipped:  r.Skipped,
+			ExitCode: r.ExitCode,
+			Duration: r.Duration.Seconds(),
+			Output:   r.Output,
+			Message:  r.Message,
+		})
+	}
+	return list
+}
+
+// formatTaskResults returns a table of task results. The pod column is shown for tasks
+// run in pods only.
+func formatTaskResults(results []tasks.Result) string {
+	inPods := false
+	for _, r := range results {
+		if r.Pod != "" {
+			inPods = true
+		}
+	}
+
+	table := uitable.New()
+	table.MaxColWidth = 80
+	header := []interface{}{"TASK", "RESULT", "EXIT CODE", "DURATION"}
+	if inPods {
+		header = append([]interface{}{"POD"}, header...)
+	}
+	table.AddRow(header...)
+	for _, r := range results {
+		status, code := "passed", fmt.Sprint(r.ExitCode)
+		switch {
+		case r.Skipped:
+			status, code = r.Message, ""
+		case !r.Pass:
+			status = "failed: " + r.Message
+		}
+		if r.ExitCode < 0 {
+			code = ""
+		}
+		row := []interface{}{r.Name, status, code, r.Duration.Round(time.Millisecond)}
+		if inPods {
+			row = append([]interface{}{r.Pod}, row...)
+		}
+		table.AddRow(row...)
+	}
+	return table.String()
+}
+
+// taskKind returns the kind of tasks given by its key in the tasks file.
+func taskKind(key string) (string, error) {
+	kind, ok := tasks.KindOf(key)
+	if !ok {
+		return "", fmt.Errorf("unknown kind of tasks %q (available: %s)", key, strings.Join(tasks.Keys(), ", "))
+	}
+	return kind, nil
+}
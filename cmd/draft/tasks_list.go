@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/draft/pkg/tasks"
+)
+
+const tasksListDesc = `List the tasks of the application, by kind, in the order they are declared.`
+
+type tasksListCmd struct {
+	out io.Writer
+	fmt string
+}
+
+func newTasksListCmd(out io.Writer) *cobra.Command {
+	lc := &tasksListCmd{out: out}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list the tasks of the application",
+		Long:  tasksListDesc,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return lc.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&lc.fmt, "output", "o", "table", "prints the output in the specified format (json|table)")
+	return cmd
+}
+
+// taskInfo is a task as shown in JSON output.
+type taskInfo struct {
+	Kind            string            `json:"kind"`
+	Name            string            `json:"name"`
+	Type            string            `json:"type"`
+	Command         string            `json:"command"`
+	Shell           bool              `json:"shell,omitempty"`
+	Timeout         string            `json:"timeout,omitempty"`
+	Env             map[string]string `json:"env,omitempty"`
+	Dir             string            `json:"dir,omitempty"`
+	Container       string            `json:"container,omitempty"`
+	DependsOn       []string          `json:"dependsOn,omitempty"`
+	ContinueOnError bool              `json:"continueOnError,omitempty"`
+}
+
+func (lc *tasksListCmd) run() error {
+	taskList, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	infos := taskInfos(taskList)
+
+	switch lc.fmt {
+	case "json":
+		output, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(lc.out, string(output))
+	case "table":
+		if len(infos) == 0 {
+			fmt.Fprintf(lc.out, "No tasks are defined in %s\n", tasksTOMLFile)
+			return nil
+		}
+		fmt.Fprintln(lc.out, formatTaskInfos(infos))
+	default:
+		return fmt.Errorf("unknown output format %q", lc.fmt)
+	}
+	return nil
+}
+
+func taskInfos(taskList *tasks.Tasks) []taskInfo {
+	infos := []taskInfo{}
+	for _, key := range tasks.Keys() {
+		kind, _ := tasks.KindOf(key)
+		for _, t := range taskList.List(kind) {
+			info := taskInfo{
+				Kind:            key,
+				Name:            t.Name,
+				Type:            t.Type,
+				Command:         t.Command,
+				Shell:           t.Shell,
+				Env:             t.Env,
+				Dir:             t.Dir,
+				Container:       t.Container,
+				DependsOn:       t.DependsOn,
+				ContinueOnError: t.ContinueOnError,
+			}
+			if info.Type == "" {
+				info.Type = tasks.CommandTask
+			}
+			if t.Timeout.Duration > 0 {
+				info.Timeout = t.Timeout.String()
+			}
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+func formatTaskInfos(infos []taskInfo) string {
+	table := uitable.New()
+	table.MaxColWidth = 60
+	table.AddRow("KIND", "NAME", "TYPE", "COMMAND", "DEPENDS ON")
+	for _, i := range infos {
+		table.AddRow(i.Kind, i.Name, i.Type, i.Command, strings.Join(i.DependsOn, ", "))
+	}
+	return table.String()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/draft/pkg/draft/manifest"
+	"github.com/Azure/draft/pkg/local"
+	"github.com/Azure/draft/pkg/tasks"
+)
+
+const tasksRunDesc = `Run the tasks of a given kind, such as post-deploy, and report their results.
+
+Post-deploy tasks run in every pod of the latest build of the application. Other tasks
+run on the local machine; build hooks receive the environment name in DRAFT_ENV. Job
+tasks only run during 'draft up'.
+
+The results include the exit code, the duration and the output of every task. With
+--output json or --output junit, the output of the tasks is not shown as they run and
+the report is the only output, e.g. to publish it from a CI pipeline:
+
+	$ draft tasks run post-deploy -o junit > post-deploy.xml
+
+The command fails if a task failed.
+`
+
+type tasksRunCmd struct {
+	out io.Writer
+	env string
+	fmt string
+}
+
+func newTasksRunCmd(out io.Writer) *cobra.Command {
+	rc := &tasksRunCmd{out: out}
+	cmd := &cobra.Command{
+		Use:   "run <kind>",
+		Short: "run the tasks of a given kind",
+		Long:  tasksRunDesc,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return rc.run(args[0])
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&rc.fmt, "output", "o", "table", "prints the results in the specified format (json|junit|table)")
+	f.StringVarP(&rc.env, environmentFlagName, environmentFlagShorthand, defaultDraftEnvironment(), environmentFlagUsage)
+	return cmd
+}
+
+func (rc *tasksRunCmd) run(key string) error {
+	kind, err := taskKind(key)
+	if err != nil {
+		return err
+	}
+	switch rc.fmt {
+	case "json", "junit", "table":
+	default:
+		return fmt.Errorf("unknown output format %q", rc.fmt)
+	}
+	taskList, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	if len(taskList.List(kind)) == 0 {
+		return fmt.Errorf("no %s tasks are defined in %s", key, tasksTOMLFile)
+	}
+
+	taskOut := rc.out
+	if rc.fmt != "table" {
+		taskOut = ioutil.Discard
+	}
+	var results []tasks.Result
+	if kind == tasks.PostDeploy {
+		results, err = rc.runInPods(taskOut, taskList)
+	} else {
+		results, err = taskList.RunWithOptions(tasks.DefaultRunner, kind, "", tasks.Options{
+			Out: taskOut,
+			Env: map[string]string{"DRAFT_ENV": rc.env},
+		})
+	}
+	if len(results) == 0 {
+		return err
+	}
+	if rerr := rc.report(results); rerr != nil {
+		return rerr
+	}
+	return err
+}
+
+// runInPods runs the post-deploy tasks in the pods of the latest build.
+func (rc *tasksRunCmd) runInPods(out io.Writer, taskList *tasks.Tasks) ([]tasks.Result, error) {
+	mfst, err := manifest.Load(draftToml)
+	if err != nil {
+		return nil, err
+	}
+	env, ok := mfst.Environments[rc.env]
+	if !ok {
+		return nil, fmt.Errorf("Environment %v not found", rc.env)
+	}
+	app := &local.App{
+		Name:             env.Name,
+		Namespace:        env.Namespace,
+		KubeContexts:     env.Contexts(),
+		StorageNamespace: env.StorageNamespace,
+	}
+	buildID, err := latestBuildID(app, rc.env)
+	if err != nil {
+		return nil, err
+	}
+	kctx, err := resolveKubeContext(rc.env, app.KubeContexts)
+	if err != nil {
+		return nil, err
+	}
+	return runInPods(out, taskList, env, buildID, kctx)
+}
+
+func (rc *tasksRunCmd) report(results []tasks.Result) error {
+	switch rc.fmt {
+	case "json":
+		output, err := json.MarshalIndent(toTaskResults(results), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(rc.out, string(output))
+	case "junit":
+		return tasks.WriteJUnit(rc.out, results)
+	default:
+		fmt.Fprintln(rc.out, formatTaskResults(results))
+	}
+	return nil
+}
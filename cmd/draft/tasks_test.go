@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/draft/pkg/tasks"
+)
+
+func TestFormatTaskResults(t *testing.T) {
+	results := []tasks.Result{
+		{Kind: tasks.PostUp, Name: "smoke", Pass: true, Duration: 1234 * time.Millisecond},
+		{Kind: tasks.PostUp, Name: "notify", Message: "exit status 7", ExitCode: 7},
+		{Kind: tasks.PostUp, Name: "report", Skipped: true, Message: `skipped: task "notify" failed`, ExitCode: -1},
+	}
+	expected := `TASK  	RESULT                       	EXIT CODE	DURATION
+smoke 	passed                       	0        	1.234s  
+notify	failed: exit status 7        	7        	0s      
+report	skipped: task "notify" failed	         	0s      `
+	if got := formatTaskResults(results); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+
+	results[0].Pod = "web-1"
+	if got := formatTaskResults(results); !strings.HasPrefix(got, "POD") {
+		t.Errorf("expected a pod column for tasks run in pods, got\n%s", got)
+	}
+}
+
+func TestTaskInfos(t *testing.T) {
+	taskList := &tasks.Tasks{
+		PreUp: []tasks.Task{{Name: "install", Command: "helm install mysql", Timeout: tasks.Duration{Duration: 5 * time.Minute}}},
+		PreRelease: []tasks.Task{
+			{Name: "migrate", Type: tasks.JobTask, Command: "rake db:migrate"},
+			{Name: "seed", Command: "rake db:seed", DependsOn: []string{"migrate"}},
+		},
+	}
+	expected := []taskInfo{
+		{Kind: "pre-up", Name: "install", Type: "command", Command: "helm install mysql", Timeout: "5m0s"},
+		{Kind: "pre-release", Name: "migrate", Type: "job", Command: "rake db:migrate"},
+		{Kind: "pre-release", Name: "seed", Type: "command", Command: "rake db:seed", DependsOn: []string{"migrate"}},
+	}
+	if got := taskInfos(taskList); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestTaskKind(t *testing.T) {
+	if kind, err := taskKind("post-deploy"); err != nil || kind != tasks.PostDeploy {
+		t.Errorf("expected post-deploy to be the PostDeploy kind, got %q and %v", kind, err)
+	}
+	if _, err := taskKind("PostDeploy"); err == nil || !strings.Contains(err.Error(), "pre-up, post-up") {
+		t.Errorf("expected an error listing the kinds of tasks, got %v", err)
+	}
+}
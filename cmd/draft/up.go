@@ -7,7 +7,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
+	"github.com/Azure/azure-sdk-for-go/services/preview/containerregistry/mgmt/2019-12-01-preview/containerregistry"
 	"github.com/Azure/go-autorest/autorest"
 	azurecli "github.com/Azure/go-autorest/autorest/azure/cli"
 	"github.com/docker/cli/cli/command"
@@ -19,7 +23,8 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"golang.org/x/net/context"
-	"github.com/Azure/azure-sdk-for-go/services/preview/containerregistry/mgmt/2019-12-01-preview/containerregistry"
+	"k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
 
 	"github.com/Azure/draft/pkg/azure/iam"
 	"github.com/Azure/draft/pkg/builder"
@@ -27,14 +32,26 @@ import (
 	dockercontainerbuilder "github.com/Azure/draft/pkg/builder/docker"
 	"github.com/Azure/draft/pkg/cmdline"
 	"github.com/Azure/draft/pkg/draft/draftpath"
+	"github.com/Azure/draft/pkg/draft/manifest"
+	"github.com/Azure/draft/pkg/draft/workspace"
 	"github.com/Azure/draft/pkg/local"
-	"github.com/Azure/draft/pkg/storage/kube/configmap"
+	"github.com/Azure/draft/pkg/storage"
 	"github.com/Azure/draft/pkg/tasks"
 )
 
 const upDesc = `
 This command builds a container image using Docker, pushes it to a container registry
 and then instructs helm to install the chart, referencing the image just built.
+
+With --watch, or watch = true in draft.toml, draft up keeps watching the application
+directory and runs again when files change. Files below the sync paths of the environment
+are copied into the running application instead, see 'draft sync'. Files ignored by
+.draftignore or .gitignore are not watched. The post-up tasks run after the first deployment,
+and with --auto-connect, draft up watches for changes while it is connected.
+
+With --all, every application listed in the workspace file, as written by 'draft create --scan',
+is built and deployed at the same time, and the progress of all of them is reported together.
+Applications deployed with --all are neither watched nor connected to.
 `
 
 const (
@@ -55,10 +72,19 @@ type upCmd struct {
 	out  io.Writer
 	src  string
 	home draftpath.Home
-	// storage engine draft should use for storing builds, logs, etc.
-	storageEngine string
 	// options common to the docker client and the daemon.
 	dockerClientOptions *dockerflags.ClientOptions
+	// watch overrides the watch setting of draft.toml if watchSet is true.
+	watch    bool
+	watchSet bool
+	// rebuild is set when draft up runs again on changes, in which case it
+	// neither watches for changes nor connects to the application.
+	rebuild bool
+	// all deploys every application of the workspace file.
+	all           bool
+	workspaceFile string
+	// progress displays the progress of the applications deployed together with --all.
+	progress *cmdline.Progress
 }
 
 func defaultDockerTLS() bool {
@@ -82,11 +108,14 @@ func dockerPreRun(opts *dockerflags.ClientOptions) {
 }
 
 func newUpCmd(out io.Writer) *cobra.Command {
+	return newUpCmdFor(&upCmd{
+		out:                 out,
+		dockerClientOptions: dockerflags.NewClientOptions(),
+	})
+}
+
+func newUpCmdFor(up *upCmd) *cobra.Command {
 	var (
-		up = &upCmd{
-			out:                 out,
-			dockerClientOptions: dockerflags.NewClientOptions(),
-		}
 		runningEnvironment string
 		f                  *pflag.FlagSet
 	)
@@ -100,7 +129,15 @@ func newUpCmd(out io.Writer) *cobra.Command {
 			up.dockerClientOptions.Common.SetDefaultOptions(f)
 			dockerPreRun(up.dockerClientOptions)
 		},
-		RunE: func(_ *cobra.Command, args []string) (err error) {
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			up.watchSet = cmd.Flags().Changed("watch")
+			up.home = draftpath.Home(homePath())
+			if up.all {
+				if len(args) > 0 {
+					return errors.New("--all deploys the applications of the workspace file and takes no path")
+				}
+				return up.runAll(runningEnvironment)
+			}
 			if len(args) > 0 {
 				up.src = args[0]
 			}
@@ -109,7 +146,6 @@ func newUpCmd(out io.Writer) *cobra.Command {
 					return err
 				}
 			}
-			up.home = draftpath.Home(homePath())
 			return up.run(runningEnvironment)
 		},
 	}
@@ -124,6 +160,9 @@ func newUpCmd(out io.Writer) *cobra.Command {
 	f.BoolVarP(&autoConnect, "auto-connect", "", false, "specifies if draft up should automatically connect to the application")
 	f.BoolVar(&skipImagePush, "skip-image-push", false, "skip pushing image to registry")
 	f.BoolVarP(&quiet, "quiet", "q", false, "only output errors")
+	f.BoolVarP(&up.watch, "watch", "w", false, "run again whenever files change, overriding watch in draft.toml")
+	f.BoolVar(&up.all, "all", false, "build and deploy every application of the workspace file together")
+	f.StringVar(&up.workspaceFile, "workspace", workspace.DefaultFilename, "path to the workspace file used by --all")
 
 	up.dockerClientOptions.Common.TLSOptions = &tlsconfig.Options{
 		CAFile:   filepath.Join(dockerCertPath, dockerflags.DefaultCaFile),
@@ -144,13 +183,11 @@ func newUpCmd(out io.Writer) *cobra.Command {
 
 func (u *upCmd) run(environment string) (err error) {
 	var (
-		buildctx   *builder.Context
-		ctx        = context.Background()
-		bldr       = builder.New()
+		buildctx *builder.Context
+		ctx      = context.Background()
 	)
-	bldr.LogsDir = u.home.Logs()
 
-	taskList, err := tasks.Load(tasksTOMLFile)
+	taskList, err := tasks.Load(filepath.Join(u.src, tasksTOMLFile))
 	if err != nil {
 		if err == tasks.ErrNoTaskFile {
 			debug(err.Error())
@@ -158,7 +195,8 @@ func (u *upCmd) run(environment string) (err error) {
 			return err
 		}
 	} else {
-		if _, err = taskList.Run(tasks.DefaultRunner, tasks.PreUp, ""); err != nil {
+		taskList.InDir(u.src)
+		if _, err = taskList.RunWithOptions(tasks.DefaultRunner, tasks.PreUp, "", tasks.Options{Out: u.out}); err != nil {
 			return err
 		}
 	}
@@ -213,7 +251,7 @@ func (u *upCmd) run(environment string) (err error) {
 		runsClient.AddToUserAgent(containerregistry.UserAgent())
 		cb = &azurecontainerbuilder.Builder{
 			RegistryClient: registriesClient,
-			RunsClient:   runsClient,
+			RunsClient:     runsClient,
 			AdalToken:      token,
 			Subscription:   subscription,
 		}
@@ -227,16 +265,119 @@ func (u *upCmd) run(environment string) (err error) {
 			DockerClient: cli,
 		}
 	}
+	contexts, err := resolveKubeContexts(environment, buildctx.Env.Contexts())
+	if err != nil {
+		return err
+	}
+
+	// the image is built and pushed once, then released to every context.
+	bldr := builder.New()
+	bldr.LogsDir = u.home.Logs()
 	bldr.ContainerBuilder = cb
+	bldr.Tasks = taskList
+	for i, kctx := range contexts {
+		cluster, err := u.cluster(ctx, buildctx, kctx)
+		if err != nil {
+			return err
+		}
+		if len(contexts) > 1 {
+			cluster.Name = kctx
+		}
+		if i == 0 {
+			bldr.ClusterName, bldr.Kube, bldr.HelmConfig, bldr.Storage = cluster.Name, cluster.Kube, cluster.HelmConfig, cluster.Storage
+		} else {
+			bldr.Clusters = append(bldr.Clusters, cluster)
+		}
+	}
+	u.up(ctx, bldr, buildctx)
+
+	for _, kctx := range contexts {
+		if err := runPostDeployTasks(u.out, taskList, buildctx.Env, bldr.ID, kctx); err != nil {
+			return err
+		}
+		if err := u.applyRetentionPolicy(buildctx.Env, kctx); err != nil {
+			fmt.Fprintf(u.out, "WARNING: %v\n", err)
+		}
+	}
+
+	if u.rebuild {
+		return nil
+	}
+
+	if _, err = taskList.RunWithOptions(tasks.DefaultRunner, tasks.PostUp, "", tasks.Options{Out: u.out}); err != nil {
+		debug(err.Error())
+	}
+	if u.progress != nil {
+		return nil
+	}
+
+	watch := (u.watchSet && u.watch) || (!u.watchSet && buildctx.Env.Watch)
+	connect := buildctx.Env.AutoConnect || autoConnect
+	switch {
+	case watch && connect:
+		// the connection moves to the pods of every new build, so the watch runs alongside
+		// it until the connection is interrupted.
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			if err := u.watchChanges(ctx, environment, buildctx); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(u.out, "WARNING: stopped watching for changes: %v\n", err)
+			}
+		}()
+		c := newConnectCmd(u.out)
+		return c.RunE(c, []string{})
+	case watch:
+		return u.watchChanges(ctx, environment, buildctx)
+	case connect:
+		c := newConnectCmd(u.out)
+		return c.RunE(c, []string{})
+	}
+	return nil
+}
+
+// watchChanges runs draft up again whenever files of the application change, copying
+// files below the sync paths of the environment into the running application instead.
+func (u *upCmd) watchChanges(ctx context.Context, environment string, buildctx *builder.Context) error {
+	s := newFileSyncer(u.out, buildctx.AppDir, environment, buildctx.Env)
+	return s.watch(ctx, watchDelay(buildctx.Env), func() error {
+		r := *u
+		r.rebuild = true
+		return r.run(environment)
+	})
+}
 
-	// setup kube
-	bldr.Kube, _, err = getKubeClient(kubeContext)
+// cluster prepares the release of the application to the cluster of the given kubeconfig
+// context.
+func (u *upCmd) cluster(ctx context.Context, buildctx *builder.Context, kubeContext string) (*builder.Cluster, error) {
+	kube, _, err := getKubeClient(kubeContext)
 	if err != nil {
-		return fmt.Errorf("Could not get a kube client: %s", err)
+		return nil, fmt.Errorf("Could not get a kube client: %s", err)
+	}
+	helmConfig, err := getHelmConfig(kubeContext, buildctx.Env.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	// the upgrade of the release does not revert the changes draft debug made to the
+	// Deployments, so those left behind by an interrupted draft debug are restored first.
+	deployed := &local.App{Name: buildctx.Env.Name, Namespace: buildctx.Env.Namespace}
+	if err := restoreDebugOverlays(ctx, kube, deployed, u.out); err != nil {
+		fmt.Fprintf(u.out, "WARNING: %v\n", err)
 	}
 
 	// setup the storage engine
-	bldr.Storage = configmap.NewConfigMaps(bldr.Kube.CoreV1().ConfigMaps("default"))
+	store, err := newStore("", kubeContext, storageNamespace(buildctx.Env.StorageNamespace, buildctx.Env.Namespace))
+	if err != nil {
+		return nil, err
+	}
+	return &builder.Cluster{Kube: kube, HelmConfig: helmConfig, Storage: store}, nil
+}
+
+// up builds and pushes the application, and releases it to the clusters of the builder.
+func (u *upCmd) up(ctx context.Context, bldr *builder.Builder, buildctx *builder.Context) {
+	// the build logs are shared through the storage of the first cluster.
+	if logStore, ok := bldr.Storage.(storage.LogStore); ok {
+		bldr.LogStore = logStore
+	}
 	progressC := bldr.Up(ctx, buildctx)
 	opts := []cmdline.Option{cmdline.WithBuildID(bldr.ID)}
 
@@ -248,52 +389,142 @@ func (u *upCmd) run(environment string) (err error) {
 		opts = append(opts, cmdline.WithDisplayEmoji(displayEmoji))
 	}
 
-	cmdline.Display(ctx, buildctx.Env.Name, progressC, opts...)
+	app := buildctx.Env.Name
+	if u.progress != nil {
+		u.progress.Display(ctx, app, progressC)
+		return
+	}
+	cmdline.Display(ctx, app, progressC, opts...)
+}
 
-	if buildctx.Env.AutoConnect || autoConnect {
-		c := newConnectCmd(u.out)
-		return c.RunE(c, []string{})
+// runAll builds and deploys every application of the workspace file at the same time. The
+// output of each application is prefixed with its name, and the outcome of all of them is
+// summarized once they are done.
+func (u *upCmd) runAll(environment string) error {
+	w, err := workspace.Load(u.workspaceFile)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("--all requires a workspace file (%s not found); create one with 'draft create --scan'", u.workspaceFile)
+	} else if err != nil {
+		return err
+	}
+	if len(w.Apps) == 0 {
+		return fmt.Errorf("no applications in %s", u.workspaceFile)
 	}
 
-	if err := runPostDeployTasks(taskList, bldr.ID); err != nil {
-		debug(err.Error())
+	opts := []cmdline.Option{cmdline.WithDisplayEmoji(displayEmoji)}
+	out := u.out
+	if quiet {
+		opts = append(opts, cmdline.WithStdout(ioutil.Discard))
+		out = ioutil.Discard
 	}
+	progress := cmdline.NewProgress(opts...)
 
-	if _, err = taskList.Run(tasks.DefaultRunner, tasks.PostUp, ""); err != nil {
-		debug(err.Error())
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed = make(map[string]bool)
+	)
+	for _, app := range w.Apps {
+		wg.Add(1)
+		go func(app *workspace.App) {
+			defer wg.Done()
+			env := app.Environment
+			if env == "" {
+				env = environment
+			}
+			a := *u
+			a.out = &prefixWriter{mu: &mu, out: out, prefix: app.Name + ": "}
+			a.progress = progress
+			src, err := filepath.Abs(app.Path)
+			if err == nil {
+				a.src = src
+				err = a.run(env)
+			}
+			if err != nil {
+				fmt.Fprintf(a.out, "Error: %v\n", err)
+				mu.Lock()
+				failed[app.Name] = true
+				mu.Unlock()
+			}
+		}(app)
 	}
+	wg.Wait()
 
+	for _, r := range progress.Summarize() {
+		if r.Failed {
+			failed[r.App] = true
+		}
+	}
+	if len(failed) > 0 {
+		names := make([]string, 0, len(failed))
+		for name := range failed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("draft up failed for %s", strings.Join(names, ", "))
+	}
 	return nil
 }
 
-func runPostDeployTasks(taskList *tasks.Tasks, buildID string) error {
-	if taskList == nil || len(taskList.PostDeploy) == 0 {
-		return errors.New("No post deploy tasks to run")
+// applyRetentionPolicy prunes the build history of the environment's application according
+// to the retention policy configured in $DRAFT_HOME/config.toml, if any.
+func (u *upCmd) applyRetentionPolicy(env *manifest.Environment, kubeContext string) error {
+	policy, helm, ok, err := configuredRetentionPolicy()
+	if err != nil || !ok {
+		return err
 	}
+	out := u.out
+	if quiet {
+		out = ioutil.Discard
+	}
+	app := &local.App{Name: env.Name, Namespace: env.Namespace, StorageNamespace: env.StorageNamespace}
+	return pruneHistory(out, app, kubeContext, policy, helm)
+}
 
-	app, err := local.DeployedApplication(draftToml, runningEnvironment)
-	if err != nil {
-		return err
+// runPostDeployTasks runs the post-deploy tasks in every pod of the build, once a pod
+// is ready, and reports the results by pod.
+func runPostDeployTasks(out io.Writer, taskList *tasks.Tasks, env *manifest.Environment, buildID, kubeContext string) error {
+	if taskList == nil || len(taskList.PostDeploy) == 0 {
+		return nil
 	}
+	fmt.Fprintln(out, "Running post-deploy tasks")
+	results, err := runInPods(out, taskList, env, buildID, kubeContext)
+	if len(results) > 0 {
+		fmt.Fprintln(out, formatTaskResults(results))
+	}
+	return err
+}
+
+// runInPods runs the post-deploy tasks in every pod of the build, once a pod is ready,
+// writing the output of the tasks to out.
+func runInPods(out io.Writer, taskList *tasks.Tasks, env *manifest.Environment, buildID, kubeContext string) ([]tasks.Result, error) {
+	app := &local.App{Name: env.Name, Namespace: env.Namespace}
 
-	client, _, err := getKubeClient(kubeContext)
+	clientset, config, err := getKubeClient(kubeContext)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	names, err := app.GetPodNames(buildID, client)
+	if _, err := app.Pod(clientset, buildID); err != nil {
+		return nil, fmt.Errorf("could not run post-deploy tasks: %v", err)
+	}
+	names, err := app.GetPodNames(buildID, clientset)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return taskList.RunInPods(names, tasks.Options{Out: out, Exec: podExecutor(app, clientset, config)})
+}
 
-	for _, name := range names {
-		_, err := taskList.Run(tasks.DefaultRunner, tasks.PostDeploy, name)
-		if err != nil {
-			debug("error running task: %v", err)
-		}
+// podExecutor runs post-deploy tasks with the exec subresource of the application pods.
+func podExecutor(app *local.App, clientset kubernetes.Interface, config *restclient.Config) tasks.PodExecutor {
+	return func(pod, container string, command []string, stdout, stderr io.Writer) error {
+		return app.Exec(clientset, config, pod, local.ExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    stdout,
+			Stderr:    stderr,
+		})
 	}
-
-	return nil
 }
 
 func getSubscriptionFromProfile() (azurecli.Subscription, error) {
@@ -31,12 +31,14 @@ require (
 	github.com/spf13/pflag v1.0.5
 	github.com/technosophos/moniker v0.0.0-20180509230615-a5dbd03a2245
 	github.com/theupdateframework/notary v0.6.2-0.20200406090937-dc18d79970fc // indirect
+	go.etcd.io/bbolt v1.3.5
 	golang.org/x/crypto v0.0.0-20200414173820-0848c9571904
 	golang.org/x/net v0.0.0-20191004110552-13f9640d40b9
 	helm.sh/helm/v3 v3.2.0
 	k8s.io/api v0.18.0
 	k8s.io/apimachinery v0.18.0
 	k8s.io/client-go v0.18.0
+	k8s.io/kubectl v0.18.0
 )
 
 replace github.com/docker/distribution => github.com/docker/distribution v0.0.0-20191216044856-a8371794149d
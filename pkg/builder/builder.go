@@ -35,6 +35,7 @@ import (
 	"github.com/Azure/draft/pkg/local"
 	"github.com/Azure/draft/pkg/osutil"
 	"github.com/Azure/draft/pkg/storage"
+	"github.com/Azure/draft/pkg/tasks"
 )
 
 const (
@@ -53,7 +54,27 @@ type Builder struct {
 	HelmConfig       *action.Configuration
 	Kube             k8s.Interface
 	Storage          storage.Store
+	LogStore         storage.LogStore
 	LogsDir          string
+	// Tasks are the tasks of the application. Build hooks (pre-build, post-build,
+	// pre-release, post-release and on-failure tasks) run as stages of Up.
+	Tasks *tasks.Tasks
+	// ClusterName names the cluster of Kube and HelmConfig in the stages of the build when
+	// it is released to several clusters.
+	ClusterName string
+	// Clusters are more clusters the build is released to, once it is released with Kube
+	// and HelmConfig. The image is built and pushed once, then the release stages run in
+	// every cluster, and the build is stored in the storage of every cluster.
+	Clusters []*Cluster
+}
+
+// Cluster is a cluster a build is released to.
+type Cluster struct {
+	// Name identifies the cluster in the stages of the build, such as its kube context.
+	Name       string
+	Kube       k8s.Interface
+	HelmConfig *action.Configuration
+	Storage    storage.Store
 }
 
 // ContainerBuilder defines how a container is built and pushed to a container registry using the supplied app context.
@@ -141,6 +162,13 @@ func newAppContext(b *Builder, buildCtx *Context) (*AppContext, error) {
 	if err != nil {
 		return nil, err
 	}
+	var buildLog io.WriteCloser = logf
+	if b.LogStore != nil {
+		buildLog = &sharedLog{
+			file:   logf,
+			shared: storage.NewLogWriter(context.Background(), b.LogStore, buildCtx.Env.Name, b.ID),
+		}
+	}
 	state := &storage.Object{
 		BuildID:     b.ID,
 		ContextID:   ctxtID,
@@ -154,11 +182,32 @@ func newAppContext(b *Builder, buildCtx *Context) (*AppContext, error) {
 		Buf:       buf,
 		Images:    images,
 		MainImage: image,
-		Log:       logf,
+		Log:       buildLog,
 		Vals:      buildCtx.Values,
 	}, nil
 }
 
+// sharedLog writes build logs to the local log file and to the log store.
+//
+// Failures to store the logs do not fail the build; they are reported by Close.
+type sharedLog struct {
+	file   io.WriteCloser
+	shared *storage.LogWriter
+}
+
+func (l *sharedLog) Write(p []byte) (int, error) {
+	l.shared.Write(p)
+	return l.file.Write(p)
+}
+
+func (l *sharedLog) Close() error {
+	err := l.file.Close()
+	if serr := l.shared.Close(); serr != nil {
+		return fmt.Errorf("failed to store build logs: %v", serr)
+	}
+	return err
+}
+
 // LoadWithEnv takes the directory of the application and the environment the application
 //  will be pushed to and returns a Context object with a merge of environment and app
 //  information
@@ -343,18 +392,13 @@ func (b *Builder) Up(ctx context.Context, bctx *Context) <-chan *Summary {
 			log.Printf("error creating app context: %v\n", err)
 			return
 		}
-		log.SetOutput(app.Log)
-		if err := b.ContainerBuilder.Build(ctx, app, ch); err != nil {
-			log.Printf("error while building: %v\n", err)
-			return
-		}
-		if err := b.ContainerBuilder.Push(ctx, app, ch); err != nil {
-			log.Printf("error while pushing: %v\n", err)
-			return
-		}
-		if err := b.release(ctx, app, ch); err != nil {
-			log.Printf("error while releasing: %v\n", err)
-			return
+		// builds may run concurrently, so each one logs through its own logger rather than
+		// redirecting the standard logger.
+		logger := log.New(app.Log, "", log.LstdFlags)
+		if err = b.up(ctx, app, logger, ch); err != nil {
+			if herr := b.runHooks(ctx, app, tasks.OnFailure, err, ch); herr != nil {
+				logger.Printf("error while running on-failure tasks: %v\n", herr)
+			}
 		}
 	}()
 	go func() {
@@ -364,20 +408,171 @@ func (b *Builder) Up(ctx context.Context, bctx *Context) <-chan *Summary {
 	return ch
 }
 
+// up runs the stages of a build, with the build hooks around them.
+func (b *Builder) up(ctx context.Context, app *AppContext, logger *log.Logger, out chan<- *Summary) error {
+	if err := b.runHooks(ctx, app, tasks.PreBuild, nil, out); err != nil {
+		logger.Printf("error while running pre-build tasks: %v\n", err)
+		return err
+	}
+	if err := b.ContainerBuilder.Build(ctx, app, out); err != nil {
+		logger.Printf("error while building: %v\n", err)
+		return err
+	}
+	if err := b.runHooks(ctx, app, tasks.PostBuild, nil, out); err != nil {
+		logger.Printf("error while running post-build tasks: %v\n", err)
+		return err
+	}
+	if err := b.ContainerBuilder.Push(ctx, app, out); err != nil {
+		logger.Printf("error while pushing: %v\n", err)
+		return err
+	}
+	if err := b.releaseStages(ctx, app, logger, out); err != nil {
+		return err
+	}
+	for _, c := range b.Clusters {
+		cb := b.inCluster(c)
+		err := cb.releaseStages(ctx, app, logger, out)
+		cb.storeBuild(app)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inCluster returns a copy of the builder releasing to the cluster c.
+func (b *Builder) inCluster(c *Cluster) *Builder {
+	cb := *b
+	cb.ClusterName, cb.Kube, cb.HelmConfig, cb.Storage, cb.Clusters = c.Name, c.Kube, c.HelmConfig, c.Storage, nil
+	return &cb
+}
+
+// releaseStages releases the application, with the release hooks around the release.
+func (b *Builder) releaseStages(ctx context.Context, app *AppContext, logger *log.Logger, out chan<- *Summary) error {
+	if err := b.runHooks(ctx, app, tasks.PreRelease, nil, out); err != nil {
+		logger.Printf("error while running pre-release tasks: %v\n", err)
+		return err
+	}
+	if err := b.release(ctx, app, out); err != nil {
+		logger.Printf("error while releasing: %v\n", err)
+		return err
+	}
+	if err := b.runHooks(ctx, app, tasks.PostRelease, nil, out); err != nil {
+		logger.Printf("error while running post-release tasks: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// stage returns the description of a stage, naming the cluster of the builder if set.
+func (b *Builder) stage(desc string) string {
+	if b.ClusterName == "" {
+		return desc
+	}
+	return fmt.Sprintf("%s (%s)", desc, b.ClusterName)
+}
+
+// hookStages describes the stages running build hooks.
+var hookStages = map[string]string{
+	tasks.PreBuild:    "Running pre-build tasks",
+	tasks.PostBuild:   "Running post-build tasks",
+	tasks.PreRelease:  "Running pre-release tasks",
+	tasks.PostRelease: "Running post-release tasks",
+	tasks.OnFailure:   "Running on-failure tasks",
+}
+
+// runHooks runs the tasks of a given kind as a stage of the build. Their output is
+// written to the build log and reported line by line. The tasks receive the build
+// metadata in DRAFT_BUILD_ID, DRAFT_IMAGE and DRAFT_ENV, and on-failure tasks the error
+// failing the build in DRAFT_ERROR.
+func (b *Builder) runHooks(ctx context.Context, app *AppContext, kind string, failure error, out chan<- *Summary) (err error) {
+	if b.Tasks == nil || len(b.Tasks.List(kind)) == 0 {
+		return nil
+	}
+	stageDesc := b.stage(hookStages[kind])
+
+	defer Complete(app.ID, stageDesc, out, &err)
+	summary := Summarize(app.ID, stageDesc, out)
+
+	// notify that particular stage has started.
+	summary("started", SummaryStarted)
+
+	env := map[string]string{
+		"DRAFT_BUILD_ID": app.ID,
+		"DRAFT_IMAGE":    app.MainImage,
+		"DRAFT_ENV":      app.Ctx.EnvName,
+	}
+	if failure != nil {
+		env["DRAFT_ERROR"] = failure.Error()
+	}
+	lines := &lineWriter{line: func(line string) { summary(line, SummaryLogging) }}
+	results, err := b.Tasks.RunWithOptions(tasks.DefaultRunner, kind, "", tasks.Options{
+		Out: io.MultiWriter(app.Log, lines),
+		Env: env,
+		Job: func(task tasks.Task, command []string, out io.Writer) error {
+			return b.runJob(ctx, app, task, command, out)
+		},
+	})
+	lines.Flush()
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			summary(fmt.Sprintf("task %q %s", r.Name, r.Message), SummaryLogging)
+		case !r.Pass:
+			summary(fmt.Sprintf("task %q failed: %s", r.Name, r.Message), SummaryLogging)
+		}
+	}
+	return err
+}
+
+// lineWriter calls line for every line written to it.
+type lineWriter struct {
+	line func(string)
+	buf  []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.line(strings.TrimSuffix(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush reports the last line, if it does not end with a newline.
+func (w *lineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.line(string(w.buf))
+		w.buf = nil
+	}
+}
+
 // saveState saves information collected from a draft build.
+//
+// The build logs are closed first, so that once the build is stored, its logs are too and
+// followers of the logs can stop.
 func (b *Builder) saveState(app *AppContext) {
-	if err := b.Storage.UpdateBuild(context.Background(), app.Ctx.Env.Name, app.Obj); err != nil {
-		log.Printf("complete: failed to store build object for app %q: %v\n", app.Ctx.Env.Name, err)
-		return
-	}
 	if app.Log != nil {
 		app.Log.Close()
 	}
+	b.storeBuild(app)
+}
+
+// storeBuild stores the build record in the storage of the builder.
+func (b *Builder) storeBuild(app *AppContext) {
+	if err := b.Storage.UpdateBuild(context.Background(), app.Ctx.Env.Name, app.Obj); err != nil {
+		log.Printf("complete: failed to store build object for app %q: %v\n", app.Ctx.Env.Name, err)
+	}
 }
 
 // release installs or updates the application deployment.
 func (b *Builder) release(ctx context.Context, app *AppContext, out chan<- *Summary) (err error) {
-	const stageDesc = "Releasing Application"
+	stageDesc := b.stage("Releasing Application")
 
 	defer Complete(app.ID, stageDesc, out, &err)
 	summary := Summarize(app.ID, stageDesc, out)
@@ -433,17 +628,8 @@ func (b *Builder) release(ctx context.Context, app *AppContext, out chan<- *Summ
 }
 
 func (b *Builder) prepareReleaseEnvironment(ctx context.Context, app *AppContext) error {
-	// determine if the destination namespace exists, create it if not.
-	if _, err := b.Kube.CoreV1().Namespaces().Get(context.Background(), app.Ctx.Env.Namespace, metav1.GetOptions{}); err != nil {
-		if !apiErrors.IsNotFound(err) {
-			return err
-		}
-		_, err = b.Kube.CoreV1().Namespaces().Create(context.Background(), &v1.Namespace{
-			ObjectMeta: metav1.ObjectMeta{Name: app.Ctx.Env.Namespace},
-		}, metav1.CreateOptions{})
-		if err != nil {
-			return fmt.Errorf("could not create namespace %q: %v", app.Ctx.Env.Namespace, err)
-		}
+	if err := b.ensureNamespace(ctx, app); err != nil {
+		return err
 	}
 
 	authToken, err := b.ContainerBuilder.AuthToken(ctx, app)
@@ -523,6 +709,23 @@ func (b *Builder) prepareReleaseEnvironment(ctx context.Context, app *AppContext
 	return nil
 }
 
+// ensureNamespace creates the namespace of the application if it does not exist.
+func (b *Builder) ensureNamespace(ctx context.Context, app *AppContext) error {
+	// determine if the destination namespace exists, create it if not.
+	if _, err := b.Kube.CoreV1().Namespaces().Get(context.Background(), app.Ctx.Env.Namespace, metav1.GetOptions{}); err != nil {
+		if !apiErrors.IsNotFound(err) {
+			return err
+		}
+		_, err = b.Kube.CoreV1().Namespaces().Create(context.Background(), &v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: app.Ctx.Env.Namespace},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("could not create namespace %q: %v", app.Ctx.Env.Namespace, err)
+		}
+	}
+	return nil
+}
+
 func formatReleaseStatus(app *AppContext, rls *release.Release, summary func(string, SummaryStatusCode)) {
 	status := fmt.Sprintf("%s %v", app.Ctx.Env.Name, rls.Info.Status)
 	summary(status, SummaryLogging)
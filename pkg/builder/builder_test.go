@@ -1,10 +1,26 @@
 package builder
 
 import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
+	"golang.org/x/net/context"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	helmstorage "helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+
 	"github.com/Azure/draft/pkg/draft/manifest"
+	"github.com/Azure/draft/pkg/storage/inprocess"
+	"github.com/Azure/draft/pkg/tasks"
 )
 
 func TestArchiveSrc(t *testing.T) {
@@ -26,3 +42,147 @@ func TestArchiveSrc(t *testing.T) {
 		t.Errorf("expected non-zero archive length, got %d", len(ctx.Archive))
 	}
 }
+
+type nopCloser struct{ bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the tasks use sh")
+	}
+	b := &Builder{Tasks: &tasks.Tasks{
+		OnFailure: []tasks.Task{
+			{Name: "notify", Command: `echo "$DRAFT_BUILD_ID $DRAFT_IMAGE $DRAFT_ENV"; echo "$DRAFT_ERROR"`, Shell: true},
+			{Name: "fail", Command: "exit 3", Shell: true},
+		},
+	}}
+	log := &nopCloser{}
+	app := &AppContext{
+		ID:        "01ABC",
+		MainImage: "app:1234",
+		Ctx:       &Context{EnvName: "staging"},
+		Log:       log,
+	}
+	out := make(chan *Summary, 10)
+	err := b.runHooks(context.Background(), app, tasks.OnFailure, errors.New("push denied"), out)
+	close(out)
+	if err == nil {
+		t.Fatal("expected the failing task to fail the stage")
+	}
+
+	expectedLog := "01ABC app:1234 staging\npush denied\n"
+	if log.String() != expectedLog {
+		t.Errorf("expected build log %q, got %q", expectedLog, log.String())
+	}
+	var got []string
+	for s := range out {
+		if s.StageDesc != "Running on-failure tasks" {
+			t.Errorf("unexpected stage %q", s.StageDesc)
+		}
+		got = append(got, s.StatusText)
+	}
+	expected := []string{"started", "01ABC app:1234 staging", "push denied", `task "fail" failed: exit status 3`}
+	if len(got) != len(expected)+1 || !strings.HasPrefix(got[len(got)-1], "failure:") {
+		t.Fatalf("expected summaries %q and a failure, got %q", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("expected summary %q, got %q", expected[i], got[i])
+		}
+	}
+
+	// stages without tasks are skipped.
+	out = make(chan *Summary, 1)
+	if err := b.runHooks(context.Background(), app, tasks.PreBuild, nil, out); err != nil || len(out) != 0 {
+		t.Errorf("expected no pre-build stage, got %v and %d summaries", err, len(out))
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := &lineWriter{line: func(l string) { lines = append(lines, l) }}
+	w.Write([]byte("first\r\nsec"))
+	w.Write([]byte("ond\nthird"))
+	w.Flush()
+	expected := []string{"first", "second", "third"}
+	if strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected lines %q, got %q", expected, lines)
+	}
+}
+
+// countingBuilder counts the images it builds and pushes.
+type countingBuilder struct {
+	builds, pushes int
+}
+
+func (c *countingBuilder) Build(ctx context.Context, app *AppContext, out chan<- *Summary) error {
+	c.builds++
+	return nil
+}
+
+func (c *countingBuilder) Push(ctx context.Context, app *AppContext, out chan<- *Summary) error {
+	c.pushes++
+	return nil
+}
+
+func (c *countingBuilder) AuthToken(ctx context.Context, app *AppContext) (string, error) {
+	return "", nil
+}
+
+func newHelmConfig() *action.Configuration {
+	return &action.Configuration{
+		Releases:     helmstorage.Init(driver.NewMemory()),
+		KubeClient:   &kubefake.PrintingKubeClient{Out: ioutil.Discard},
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          func(string, ...interface{}) {},
+	}
+}
+
+func TestUpReleasesToClusters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "draft-builder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cb := &countingBuilder{}
+	b := New()
+	b.LogsDir = dir
+	b.ContainerBuilder = cb
+	b.ClusterName, b.HelmConfig, b.Storage = "dev", newHelmConfig(), inprocess.NewStore()
+	other := &Cluster{Name: "prod", HelmConfig: newHelmConfig(), Storage: inprocess.NewStore()}
+	b.Clusters = []*Cluster{other}
+
+	bctx := &Context{
+		Env:    &manifest.Environment{Name: "app", Namespace: "default"},
+		Chart:  &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "app", Version: "0.1.0"}},
+		Values: chartutil.Values{},
+	}
+	var stages []string
+	for s := range b.Up(context.Background(), bctx) {
+		if s.StatusCode == SummaryFailure {
+			t.Errorf("stage %q failed: %s", s.StageDesc, s.StatusText)
+		}
+		if s.StatusCode == SummaryStarted {
+			stages = append(stages, s.StageDesc)
+		}
+	}
+
+	if cb.builds != 1 || cb.pushes != 1 {
+		t.Errorf("expected the image to be built and pushed once, got %d builds and %d pushes", cb.builds, cb.pushes)
+	}
+	expected := []string{"Releasing Application (dev)", "Releasing Application (prod)"}
+	if strings.Join(stages, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected stages %q, got %q", expected, stages)
+	}
+	for name, c := range map[string]*Cluster{"dev": {HelmConfig: b.HelmConfig, Storage: b.Storage}, "prod": other} {
+		if _, err := c.HelmConfig.Releases.Last("app"); err != nil {
+			t.Errorf("expected the application to be released to %s: %v", name, err)
+		}
+		builds, err := c.Storage.GetBuilds(context.Background(), "app")
+		if err != nil || len(builds) != 1 || builds[0].GetBuildID() != b.ID {
+			t.Errorf("expected build %s to be stored in %s, got %v (%v)", b.ID, name, builds, err)
+		}
+	}
+}
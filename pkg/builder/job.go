@@ -0,0 +1,240 @@
+package builder
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	batchclient "k8s.io/client-go/kubernetes/typed/batch/v1"
+
+	"github.com/Azure/draft/pkg/local"
+	"github.com/Azure/draft/pkg/tasks"
+)
+
+const (
+	// JobTaskLabel is the label of Jobs, and of their pods, running job tasks. Its value
+	// is the name of the Job.
+	JobTaskLabel = "draft.sh/task"
+
+	jobPollInterval = 2 * time.Second
+	// jobStartTimeout is how long the pod of a job may stay pending, e.g. as it cannot be
+	// scheduled, before the job is considered failed.
+	jobStartTimeout = 5 * time.Minute
+)
+
+// invalidNameChars matches the characters that cannot be part of a Kubernetes object name.
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// runJob runs a job task as a Kubernetes Job from the image of the build, in the namespace
+// of the application, and writes its logs to out. Jobs that succeed are deleted; failed
+// jobs are kept for inspection.
+func (b *Builder) runJob(ctx context.Context, app *AppContext, task tasks.Task, command []string, out io.Writer) error {
+	if app.Ctx.Env.Registry != "" {
+		if err := b.prepareReleaseEnvironment(ctx, app); err != nil {
+			return err
+		}
+	} else if err := b.ensureNamespace(ctx, app); err != nil {
+		return err
+	}
+
+	jobs := b.Kube.BatchV1().Jobs(app.Ctx.Env.Namespace)
+	job, err := jobs.Create(ctx, newJob(app, task, command), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("could not create job: %v", err)
+	}
+	fmt.Fprintf(out, "Running job %s\n", job.Name)
+
+	inspect := fmt.Sprintf("inspect it with 'kubectl describe --namespace %s job/%s'", job.Namespace, job.Name)
+	pod, err := b.waitForJobPod(ctx, job, jobStartTimeout)
+	if err != nil {
+		return fmt.Errorf("job %s did not start: %v; %s", job.Name, err, inspect)
+	}
+	if pod != nil {
+		if err := b.streamLogs(ctx, pod, out); err != nil {
+			fmt.Fprintf(out, "WARNING: could not stream the logs of job %s: %v\n", job.Name, err)
+		}
+	}
+	if err := waitForJob(ctx, jobs, job.Name, jobPollInterval); err != nil {
+		return fmt.Errorf("%v; %s", err, inspect)
+	}
+
+	propagation := metav1.DeletePropagationBackground
+	if err := jobs.Delete(ctx, job.Name, metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil {
+		fmt.Fprintf(out, "WARNING: could not delete job %s: %v\n", job.Name, err)
+	}
+	return nil
+}
+
+// newJob returns the Job running a job task with the given command.
+func newJob(app *AppContext, task tasks.Task, command []string) *batchv1.Job {
+	name := jobName(app.Ctx.Env.Name, task.Name, app.ID)
+
+	var env []v1.EnvVar
+	for k, v := range task.Env {
+		env = append(env, v1.EnvVar{Name: k, Value: v})
+	}
+	sort.Slice(env, func(i, j int) bool { return env[i].Name < env[j].Name })
+
+	backoffLimit := int32(0)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   app.Ctx.Env.Namespace,
+			Labels:      map[string]string{local.DraftLabelKey: app.Ctx.Env.Name, JobTaskLabel: name},
+			Annotations: map[string]string{local.BuildIDKey: app.ID, "draft.sh/task-name": task.Name},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: v1.PodTemplateSpec{
+				// the pods are not labeled as pods of the application, so draft connect
+				// does not select them.
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{JobTaskLabel: name}},
+				Spec: v1.PodSpec{
+					RestartPolicy: v1.RestartPolicyNever,
+					Containers: []v1.Container{{
+						Name:       "task",
+						Image:      app.MainImage,
+						Command:    command,
+						Env:        env,
+						WorkingDir: task.Dir,
+					}},
+				},
+			},
+		},
+	}
+	if task.Timeout.Duration > 0 {
+		deadline := int64(task.Timeout.Duration.Seconds())
+		if deadline < 1 {
+			deadline = 1
+		}
+		job.Spec.ActiveDeadlineSeconds = &deadline
+	}
+	return job
+}
+
+// jobName returns a name for the Job running a task of a build, unique to the build.
+func jobName(app, task, buildID string) string {
+	const maxLen = 63
+	suffix := strings.ToLower(buildID)
+	if len(suffix) > 8 {
+		suffix = suffix[len(suffix)-8:]
+	}
+	prefix := invalidNameChars.ReplaceAllString(strings.ToLower(app+"-"+task), "-")
+	if len(prefix) > maxLen-len(suffix)-1 {
+		prefix = prefix[:maxLen-len(suffix)-1]
+	}
+	return strings.Trim(prefix, "-") + "-" + suffix
+}
+
+// waitForJobPod waits up to timeout for the pod of a job to start. It returns a nil pod if
+// the job finished without starting a pod.
+func (b *Builder) waitForJobPod(ctx context.Context, job *batchv1.Job, timeout time.Duration) (*v1.Pod, error) {
+	pods := b.Kube.CoreV1().Pods(job.Namespace)
+	selector := JobTaskLabel + "=" + job.Name
+	var (
+		pod     *v1.Pod
+		pending string
+	)
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := wait.PollImmediateUntil(jobPollInterval, func() (bool, error) {
+		list, err := pods.List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return false, err
+		}
+		for i := range list.Items {
+			p := &list.Items[i]
+			if p.Status.Phase != v1.PodPending {
+				pod = p
+				return true, nil
+			}
+			for _, s := range p.Status.ContainerStatuses {
+				if w := s.State.Waiting; w != nil && isImageError(w.Reason) {
+					return false, fmt.Errorf("could not pull image %s: %s", s.Image, w.Message)
+				}
+			}
+			for _, c := range p.Status.Conditions {
+				if c.Type == v1.PodScheduled && c.Status == v1.ConditionFalse && c.Message != "" {
+					pending = c.Message
+				}
+			}
+		}
+		// stop waiting if the job failed before a pod started.
+		j, err := b.Kube.BatchV1().Jobs(job.Namespace).Get(ctx, job.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return jobFinished(j), nil
+	}, waitCtx.Done())
+	if err == wait.ErrWaitTimeout && ctx.Err() == nil {
+		err = fmt.Errorf("its pod did not start within %s", timeout)
+		if pending != "" {
+			err = fmt.Errorf("%v: %s", err, pending)
+		}
+	}
+	return pod, err
+}
+
+// streamLogs writes the logs of a pod to out until the pod terminates.
+func (b *Builder) streamLogs(ctx context.Context, pod *v1.Pod, out io.Writer) error {
+	logs, err := b.Kube.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &v1.PodLogOptions{Follow: true}).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+	_, err = io.Copy(out, logs)
+	return err
+}
+
+func isImageError(reason string) bool {
+	switch reason {
+	case "ErrImagePull", "ImagePullBackOff", "InvalidImageName":
+		return true
+	}
+	return false
+}
+
+// waitForJob waits for a job to complete, returning an error if it fails.
+func waitForJob(ctx context.Context, jobs batchclient.JobInterface, name string, interval time.Duration) error {
+	var failure error
+	err := wait.PollImmediateUntil(interval, func() (bool, error) {
+		job, err := jobs.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, c := range job.Status.Conditions {
+			if c.Status != v1.ConditionTrue {
+				continue
+			}
+			switch c.Type {
+			case batchv1.JobComplete:
+				return true, nil
+			case batchv1.JobFailed:
+				failure = fmt.Errorf("job %s failed: %s", name, c.Message)
+				return true, nil
+			}
+		}
+		return false, nil
+	}, ctx.Done())
+	if err != nil {
+		return err
+	}
+	return failure
+}
+
+func jobFinished(job *batchv1.Job) bool {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package builder
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/Azure/draft/pkg/draft/manifest"
+	"github.com/Azure/draft/pkg/tasks"
+)
+
+func TestJobName(t *testing.T) {
+	cases := []struct {
+		app, task, buildID, expected string
+	}{
+		{"myapp", "migrate", "01E5Y3C0ZJ4Q8M9X2R7TKB6N1V", "myapp-migrate-7tkb6n1v"},
+		{"myapp", "Run DB migrations!", "01ABC", "myapp-run-db-migrations-01abc"},
+		{strings.Repeat("a", 70), "seed", "01E5Y3C0ZJ4Q8M9X2R7TKB6N1V", strings.Repeat("a", 54) + "-7tkb6n1v"},
+	}
+	for _, tc := range cases {
+		if got := jobName(tc.app, tc.task, tc.buildID); got != tc.expected {
+			t.Errorf("jobName(%q, %q, %q): expected %q, got %q", tc.app, tc.task, tc.buildID, tc.expected, got)
+		}
+	}
+}
+
+func TestNewJob(t *testing.T) {
+	app := &AppContext{
+		ID:        "01ABC",
+		MainImage: "myregistry/myapp:1234",
+		Ctx:       &Context{Env: &manifest.Environment{Name: "myapp", Namespace: "staging"}},
+	}
+	task := tasks.Task{
+		Name:    "migrate",
+		Type:    tasks.JobTask,
+		Env:     map[string]string{"RAILS_ENV": "production", "DRAFT_BUILD_ID": "01ABC"},
+		Dir:     "/app",
+		Timeout: tasks.Duration{Duration: 90 * time.Second},
+	}
+	job := newJob(app, task, []string{"rake", "db:migrate"})
+
+	if job.Name != "myapp-migrate-01abc" || job.Namespace != "staging" {
+		t.Errorf("unexpected job %s/%s", job.Namespace, job.Name)
+	}
+	if *job.Spec.BackoffLimit != 0 || *job.Spec.ActiveDeadlineSeconds != 90 {
+		t.Errorf("expected the job to run once with a deadline of 90s, got %d and %d", *job.Spec.BackoffLimit, *job.Spec.ActiveDeadlineSeconds)
+	}
+	if _, ok := job.Spec.Template.Labels["draft"]; ok {
+		t.Errorf("expected the job pods not to be labeled as application pods, got %v", job.Spec.Template.Labels)
+	}
+	c := job.Spec.Template.Spec.Containers[0]
+	if c.Image != app.MainImage || strings.Join(c.Command, " ") != "rake db:migrate" || c.WorkingDir != "/app" {
+		t.Errorf("unexpected container %+v", c)
+	}
+	if len(c.Env) != 2 || c.Env[0].Name != "DRAFT_BUILD_ID" || c.Env[1].Name != "RAILS_ENV" {
+		t.Errorf("expected the task environment, got %v", c.Env)
+	}
+}
+
+func TestWaitForJob(t *testing.T) {
+	cases := []struct {
+		condition batchv1.JobConditionType
+		message   string
+		err       string
+	}{
+		{batchv1.JobComplete, "", ""},
+		{batchv1.JobFailed, "Job has reached the specified backoff limit", "job migrate failed: Job has reached the specified backoff limit"},
+	}
+	for _, tc := range cases {
+		clientset := fake.NewSimpleClientset(&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "default"},
+			Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+				{Type: tc.condition, Status: v1.ConditionTrue, Message: tc.message},
+			}},
+		})
+		err := waitForJob(context.Background(), clientset.BatchV1().Jobs("default"), "migrate", time.Millisecond)
+		if tc.err == "" && err != nil {
+			t.Errorf("expected the job to complete, got %v", err)
+		}
+		if tc.err != "" && (err == nil || err.Error() != tc.err) {
+			t.Errorf("expected error %q, got %v", tc.err, err)
+		}
+	}
+}
+
+func TestWaitForJobPod(t *testing.T) {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "myapp-migrate-01abc", Namespace: "default"}}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp-migrate-01abc-x7k2p", Namespace: "default", Labels: map[string]string{JobTaskLabel: job.Name}},
+		Status: v1.PodStatus{
+			Phase: v1.PodPending,
+			ContainerStatuses: []v1.ContainerStatus{{
+				Image: "myregistry/myapp:1234",
+				State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "unauthorized"}},
+			}},
+		},
+	}
+	b := &Builder{Kube: fake.NewSimpleClientset(job, pod)}
+	if _, err := b.waitForJobPod(context.Background(), job, time.Minute); err == nil || !strings.Contains(err.Error(), "could not pull image myregistry/myapp:1234: unauthorized") {
+		t.Errorf("expected an image pull error, got %v", err)
+	}
+
+	pod.Status = v1.PodStatus{Phase: v1.PodRunning}
+	b = &Builder{Kube: fake.NewSimpleClientset(job, pod)}
+	got, err := b.waitForJobPod(context.Background(), job, time.Minute)
+	if err != nil || got == nil || got.Name != pod.Name {
+		t.Errorf("expected pod %s, got %v and %v", pod.Name, got, err)
+	}
+
+	pod.Status = v1.PodStatus{
+		Phase:      v1.PodPending,
+		Conditions: []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionFalse, Message: "0/3 nodes are available"}},
+	}
+	b = &Builder{Kube: fake.NewSimpleClientset(job, pod)}
+	if _, err := b.waitForJobPod(context.Background(), job, 10*time.Millisecond); err == nil || err.Error() != "its pod did not start within 10ms: 0/3 nodes are available" {
+		t.Errorf("expected the pending pod to time out, got %v", err)
+	}
+}
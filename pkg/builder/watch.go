@@ -3,12 +3,15 @@ package builder
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
-	"strings"
+	"sort"
 	"time"
 
 	"github.com/rjeczalik/notify"
 	"golang.org/x/net/context"
+
+	"github.com/Azure/draft/pkg/linguist"
 )
 
 // Watch watches for inotify events in the build context's application directory, returning events
@@ -26,33 +29,66 @@ func (buildctx *Context) Watch(ctx context.Context, stream chan<- *Context) (err
 }
 
 func watch(ctx context.Context, dir string, action func() error) error {
-	infoc := make(chan notify.EventInfo, 1)
-	if err := notify.Watch(dir, infoc, notify.All); err != nil {
+	return WatchFiles(ctx, dir, 0, func([]string) error { return action() })
+}
+
+// WatchFiles watches for changes to the files below dir. Once no further change happened
+// for delay, action is called with the changed paths, relative to dir and sorted.
+// Changes inside the .git/ directory, and to files ignored by the .draftignore or
+// .gitignore files, are ignored.
+func WatchFiles(ctx context.Context, dir string, delay time.Duration, action func(changed []string) error) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	// events report paths with symlinks resolved.
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	ignore, err := linguist.LoadIgnore(dir)
+	if err != nil {
+		return err
+	}
+	infoc := make(chan notify.EventInfo, 64)
+	if err := notify.Watch(filepath.Join(dir, "..."), infoc, notify.All); err != nil {
 		return fmt.Errorf("could not watch %q: %v", dir, err)
 	}
+	defer notify.Stop(infoc)
 
-	evtc := make(chan struct{})
-	go func() {
-		for info := range infoc {
-			prefix := strings.TrimPrefix(info.Path(), dir+"/")
-			// ignore manually everything inside the .git/ directory as
-			// helm ignore file doesn't have directory and whole content
-			// (subdir of subdir) ignore support yet.
-			if filepath.HasPrefix(prefix, ".git/") {
-				continue
-			}
-			evtc <- struct{}{}
-		}
-		close(evtc)
-	}()
-	defer func() {
-		notify.Stop(infoc)
-		close(evtc)
-	}()
+	var (
+		pending = make(map[string]bool)
+		timer   <-chan time.Time
+	)
 	for {
 		select {
-		case <-evtc:
-			if err := action(); err != nil {
+		case info := <-infoc:
+			rel, err := filepath.Rel(dir, info.Path())
+			if err != nil {
+				continue
+			}
+			rel = filepath.ToSlash(rel)
+			if base := path.Base(rel); base == ".draftignore" || base == ".gitignore" {
+				// the ignore files changed, so are the files they ignore.
+				if reloaded, err := linguist.LoadIgnore(dir); err == nil {
+					ignore = reloaded
+				}
+			}
+			// removed files are not directories as far as patterns matching only
+			// directories are concerned.
+			fi, err := os.Lstat(info.Path())
+			if ignore.Ignored(rel, err == nil && fi.IsDir()) {
+				continue
+			}
+			pending[rel] = true
+			timer = time.After(delay)
+		case <-timer:
+			changed := make([]string, 0, len(pending))
+			for p := range pending {
+				changed = append(changed, p)
+			}
+			sort.Strings(changed)
+			pending, timer = make(map[string]bool), nil
+			if err := action(changed); err != nil {
 				return err
 			}
 		case <-ctx.Done():
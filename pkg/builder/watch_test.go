@@ -0,0 +1,61 @@
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestWatchFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "draft-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*.log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ".draftignore"), []byte("tmp/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	changes := make(chan []string, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchFiles(ctx, dir, 200*time.Millisecond, func(changed []string) error {
+			changes <- changed
+			cancel()
+			return nil
+		})
+	}()
+
+	// give the watcher time to start.
+	time.Sleep(200 * time.Millisecond)
+	for _, name := range []string{"src/index.js", "src/index.js", ".git/HEAD", "debug.log", "tmp/cache", "Dockerfile"} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := ioutil.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case changed := <-changes:
+		if expected := []string{"Dockerfile", "src/index.js"}; !reflect.DeepEqual(changed, expected) {
+			t.Errorf("expected changes %v, got %v", expected, changed)
+		}
+	case err := <-done:
+		t.Fatalf("watch stopped before reporting changes: %v", err)
+	}
+	<-done
+}
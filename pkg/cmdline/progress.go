@@ -0,0 +1,129 @@
+package cmdline
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Azure/draft/pkg/builder"
+	"golang.org/x/net/context"
+)
+
+// Progress displays the progress of several applications deployed together, as with
+// 'draft up --all'. Unlike Display, it draws no spinners: every stage completed is printed
+// on its own line, prefixed with the application, so concurrent builds don't garble the
+// output. Display may be called concurrently, once per application.
+type Progress struct {
+	opts    options
+	mu      sync.Mutex
+	results []*Result
+}
+
+// Result is the outcome of the deployment of an application displayed by Progress.
+type Result struct {
+	// App is the application, as given to Display.
+	App string
+	// BuildID is the ID of the build, empty if the build did not start.
+	BuildID string
+	// Failed is set if a stage of the build failed.
+	Failed bool
+	// Duration is the time the build took.
+	Duration time.Duration
+}
+
+// NewProgress returns a Progress configured with opts.
+func NewProgress(opts ...Option) *Progress {
+	p := new(Progress)
+	DefaultOpts()(&p.opts)
+	for _, opt := range opts {
+		opt(&p.opts)
+	}
+	if out := p.opts.stdout; isTerminal(out) {
+		initTerminal(out)
+	} else {
+		NoColor()(&p.opts)
+	}
+	return p
+}
+
+// Display prints the stages of the build of app as they complete, until summaries is
+// closed or ctx is done, and returns the outcome of the build.
+func (p *Progress) Display(ctx context.Context, app string, summaries <-chan *builder.Summary) *Result {
+	r := &Result{App: app}
+	p.mu.Lock()
+	p.results = append(p.results, r)
+	p.mu.Unlock()
+
+	start := time.Now()
+	started := make(map[string]time.Time)
+	defer func() {
+		p.mu.Lock()
+		r.Duration = time.Since(start)
+		p.mu.Unlock()
+	}()
+	for {
+		select {
+		case summary, ok := <-summaries:
+			if !ok {
+				return r
+			}
+			if r.BuildID == "" && summary.BuildID != "" {
+				p.printf(p.opts.stdout, func() {
+					r.BuildID = summary.BuildID
+				}, "%s: %s: %s\n", cyan(app), blue("Draft Up Started"), yellow(summary.BuildID))
+			}
+			if _, ok := started[summary.StageDesc]; !ok {
+				started[summary.StageDesc] = time.Now()
+			}
+			elapsed := time.Since(started[summary.StageDesc]).Seconds()
+			switch summary.StatusCode {
+			case builder.SummarySuccess:
+				p.printf(p.opts.stdout, nil, "%s: %s  (%.4fs)\n", cyan(app), passStr(summary.StageDesc, p.opts.displayEmoji), elapsed)
+			case builder.SummaryFailure:
+				p.printf(p.opts.stderr, func() {
+					r.Failed = true
+				}, "%s: %s  (%.4fs)\n", cyan(app), failStr(summary.StageDesc, p.opts.displayEmoji), elapsed)
+			}
+		case <-ctx.Done():
+			return r
+		}
+	}
+}
+
+// printf writes a line, after updating the results with update if not nil, so lines of
+// concurrent builds never interleave.
+func (p *Progress) printf(w io.Writer, update func(), format string, a ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if update != nil {
+		update()
+	}
+	fmt.Fprintf(w, format, a...)
+}
+
+// Summarize prints the outcome of every application displayed, by application, and returns
+// the results.
+func (p *Progress) Summarize() []Result {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	results := make([]Result, len(p.results))
+	for i, r := range p.results {
+		results[i] = *r
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].App < results[j].App })
+
+	for _, r := range results {
+		w, status := p.opts.stdout, passStr("Draft Up", p.opts.displayEmoji)
+		if r.Failed || r.BuildID == "" {
+			w, status = p.opts.stderr, failStr("Draft Up", p.opts.displayEmoji)
+		}
+		fmt.Fprintf(w, "%s: %s  (%.4fs)", cyan(r.App), status, r.Duration.Seconds())
+		if r.BuildID != "" {
+			fmt.Fprintf(w, " %s `%s`", blue("Inspect the logs with"), yellow("draft logs ", r.BuildID))
+		}
+		fmt.Fprintln(w)
+	}
+	return results
+}
@@ -0,0 +1,70 @@
+package cmdline
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/draft/pkg/builder"
+	"golang.org/x/net/context"
+)
+
+func TestProgress(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	p := NewProgress(WithStdout(&stdout), WithStderr(&stderr))
+
+	builds := map[string][]*builder.Summary{
+		"api": {
+			{StageDesc: "Building Docker Image", StatusCode: builder.SummaryStarted, BuildID: "01API"},
+			{StageDesc: "Building Docker Image", StatusCode: builder.SummarySuccess, BuildID: "01API"},
+			{StageDesc: "Releasing Application", StatusCode: builder.SummaryStarted, BuildID: "01API"},
+			{StageDesc: "Releasing Application", StatusCode: builder.SummarySuccess, BuildID: "01API"},
+		},
+		"web": {
+			{StageDesc: "Building Docker Image", StatusCode: builder.SummaryStarted, BuildID: "01WEB"},
+			{StageDesc: "Building Docker Image", StatusCode: builder.SummaryFailure, BuildID: "01WEB"},
+		},
+	}
+	var wg sync.WaitGroup
+	for app, summaries := range builds {
+		ch := make(chan *builder.Summary, len(summaries))
+		for _, s := range summaries {
+			ch <- s
+		}
+		close(ch)
+		wg.Add(1)
+		go func(app string) {
+			defer wg.Done()
+			p.Display(context.Background(), app, ch)
+		}(app)
+	}
+	wg.Wait()
+
+	results := p.Summarize()
+	if len(results) != 2 || results[0].App != "api" || results[1].App != "web" {
+		t.Fatalf("expected results for api and web, got %+v", results)
+	}
+	if results[0].Failed || results[0].BuildID != "01API" {
+		t.Errorf("expected api to succeed with build 01API, got %+v", results[0])
+	}
+	if !results[1].Failed || results[1].BuildID != "01WEB" {
+		t.Errorf("expected web to fail with build 01WEB, got %+v", results[1])
+	}
+
+	for _, line := range []string{
+		"api: Draft Up Started: 01API",
+		"api: Releasing Application: SUCCESS",
+		"api: Draft Up: SUCCESS",
+		"draft logs 01API",
+	} {
+		if !strings.Contains(stdout.String(), line) {
+			t.Errorf("expected %q in the output, got:\n%s", line, stdout.String())
+		}
+	}
+	for _, line := range []string{"web: Building Docker Image: FAIL", "web: Draft Up: FAIL"} {
+		if !strings.Contains(stderr.String(), line) {
+			t.Errorf("expected %q in the error output, got:\n%s", line, stderr.String())
+		}
+	}
+}
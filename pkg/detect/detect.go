@@ -0,0 +1,146 @@
+// Package detect identifies the framework an application is built with, on top of the
+// languages detected by linguist, by inspecting manifests such as requirements.txt,
+// package.json, go.mod, pom.xml and Gemfile.
+package detect
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// App describes an application detected from a manifest.
+type App struct {
+	// Language is the language of the application, as named by linguist, e.g. "Python".
+	Language string `json:"language"`
+	// Framework is the framework of the application, e.g. "django". It is empty if the
+	// application uses no known framework.
+	Framework string `json:"framework,omitempty"`
+	// RuntimeVersion is the version of the language runtime the application requires,
+	// e.g. "1.14" for Go. It is empty if unknown.
+	RuntimeVersion string `json:"runtimeVersion,omitempty"`
+	// StartCommand is the command starting the application. It is empty if unknown.
+	StartCommand string `json:"startCommand,omitempty"`
+	// Port is the port the application listens on, or 0 if unknown.
+	Port int `json:"port,omitempty"`
+	// Manifest is the file the application was detected from, relative to the
+	// application directory.
+	Manifest string `json:"manifest"`
+}
+
+// PackNames returns the names of the packs suited to the application, most specific
+// first: the pack variant for the framework, such as "python-django", then the pack for
+// the language.
+func (a *App) PackNames() []string {
+	lang := strings.ToLower(a.Language)
+	if a.Framework == "" {
+		return []string{lang}
+	}
+	return []string{lang + "-" + a.Framework, lang}
+}
+
+// detector detects an application from a manifest, given its content.
+type detector struct {
+	manifest string
+	detect   func(dir string, data []byte) (*App, error)
+}
+
+// detectors are tried in order, so applications are returned in a stable order.
+var detectors = []detector{
+	{"go.mod", detectGo},
+	{"package.json", detectNode},
+	{"requirements.txt", detectPython},
+	{"pom.xml", detectJava},
+	{"Gemfile", detectRuby},
+}
+
+// Manifests returns the names of the manifests applications are detected from.
+func Manifests() []string {
+	names := make([]string, len(detectors))
+	for i, d := range detectors {
+		names[i] = d.manifest
+	}
+	return names
+}
+
+// Dir detects the applications whose manifests are at the root of dir. A directory may
+// hold several applications, e.g. a Go service with a package.json for its front end.
+//
+// The port exposed by an existing Dockerfile, or else found in the source code, wins over
+// the default port of the framework.
+func Dir(dir string) ([]*App, error) {
+	var apps []*App
+	port := Port(dir)
+	for _, d := range detectors {
+		data, err := ioutil.ReadFile(filepath.Join(dir, d.manifest))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		app, err := d.detect(dir, data)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %v", d.manifest, err)
+		}
+		app.Manifest = d.manifest
+		if port != 0 {
+			app.Port = port
+		}
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
+
+// ForLanguage returns the application detected for the given language, matched
+// case-insensitively, or nil.
+func ForLanguage(apps []*App, language string) *App {
+	for _, app := range apps {
+		if strings.EqualFold(app.Language, language) {
+			return app
+		}
+	}
+	return nil
+}
+
+// ForPack returns the application detected for the language of the pack named pack, such
+// as python or python-django, matched case-insensitively, or nil.
+func ForPack(apps []*App, pack string) *App {
+	pack = strings.ToLower(pack)
+	for _, app := range apps {
+		lang := strings.ToLower(app.Language)
+		if pack == lang || strings.HasPrefix(pack, lang+"-") {
+			return app
+		}
+	}
+	return nil
+}
+
+// reVersion matches a version number such as 14, 3.8 or 2.7.1.
+var reVersion = regexp.MustCompile(`\d+(\.\d+){0,2}`)
+
+// version returns the first version number found in s, e.g. "14" for ">=14 <16".
+func version(s string) string {
+	return reVersion.FindString(s)
+}
+
+// readVersionFile returns the version number in a file such as .nvmrc, or "".
+func readVersionFile(dir, name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return version(string(data))
+}
+
+// firstExisting returns the first of the named files found in dir, or "".
+func firstExisting(dir string, names ...string) string {
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return name
+		}
+	}
+	return ""
+}
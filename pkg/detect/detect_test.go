@@ -0,0 +1,193 @@
+package detect
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	tests := []struct {
+		dir      string
+		expected []*App
+	}{
+		{"django", []*App{{
+			Language:       "Python",
+			Framework:      "django",
+			RuntimeVersion: "3.8.6",
+			StartCommand:   "gunicorn mysite.wsgi:application --bind 0.0.0.0:8000",
+			Port:           8000,
+			Manifest:       "requirements.txt",
+		}}},
+		{"flask", []*App{{
+			Language:     "Python",
+			Framework:    "flask",
+			StartCommand: "flask run --host=0.0.0.0 --port=5000",
+			Port:         5000,
+			Manifest:     "requirements.txt",
+		}}},
+		{"express", []*App{{
+			Language:       "JavaScript",
+			Framework:      "express",
+			RuntimeVersion: "14.15.0",
+			StartCommand:   "npm start",
+			Port:           4000,
+			Manifest:       "package.json",
+		}}},
+		{"gin", []*App{{
+			Language:       "Go",
+			Framework:      "gin",
+			RuntimeVersion: "1.14",
+			StartCommand:   "./hello-gin",
+			Port:           9000,
+			Manifest:       "go.mod",
+		}}},
+		{"spring-boot", []*App{{
+			Language:       "Java",
+			Framework:      "spring-boot",
+			RuntimeVersion: "8",
+			StartCommand:   "java -jar target/demo-0.0.1-SNAPSHOT.jar",
+			Port:           8080,
+			Manifest:       "pom.xml",
+		}}},
+		{"rails", []*App{{
+			Language:       "Ruby",
+			Framework:      "rails",
+			RuntimeVersion: "2.7.1",
+			StartCommand:   "bundle exec rails server -b 0.0.0.0 -p 3000",
+			Port:           3000,
+			Manifest:       "Gemfile",
+		}}},
+		{"polyglot", []*App{
+			{Language: "Go", RuntimeVersion: "1.15", StartCommand: "./api", Manifest: "go.mod"},
+			{Language: "TypeScript", Framework: "nextjs", StartCommand: "npm start", Port: 3000, Manifest: "package.json"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			apps, err := Dir(filepath.Join("testdata", tt.dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(apps, tt.expected) {
+				for _, a := range apps {
+					t.Logf("got %+v", a)
+				}
+				t.Errorf("expected %d apps, got %d", len(tt.expected), len(apps))
+			}
+		})
+	}
+}
+
+func TestDirInvalidManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "draft-detect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "package.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Dir(dir); err == nil {
+		t.Error("expected an error for an invalid package.json")
+	}
+
+	// directories without manifests have no applications.
+	apps, err := Dir(filepath.Join("testdata", "django", "mysite"))
+	if err != nil || len(apps) != 0 {
+		t.Errorf("expected no applications, got %v and %v", apps, err)
+	}
+}
+
+func TestPackNames(t *testing.T) {
+	tests := []struct {
+		app      App
+		expected []string
+	}{
+		{App{Language: "Python", Framework: "django"}, []string{"python-django", "python"}},
+		{App{Language: "Go"}, []string{"go"}},
+	}
+	for _, tt := range tests {
+		if got := tt.app.PackNames(); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("expected pack names %v, got %v", tt.expected, got)
+		}
+	}
+}
+
+func TestForPack(t *testing.T) {
+	apps := []*App{{Language: "Go"}, {Language: "Python", Framework: "django"}}
+	for pack, expected := range map[string]string{
+		"go":            "Go",
+		"Python-Flask":  "Python",
+		"python-django": "Python",
+		"golang":        "",
+		"javascript":    "",
+	} {
+		var got string
+		if app := ForPack(apps, pack); app != nil {
+			got = app.Language
+		}
+		if got != expected {
+			t.Errorf("%s: expected the application of %q, got %q", pack, expected, got)
+		}
+	}
+}
+
+func TestPort(t *testing.T) {
+	tests := []struct {
+		dir      string
+		expected int
+	}{
+		// EXPOSE in the Dockerfile wins over r.Run(":8081") in main.go.
+		{"gin", 9000},
+		{"express", 4000},
+		// dependencies in node_modules, files ignored by .gitignore and the port of the
+		// database in config/db.py are skipped.
+		{"port", 5050},
+		{"rails", 0},
+	}
+	for _, tt := range tests {
+		if got := Port(filepath.Join("testdata", tt.dir)); got != tt.expected {
+			t.Errorf("%s: expected port %d, got %d", tt.dir, tt.expected, got)
+		}
+	}
+}
+
+func TestRoots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "draft-detect-roots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := []string{
+		"package.json",
+		filepath.Join("services", "api", "go.mod"),
+		filepath.Join("services", "api", "tools", "go.mod"),
+		filepath.Join("services", "auth", "Dockerfile"),
+		filepath.Join("frontend", "package.json"),
+		filepath.Join("frontend", "node_modules", "express", "package.json"),
+		filepath.Join(".github", "actions", "lint", "Dockerfile"),
+		filepath.Join("docs", "README.md"),
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	roots, err := Roots(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"frontend", filepath.Join("services", "api"), filepath.Join("services", "auth")}
+	if !reflect.DeepEqual(roots, expected) {
+		t.Errorf("expected roots %v, got %v", expected, roots)
+	}
+}
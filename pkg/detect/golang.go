@@ -0,0 +1,77 @@
+package detect
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"strings"
+)
+
+// goFrameworks are the Go web frameworks detected from the modules required in go.mod,
+// with the port they listen on by default.
+var goFrameworks = []struct {
+	name, module string
+	port         int
+}{
+	{"gin", "github.com/gin-gonic/gin", 8080},
+	{"echo", "github.com/labstack/echo", 1323},
+	{"fiber", "github.com/gofiber/fiber", 3000},
+}
+
+func detectGo(dir string, data []byte) (*App, error) {
+	app := &App{Language: "Go"}
+	var (
+		module   string
+		requires []string
+		inBlock  bool
+	)
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case inBlock && fields[0] == ")":
+			inBlock = false
+		case inBlock:
+			requires = append(requires, fields[0])
+		case fields[0] == "module" && len(fields) > 1:
+			module = strings.Trim(fields[1], `"`)
+		case fields[0] == "go" && len(fields) > 1:
+			app.RuntimeVersion = fields[1]
+		case fields[0] == "require" && len(fields) > 1 && fields[1] == "(":
+			inBlock = true
+		case fields[0] == "require" && len(fields) > 1:
+			requires = append(requires, fields[1])
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, f := range goFrameworks {
+		if requiresModule(requires, f.module) {
+			app.Framework = f.name
+			app.Port = f.port
+			break
+		}
+	}
+	if module != "" {
+		app.StartCommand = "./" + path.Base(module)
+	}
+	return app, nil
+}
+
+// requiresModule reports whether module, or a major version of it such as module/v2, is
+// required.
+func requiresModule(requires []string, module string) bool {
+	for _, r := range requires {
+		if r == module || strings.HasPrefix(r, module+"/v") {
+			return true
+		}
+	}
+	return false
+}
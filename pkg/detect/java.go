@@ -0,0 +1,83 @@
+package detect
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// javaFrameworks are the Java frameworks detected from the group ID of the dependencies
+// or of the parent in pom.xml.
+var javaFrameworks = []struct {
+	name, groupID string
+}{
+	{"spring-boot", "org.springframework.boot"},
+	{"quarkus", "io.quarkus"},
+	{"micronaut", "io.micronaut"},
+}
+
+type pomXML struct {
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Parent     struct {
+		GroupID string `xml:"groupId"`
+		Version string `xml:"version"`
+	} `xml:"parent"`
+	Properties struct {
+		JavaVersion string `xml:"java.version"`
+		Release     string `xml:"maven.compiler.release"`
+		Source      string `xml:"maven.compiler.source"`
+	} `xml:"properties"`
+	Dependencies []struct {
+		GroupID string `xml:"groupId"`
+	} `xml:"dependencies>dependency"`
+}
+
+func detectJava(dir string, data []byte) (*App, error) {
+	var pom pomXML
+	if err := xml.Unmarshal(data, &pom); err != nil {
+		return nil, err
+	}
+	app := &App{Language: "Java"}
+
+	groups := []string{pom.Parent.GroupID}
+	for _, d := range pom.Dependencies {
+		groups = append(groups, d.GroupID)
+	}
+	for _, f := range javaFrameworks {
+		if hasGroup(groups, f.groupID) {
+			app.Framework = f.name
+			app.Port = 8080
+			break
+		}
+	}
+
+	for _, v := range []string{pom.Properties.JavaVersion, pom.Properties.Release, pom.Properties.Source} {
+		if v != "" {
+			// Java 8 and earlier are versioned 1.8, 1.7...
+			app.RuntimeVersion = strings.TrimPrefix(v, "1.")
+			break
+		}
+	}
+
+	v := pom.Version
+	if v == "" {
+		v = pom.Parent.Version
+	}
+	switch {
+	case app.Framework == "quarkus":
+		app.StartCommand = "java -jar target/quarkus-app/quarkus-run.jar"
+	case pom.ArtifactID != "" && v != "":
+		app.StartCommand = fmt.Sprintf("java -jar target/%s-%s.jar", pom.ArtifactID, v)
+	}
+	return app, nil
+}
+
+func hasGroup(groups []string, groupID string) bool {
+	for _, g := range groups {
+		if g == groupID || strings.HasPrefix(g, groupID+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package detect
+
+import (
+	"encoding/json"
+)
+
+// nodeFrameworks are the Node.js frameworks detected from the dependencies in
+// package.json, most specific first, with the port they listen on by default.
+var nodeFrameworks = []struct {
+	name, dependency string
+	port             int
+}{
+	{"nextjs", "next", 3000},
+	{"nestjs", "@nestjs/core", 3000},
+	{"fastify", "fastify", 3000},
+	{"koa", "koa", 3000},
+	{"express", "express", 3000},
+}
+
+type packageJSON struct {
+	Main            string            `json:"main"`
+	Scripts         map[string]string `json:"scripts"`
+	Engines         map[string]string `json:"engines"`
+	Dependencies    map[string]string `json:"dependencies"`
+	DevDependencies map[string]string `json:"devDependencies"`
+}
+
+func detectNode(dir string, data []byte) (*App, error) {
+	var pkg packageJSON
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, err
+	}
+	app := &App{Language: "JavaScript"}
+	if _, ok := pkg.DevDependencies["typescript"]; ok || firstExisting(dir, "tsconfig.json") != "" {
+		app.Language = "TypeScript"
+	}
+
+	for _, f := range nodeFrameworks {
+		if _, ok := pkg.Dependencies[f.dependency]; ok {
+			app.Framework = f.name
+			app.Port = f.port
+			break
+		}
+	}
+
+	app.RuntimeVersion = version(pkg.Engines["node"])
+	if app.RuntimeVersion == "" {
+		app.RuntimeVersion = readVersionFile(dir, ".nvmrc")
+	}
+
+	switch {
+	case pkg.Scripts["start"] != "":
+		app.StartCommand = "npm start"
+	case pkg.Main != "":
+		app.StartCommand = "node " + pkg.Main
+	default:
+		if main := firstExisting(dir, "server.js", "app.js", "index.js"); main != "" {
+			app.StartCommand = "node " + main
+		}
+	}
+	return app, nil
+}
@@ -0,0 +1,132 @@
+package detect
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/draft/pkg/linguist"
+)
+
+// maxSourceSize is the size above which source files are not searched for a port.
+const maxSourceSize = 1 << 20
+
+// reExpose matches the first port of an EXPOSE instruction of a Dockerfile, such as
+// "EXPOSE 8080/tcp".
+var reExpose = regexp.MustCompile(`(?i)^\s*EXPOSE\s+(\d+)`)
+
+// sourcePorts are the patterns matching the port an application listens on, by the
+// extensions of the source files they apply to.
+var sourcePorts = []struct {
+	exts []string
+	re   *regexp.Regexp
+}{
+	{[]string{".go"}, regexp.MustCompile(`(?:Listen|ListenAndServe|ListenAndServeTLS|Run|Start)\(\s*"[\w.-]*:(\d+)"`)},
+	{[]string{".js", ".mjs", ".ts"}, regexp.MustCompile(`(?:\.listen\(\s*|process\.env\.PORT\s*\|\|\s*)(\d+)`)},
+	// only the calls running a server, as other calls, e.g. to connect to a database,
+	// take a port too.
+	{[]string{".py"}, regexp.MustCompile(`\b(?:run|run_app|run_simple|serve)\([^)]*\bport\s*=\s*(\d+)`)},
+	{[]string{".rb"}, regexp.MustCompile(`set\s+:port,\s*(\d+)`)},
+	{[]string{".properties"}, regexp.MustCompile(`^\s*server\.port\s*=\s*(\d+)`)},
+}
+
+// errPortFound stops the walk of the source files once a port is found.
+var errPortFound = errors.New("port found")
+
+// skippedDirs are not searched for a port, as they hold build output or the chart.
+// Dependencies, such as node_modules, are vendored files which are not searched either.
+var skippedDirs = map[string]bool{
+	"target": true,
+	"charts": true,
+}
+
+// Port returns the port the application in dir listens on, or 0 if unknown. The port
+// exposed by an existing Dockerfile wins over a port found in the source code.
+func Port(dir string) int {
+	if port := dockerfilePort(dir); port != 0 {
+		return port
+	}
+	return sourcePort(dir)
+}
+
+// dockerfilePort returns the first port exposed by the Dockerfile in dir, or 0.
+func dockerfilePort(dir string) int {
+	f, err := os.Open(filepath.Join(dir, "Dockerfile"))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if m := reExpose.FindStringSubmatch(s.Text()); m != nil {
+			return parsePort(m[1])
+		}
+	}
+	return 0
+}
+
+// sourcePort returns the first port found in the source files of dir, walked in lexical
+// order, or 0. Files ignored by the ignore files of dir and vendored files are skipped.
+func sourcePort(dir string) int {
+	var port int
+	linguist.Walk(context.Background(), dir, func(rel string, info os.FileInfo) error {
+		if info.Size() > maxSourceSize || inSkippedDir(rel) {
+			return nil
+		}
+		ext := path.Ext(rel)
+		for _, p := range sourcePorts {
+			if !hasExt(p.exts, ext) {
+				continue
+			}
+			data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+			if err != nil {
+				return nil
+			}
+			for _, line := range strings.Split(string(data), "\n") {
+				if m := p.re.FindStringSubmatch(line); m != nil {
+					if port = parsePort(m[1]); port != 0 {
+						return errPortFound
+					}
+				}
+			}
+		}
+		return nil
+	})
+	return port
+}
+
+// inSkippedDir returns whether rel is in a hidden or skipped directory.
+func inSkippedDir(rel string) bool {
+	dirs := strings.Split(rel, "/")
+	for _, d := range dirs[:len(dirs)-1] {
+		if strings.HasPrefix(d, ".") || skippedDirs[d] {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePort returns s as a port number, or 0 if s is not a valid port.
+func parsePort(s string) int {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 1 || port > 65535 {
+		return 0
+	}
+	return port
+}
+
+func hasExt(exts []string, ext string) bool {
+	for _, e := range exts {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package detect
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// rePythonRequirement matches the name of the package of a requirement, such as
+// "Django" in "Django>=3.0,<4.0".
+var rePythonRequirement = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)`)
+
+func detectPython(dir string, data []byte) (*App, error) {
+	requirements := make(map[string]bool)
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") {
+			continue
+		}
+		if name := rePythonRequirement.FindString(line); name != "" {
+			requirements[strings.ToLower(strings.Replace(name, "_", "-", -1))] = true
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	app := &App{Language: "Python"}
+	module := strings.TrimSuffix(firstExisting(dir, "app.py", "main.py", "server.py", "wsgi.py"), ".py")
+	switch {
+	case requirements["django"]:
+		app.Framework = "django"
+		app.Port = 8000
+		app.StartCommand = fmt.Sprintf("python manage.py runserver 0.0.0.0:%d", app.Port)
+		if project := djangoProject(dir); project != "" && requirements["gunicorn"] {
+			app.StartCommand = fmt.Sprintf("gunicorn %s.wsgi:application --bind 0.0.0.0:%d", project, app.Port)
+		}
+	case requirements["fastapi"]:
+		app.Framework = "fastapi"
+		app.Port = 8000
+		if module == "" {
+			module = "main"
+		}
+		app.StartCommand = fmt.Sprintf("uvicorn %s:app --host 0.0.0.0 --port %d", module, app.Port)
+	case requirements["flask"]:
+		app.Framework = "flask"
+		app.Port = 5000
+		if module == "" {
+			module = "app"
+		}
+		app.StartCommand = fmt.Sprintf("flask run --host=0.0.0.0 --port=%d", app.Port)
+		if requirements["gunicorn"] {
+			app.StartCommand = fmt.Sprintf("gunicorn %s:app --bind 0.0.0.0:%d", module, app.Port)
+		}
+	default:
+		if module != "" {
+			app.StartCommand = "python " + module + ".py"
+		}
+	}
+
+	app.RuntimeVersion = readVersionFile(dir, "runtime.txt")
+	if app.RuntimeVersion == "" {
+		app.RuntimeVersion = readVersionFile(dir, ".python-version")
+	}
+	return app, nil
+}
+
+// djangoProject returns the name of the Django project, the package holding wsgi.py
+// next to manage.py, or "".
+func djangoProject(dir string) string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, f := range files {
+		if f.IsDir() && firstExisting(filepath.Join(dir, f.Name()), "wsgi.py") != "" {
+			return f.Name()
+		}
+	}
+	return ""
+}
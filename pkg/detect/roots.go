@@ -0,0 +1,38 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Roots returns the directories below dir holding an application, identified by a
+// Dockerfile or one of the manifests applications are detected from, relative to dir and
+// in lexical order. Applications don't nest: the directories below an application root
+// belong to that application. dir itself is not a candidate, as the root of a monorepo
+// often holds a manifest for its tooling only.
+func Roots(dir string) ([]string, error) {
+	markers := append([]string{"Dockerfile"}, Manifests()...)
+	var roots []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || path == dir {
+			return nil
+		}
+		if name := info.Name(); strings.HasPrefix(name, ".") || skippedDirs[name] || name == "testdata" {
+			return filepath.SkipDir
+		}
+		if firstExisting(path, markers...) == "" {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		roots = append(roots, rel)
+		return filepath.SkipDir
+	})
+	return roots, err
+}
@@ -0,0 +1,53 @@
+package detect
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+var (
+	// reGem matches a gem declaration of a Gemfile, such as gem 'rails', '~> 6.0'.
+	reGem = regexp.MustCompile(`^\s*gem\s+["']([^"']+)["']`)
+	// reRubyVersion matches the Ruby version declaration of a Gemfile, such as ruby '2.7.1'.
+	reRubyVersion = regexp.MustCompile(`^\s*ruby\s+["']([^"']+)["']`)
+)
+
+func detectRuby(dir string, data []byte) (*App, error) {
+	app := &App{Language: "Ruby"}
+	gems := make(map[string]bool)
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		if m := reGem.FindStringSubmatch(s.Text()); m != nil {
+			gems[m[1]] = true
+		}
+		if m := reRubyVersion.FindStringSubmatch(s.Text()); m != nil {
+			app.RuntimeVersion = version(m[1])
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if app.RuntimeVersion == "" {
+		app.RuntimeVersion = readVersionFile(dir, ".ruby-version")
+	}
+
+	switch {
+	case gems["rails"]:
+		app.Framework = "rails"
+		app.Port = 3000
+		app.StartCommand = fmt.Sprintf("bundle exec rails server -b 0.0.0.0 -p %d", app.Port)
+	case gems["sinatra"]:
+		app.Framework = "sinatra"
+		app.Port = 4567
+		if firstExisting(dir, "config.ru") != "" {
+			app.StartCommand = fmt.Sprintf("bundle exec rackup --host 0.0.0.0 -p %d", app.Port)
+		} else if main := firstExisting(dir, "app.rb", "server.rb"); main != "" {
+			app.StartCommand = fmt.Sprintf("bundle exec ruby %s -o 0.0.0.0 -p %d", main, app.Port)
+		}
+	case firstExisting(dir, "config.ru") != "":
+		app.StartCommand = "bundle exec rackup --host 0.0.0.0"
+	}
+	return app, nil
+}
@@ -0,0 +1,8 @@
+module github.com/example/hello-gin
+
+go 1.14
+
+require (
+	github.com/gin-gonic/gin v1.6.3 // indirect
+	github.com/stretchr/testify v1.5.1
+)
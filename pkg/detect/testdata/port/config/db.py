@@ -0,0 +1 @@
+DATABASE = connect(host="db", port=5432)
@@ -0,0 +1,3 @@
+from app import app
+
+app.run(host="0.0.0.0", port=5050)
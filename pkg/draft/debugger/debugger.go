@@ -0,0 +1,244 @@
+// Package debugger applies debug overlays to application pods, so a remote debugger can
+// be attached to the application running in the cluster.
+package debugger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/BurntSushi/toml"
+	"k8s.io/api/core/v1"
+)
+
+const (
+	// Annotation is the pod template annotation marking pods running with a debug overlay.
+	// Its value identifies the debug session.
+	Annotation = "draft.sh/debug"
+	// PortName is the name of the container port the debugger listens on.
+	PortName = "debug"
+
+	// ProgramPlaceholder is replaced by the program of the original container command.
+	ProgramPlaceholder = "{{program}}"
+	// ArgsPlaceholder is replaced by the arguments of the original container command.
+	ArgsPlaceholder = "{{args}}"
+)
+
+// Overlay describes how to run a container with a debugger attached.
+type Overlay struct {
+	// Language is the language the overlay is for. Fields left empty in a debug config
+	// file default to the built-in overlay of the language.
+	Language string `toml:"language"`
+	// Port is the port the debugger listens on.
+	Port int `toml:"port"`
+	// Env is set in the container, overriding variables of the same name.
+	Env map[string]string `toml:"env"`
+	// Command replaces the container command. An element equal to {{program}} is replaced
+	// by the program of the original command, an element equal to {{args}} by its arguments.
+	Command []string `toml:"command"`
+	// Instructions explain how to attach to the debugger. {{.Port}} is the local port
+	// the debugger is forwarded to.
+	Instructions string `toml:"instructions"`
+}
+
+// builtin are the overlays used when no debug config is given.
+var builtin = map[string]Overlay{
+	"go": {
+		Port: 2345,
+		Command: []string{
+			"dlv", "exec", ProgramPlaceholder,
+			"--headless", "--listen=:2345", "--api-version=2", "--accept-multiclient", "--continue",
+			"--", ArgsPlaceholder,
+		},
+		Instructions: `The container image must provide dlv (go get github.com/go-delve/delve/cmd/dlv).
+Attach with:
+    dlv connect localhost:{{.Port}}
+or in VS Code, with a launch configuration of "type": "go", "request": "attach", "mode": "remote", "port": {{.Port}}.`,
+	},
+	"javascript": {
+		Port: 9229,
+		Env:  map[string]string{"NODE_OPTIONS": "--inspect=0.0.0.0:9229"},
+		Instructions: `Attach with Chrome DevTools at chrome://inspect, adding localhost:{{.Port}} as a target,
+or in VS Code, with a launch configuration of "type": "node", "request": "attach", "port": {{.Port}}.`,
+	},
+	"python": {
+		Port:    5678,
+		Command: []string{ProgramPlaceholder, "-m", "debugpy", "--listen", "0.0.0.0:5678", ArgsPlaceholder},
+		Instructions: `The container image must provide debugpy (pip install debugpy).
+Attach in VS Code with a launch configuration of "type": "python", "request": "attach", "connect": {"host": "localhost", "port": {{.Port}}}.`,
+	},
+	"java": {
+		Port: 5005,
+		Env:  map[string]string{"JAVA_TOOL_OPTIONS": "-agentlib:jdwp=transport=dt_socket,server=y,suspend=n,address=*:5005"},
+		Instructions: `Attach a remote JVM debugger to localhost:{{.Port}}, e.g. with
+    jdb -attach localhost:{{.Port}}
+or in IntelliJ IDEA with a "Remote JVM Debug" run configuration.`,
+	},
+}
+
+// aliases map languages detected by linguist to the language of their overlay.
+var aliases = map[string]string{
+	"typescript": "javascript",
+	"kotlin":     "java",
+	"scala":      "java",
+	"groovy":     "java",
+}
+
+// Languages returns the languages with a built-in overlay, sorted.
+func Languages() []string {
+	var languages []string
+	for l := range builtin {
+		languages = append(languages, l)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// ForLanguage returns the built-in overlay for the language, matched case-insensitively.
+func ForLanguage(language string) (*Overlay, bool) {
+	l := strings.ToLower(language)
+	if alias, ok := aliases[l]; ok {
+		l = alias
+	}
+	o, ok := builtin[l]
+	if !ok {
+		return nil, false
+	}
+	o.Language = l
+	return &o, true
+}
+
+// Load reads a debug config file. Fields left empty default to the built-in overlay of
+// the language, if any.
+func Load(path string) (*Overlay, error) {
+	o := new(Overlay)
+	if _, err := toml.DecodeFile(path, o); err != nil {
+		return nil, fmt.Errorf("could not read debug config %s: %v", path, err)
+	}
+	if b, ok := ForLanguage(o.Language); ok {
+		if o.Port == 0 {
+			o.Port = b.Port
+		}
+		if o.Env == nil {
+			o.Env = b.Env
+		}
+		if o.Command == nil {
+			o.Command = b.Command
+		}
+		if o.Instructions == "" {
+			o.Instructions = b.Instructions
+		}
+	}
+	if o.Port == 0 {
+		return nil, fmt.Errorf("debug config %s does not set the debugger port", path)
+	}
+	return o, nil
+}
+
+// AttachInstructions returns the instructions to attach to the debugger forwarded to
+// the given local port.
+func (o *Overlay) AttachInstructions(localPort int) (string, error) {
+	t, err := template.New("instructions").Parse(o.Instructions)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, struct{ Port int }{localPort}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Apply applies the overlay to the container of the pod template given by name, or to
+// the first container if name is empty. image is the command of the container image,
+// used when the overlay replaces the command of a container that does not set one.
+// The pod template is annotated with session.
+func (o *Overlay) Apply(tmpl *v1.PodTemplateSpec, name string, image ImageCommand, session string) error {
+	c, err := findContainer(tmpl.Spec.Containers, name)
+	if err != nil {
+		return err
+	}
+
+	if len(o.Command) > 0 {
+		original := append(append([]string{}, c.Command...), c.Args...)
+		if len(c.Command) == 0 {
+			original = append([]string{}, image.Entrypoint...)
+			if len(c.Args) > 0 {
+				original = append(original, c.Args...)
+			} else {
+				original = append(original, image.Cmd...)
+			}
+		}
+		if len(original) == 0 {
+			return fmt.Errorf("could not determine the command of container %s; set the command of the debug config", c.Name)
+		}
+		c.Command, c.Args = expandCommand(o.Command, original), nil
+	}
+
+	for k, v := range o.Env {
+		setEnv(c, k, v)
+	}
+	if !hasPort(c, o.Port) {
+		c.Ports = append(c.Ports, v1.ContainerPort{Name: PortName, ContainerPort: int32(o.Port), Protocol: v1.ProtocolTCP})
+	}
+	// a paused debuggee must not be restarted.
+	c.LivenessProbe = nil
+
+	if tmpl.Annotations == nil {
+		tmpl.Annotations = make(map[string]string)
+	}
+	tmpl.Annotations[Annotation] = session
+	return nil
+}
+
+func findContainer(containers []v1.Container, name string) (*v1.Container, error) {
+	if len(containers) == 0 {
+		return nil, errors.New("pod template has no containers")
+	}
+	if name == "" {
+		return &containers[0], nil
+	}
+	for i := range containers {
+		if containers[i].Name == name {
+			return &containers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("container '%s' not found", name)
+}
+
+func expandCommand(command, original []string) []string {
+	var expanded []string
+	for _, arg := range command {
+		switch arg {
+		case ProgramPlaceholder:
+			expanded = append(expanded, original[0])
+		case ArgsPlaceholder:
+			expanded = append(expanded, original[1:]...)
+		default:
+			expanded = append(expanded, arg)
+		}
+	}
+	return expanded
+}
+
+func setEnv(c *v1.Container, name, value string) {
+	for i := range c.Env {
+		if c.Env[i].Name == name {
+			c.Env[i] = v1.EnvVar{Name: name, Value: value}
+			return
+		}
+	}
+	c.Env = append(c.Env, v1.EnvVar{Name: name, Value: value})
+}
+
+func hasPort(c *v1.Container, port int) bool {
+	for _, p := range c.Ports {
+		if int(p.ContainerPort) == port {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package debugger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestForLanguage(t *testing.T) {
+	tests := []struct {
+		language string
+		expected string
+		port     int
+	}{
+		{"Go", "go", 2345},
+		{"JavaScript", "javascript", 9229},
+		{"TypeScript", "javascript", 9229},
+		{"Python", "python", 5678},
+		{"Java", "java", 5005},
+		{"Kotlin", "java", 5005},
+	}
+	for _, tt := range tests {
+		o, ok := ForLanguage(tt.language)
+		if !ok {
+			t.Errorf("expected an overlay for %s", tt.language)
+			continue
+		}
+		if o.Language != tt.expected || o.Port != tt.port {
+			t.Errorf("%s: expected overlay %s on port %d, got %s on port %d", tt.language, tt.expected, tt.port, o.Language, o.Port)
+		}
+	}
+	if _, ok := ForLanguage("COBOL"); ok {
+		t.Error("expected no overlay for COBOL")
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		spec     v1.Container
+		image    ImageCommand
+		command  []string
+		env      []v1.EnvVar
+	}{
+		{
+			name:     "go with image entrypoint",
+			language: "go",
+			spec:     v1.Container{Name: "app"},
+			image:    ImageCommand{Entrypoint: []string{"/app/server"}, Cmd: []string{"--verbose"}},
+			command:  []string{"dlv", "exec", "/app/server", "--headless", "--listen=:2345", "--api-version=2", "--accept-multiclient", "--continue", "--", "--verbose"},
+		},
+		{
+			name:     "python with container command",
+			language: "python",
+			spec:     v1.Container{Name: "app", Command: []string{"python"}, Args: []string{"app.py", "--port", "8080"}},
+			command:  []string{"python", "-m", "debugpy", "--listen", "0.0.0.0:5678", "app.py", "--port", "8080"},
+		},
+		{
+			name:     "python with container args replacing the image cmd",
+			language: "python",
+			spec:     v1.Container{Name: "app", Args: []string{"worker.py"}},
+			image:    ImageCommand{Entrypoint: []string{"python3"}, Cmd: []string{"app.py"}},
+			command:  []string{"python3", "-m", "debugpy", "--listen", "0.0.0.0:5678", "worker.py"},
+		},
+		{
+			name:     "node keeps the command",
+			language: "javascript",
+			spec:     v1.Container{Name: "app", Command: []string{"npm", "start"}, Env: []v1.EnvVar{{Name: "NODE_OPTIONS", Value: "--max-old-space-size=512"}, {Name: "PORT", Value: "3000"}}},
+			command:  []string{"npm", "start"},
+			env:      []v1.EnvVar{{Name: "NODE_OPTIONS", Value: "--inspect=0.0.0.0:9229"}, {Name: "PORT", Value: "3000"}},
+		},
+		{
+			name:     "java",
+			language: "java",
+			spec:     v1.Container{Name: "app"},
+			env:      []v1.EnvVar{{Name: "JAVA_TOOL_OPTIONS", Value: "-agentlib:jdwp=transport=dt_socket,server=y,suspend=n,address=*:5005"}},
+		},
+	}
+	for _, tt := range tests {
+		o, _ := ForLanguage(tt.language)
+		tt.spec.LivenessProbe = &v1.Probe{}
+		tmpl := &v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{tt.spec}}}
+		if err := o.Apply(tmpl, "", tt.image, "session"); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		c := tmpl.Spec.Containers[0]
+		if !reflect.DeepEqual(c.Command, tt.command) {
+			t.Errorf("%s: expected command %q, got %q", tt.name, tt.command, c.Command)
+		}
+		if tt.env != nil && !reflect.DeepEqual(c.Env, tt.env) {
+			t.Errorf("%s: expected env %v, got %v", tt.name, tt.env, c.Env)
+		}
+		if !hasPort(&c, o.Port) {
+			t.Errorf("%s: expected the debugger port %d to be exposed", tt.name, o.Port)
+		}
+		if c.LivenessProbe != nil {
+			t.Errorf("%s: expected the liveness probe to be removed", tt.name)
+		}
+		if tmpl.Annotations[Annotation] != "session" {
+			t.Errorf("%s: expected the pod template to be annotated", tt.name)
+		}
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	o, _ := ForLanguage("go")
+	tmpl := &v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}}}
+	if err := o.Apply(tmpl, "", ImageCommand{}, "session"); err == nil {
+		t.Error("expected an error when the command of the container is unknown")
+	}
+	if err := o.Apply(tmpl, "sidecar", ImageCommand{Cmd: []string{"/app"}}, "session"); err == nil || !strings.Contains(err.Error(), "sidecar") {
+		t.Errorf("expected an error about the missing container, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "draft-debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "debug.toml")
+	config := `language = "go"
+command = ["dlv", "exec", "/go/bin/app", "--headless", "--listen=:2345", "--api-version=2"]
+[env]
+GOTRACEBACK = "all"
+`
+	if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	o, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Port != 2345 {
+		t.Errorf("expected the port of the built-in overlay, got %d", o.Port)
+	}
+	if o.Command[2] != "/go/bin/app" || o.Env["GOTRACEBACK"] != "all" {
+		t.Errorf("expected the debug config to override the built-in overlay, got %+v", o)
+	}
+	instructions, err := o.AttachInstructions(40000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(instructions, "dlv connect localhost:40000") {
+		t.Errorf("expected instructions for the local port, got %q", instructions)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`language = "cobol"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected an error for a debug config without a port")
+	}
+}
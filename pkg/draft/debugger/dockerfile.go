@@ -0,0 +1,61 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// ImageCommand is the command a container image runs by default.
+type ImageCommand struct {
+	Entrypoint []string
+	Cmd        []string
+}
+
+// ParseDockerfile returns the ENTRYPOINT and CMD of the final stage of a Dockerfile.
+// Instructions inherited from the base image are unknown and left empty.
+func ParseDockerfile(r io.Reader) (ImageCommand, error) {
+	var (
+		cmd  ImageCommand
+		line string
+		s    = bufio.NewScanner(r)
+	)
+	for s.Scan() {
+		text := strings.TrimSpace(s.Text())
+		if line == "" && (text == "" || strings.HasPrefix(text, "#")) {
+			continue
+		}
+		// join continuation lines.
+		if strings.HasSuffix(text, "\\") {
+			line += strings.TrimSuffix(text, "\\") + " "
+			continue
+		}
+		line += text
+
+		instruction, args := line, ""
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			instruction, args = line[:i], strings.TrimSpace(line[i:])
+		}
+		instruction = strings.ToUpper(instruction)
+		switch instruction {
+		case "FROM":
+			cmd = ImageCommand{}
+		case "ENTRYPOINT":
+			cmd.Entrypoint = parseCommand(args)
+		case "CMD":
+			cmd.Cmd = parseCommand(args)
+		}
+		line = ""
+	}
+	return cmd, s.Err()
+}
+
+// parseCommand parses the exec form of a command, falling back to the shell form.
+func parseCommand(args string) []string {
+	var exec []string
+	if strings.HasPrefix(args, "[") && json.Unmarshal([]byte(args), &exec) == nil {
+		return exec
+	}
+	return []string{"/bin/sh", "-c", args}
+}
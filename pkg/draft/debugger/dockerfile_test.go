@@ -0,0 +1,54 @@
+package debugger
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDockerfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		dockerfile string
+		expected   ImageCommand
+	}{
+		{
+			name: "exec form",
+			dockerfile: `FROM golang:1.14
+COPY . /go/src/app
+ENTRYPOINT ["/go/bin/app"]
+CMD ["--port", "8080"]
+`,
+			expected: ImageCommand{Entrypoint: []string{"/go/bin/app"}, Cmd: []string{"--port", "8080"}},
+		},
+		{
+			name: "shell form",
+			dockerfile: `FROM node:12
+cmd npm start
+`,
+			expected: ImageCommand{Cmd: []string{"/bin/sh", "-c", "npm start"}},
+		},
+		{
+			name: "final stage of a multi-stage build",
+			dockerfile: `FROM golang:1.14 AS build
+ENTRYPOINT ["go"]
+# the final stage
+FROM alpine
+COPY --from=build /go/bin/app /app
+CMD ["/app", \
+     "--verbose"]
+`,
+			expected: ImageCommand{Cmd: []string{"/app", "--verbose"}},
+		},
+	}
+	for _, tt := range tests {
+		actual, err := ParseDockerfile(strings.NewReader(tt.dockerfile))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.expected, actual)
+		}
+	}
+}
@@ -31,6 +31,21 @@ func (h Home) Logs() string {
 	return h.Path("logs")
 }
 
+// Storage returns the path to the Draft local build storage database.
+func (h Home) Storage() string {
+	return h.Path("storage.db")
+}
+
+// Ports returns the path to the local ports assigned to application connections.
+func (h Home) Ports() string {
+	return h.Path("ports.json")
+}
+
+// Connections returns the path to the registry of detached connections.
+func (h Home) Connections() string {
+	return h.Path("connections")
+}
+
 // Plugins returns the path to the Draft plugins.
 func (h Home) Plugins() string {
 	return h.Path("plugins")
@@ -18,5 +18,8 @@ func TestDraftHome(t *testing.T) {
 
 	isEq(t, ph.String(), "/r")
 	isEq(t, ph.Packs(), "/r/packs")
+	isEq(t, ph.Storage(), "/r/storage.db")
 	isEq(t, ph.Plugins(), "/r/plugins")
+	isEq(t, ph.Ports(), "/r/ports.json")
+	isEq(t, ph.Connections(), "/r/connections")
 }
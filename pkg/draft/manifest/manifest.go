@@ -34,6 +34,9 @@ type Environment struct {
 	BuildTarPath      string            `toml:"build-tar,omitempty"`
 	ChartTarPath      string            `toml:"chart-tar,omitempty"`
 	Namespace         string            `toml:"namespace,omitempty"`
+	StorageNamespace  string            `toml:"storage-namespace,omitempty"`
+	KubeContext       string            `toml:"kube-context,omitempty"`
+	KubeContexts      []string          `toml:"kube-contexts,omitempty"`
 	Values            []string          `toml:"set,omitempty"`
 	Wait              bool              `toml:"wait"`
 	Watch             bool              `toml:"watch"`
@@ -44,6 +47,18 @@ type Environment struct {
 	Dockerfile        string            `toml:"dockerfile"`
 	Chart             string            `toml:"chart"`
 	ImageBuildArgs    map[string]string `toml:"image-build-args,omitempty"`
+	Sync              *Sync             `toml:"sync,omitempty"`
+}
+
+// Sync configures the files copied into the running application by draft sync, instead of
+// rebuilding and redeploying the image.
+type Sync struct {
+	// Paths maps local paths, relative to the application directory, to paths in the container.
+	Paths map[string]string `toml:"paths"`
+	// Container is the container files are copied into. Defaults to the first container of the pod.
+	Container string `toml:"container,omitempty"`
+	// Restart is a shell command run in the container after files were copied.
+	Restart string `toml:"restart,omitempty"`
 }
 
 // New creates a new manifest with the Environments intialized.
@@ -63,6 +78,23 @@ func New() *Manifest {
 	return &m
 }
 
+// Contexts returns the names of the kubeconfig contexts the environment is
+// bound to, combining kube-context and kube-contexts. It returns an empty slice
+// if the environment does not declare any.
+func (e *Environment) Contexts() []string {
+	var (
+		contexts []string
+		seen     = make(map[string]bool)
+	)
+	for _, c := range append([]string{e.KubeContext}, e.KubeContexts...) {
+		if c != "" && !seen[c] {
+			seen[c] = true
+			contexts = append(contexts, c)
+		}
+	}
+	return contexts
+}
+
 // generateName generates a name based on the current working directory or a random name.
 func generateName() string {
 	var name string
@@ -2,13 +2,16 @@ package manifest
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
+
+	"github.com/BurntSushi/toml"
 )
 
 func TestNew(t *testing.T) {
 	m := New()
 	m.Environments[DefaultEnvironmentName].Name = "foobar"
-	expected := "&{foobar      default [] true false 2 [] false [] Dockerfile  map[]}"
+	expected := "&{foobar      default   [] [] true false 2 [] false [] Dockerfile  map[] <nil>}"
 
 	actual := fmt.Sprintf("%v", m.Environments[DefaultEnvironmentName])
 	if expected != actual {
@@ -25,3 +28,46 @@ func TestGenerateName(t *testing.T) {
 		t.Errorf("expected name to take the form of the current directory, got %s", name)
 	}
 }
+
+func TestContexts(t *testing.T) {
+	testCases := []struct {
+		env      Environment
+		expected []string
+	}{
+		{Environment{}, nil},
+		{Environment{KubeContext: "dev"}, []string{"dev"}},
+		{Environment{KubeContexts: []string{"east", "west"}}, []string{"east", "west"}},
+		{Environment{KubeContext: "east", KubeContexts: []string{"east", "west", "west"}}, []string{"east", "west"}},
+	}
+
+	for _, tc := range testCases {
+		if actual := tc.env.Contexts(); !reflect.DeepEqual(tc.expected, actual) {
+			t.Errorf("wanted %v, got %v", tc.expected, actual)
+		}
+	}
+}
+
+func TestSyncTable(t *testing.T) {
+	const draftToml = `
+[environments.development]
+name = "web"
+[environments.development.sync]
+container = "node"
+restart = "kill -HUP 1"
+[environments.development.sync.paths]
+"src" = "/app/src"
+"templates" = "/app/views"
+`
+	m := New()
+	if _, err := toml.Decode(draftToml, m); err != nil {
+		t.Fatal(err)
+	}
+	expected := &Sync{
+		Paths:     map[string]string{"src": "/app/src", "templates": "/app/views"},
+		Container: "node",
+		Restart:   "kill -HUP 1",
+	}
+	if actual := m.Environments[DefaultEnvironmentName].Sync; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("wanted %+v, got %+v", expected, actual)
+	}
+}
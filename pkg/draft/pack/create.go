@@ -8,8 +8,9 @@ import (
 	"github.com/Azure/draft/pkg/draft/pack/repo"
 )
 
-// CreateFrom scaffolds a directory with the src pack
-func CreateFrom(dest, src string, appName string) error {
+// CreateFrom scaffolds a directory with the src pack, filling the placeholders of the pack
+// with values
+func CreateFrom(dest, src string, appName string, values map[string]string) error {
 	// first do some validation that we are copying from a valid pack directory
 	pack, err := FromDir(src)
 	if err != nil {
@@ -25,6 +26,9 @@ func CreateFrom(dest, src string, appName string) error {
 			pack.Chart.Metadata.Name = filepath.Base(cwd)
 		}
 	}
+	if err := pack.Render(values); err != nil {
+		return err
+	}
 	return pack.SaveDir(dest)
 }
 
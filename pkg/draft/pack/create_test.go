@@ -23,7 +23,7 @@ func TestCreateFrom(t *testing.T) {
 		t.Errorf("expected err to be nil, got %v", err)
 	}
 
-	if err := CreateFrom(tdir, filepath.Join(odir, "testdata", "pack-python"), ""); err != nil {
+	if err := CreateFrom(tdir, filepath.Join(odir, "testdata", "pack-python"), "", nil); err != nil {
 		t.Errorf("expected err to be nil, got %v", err)
 	}
 
@@ -53,7 +53,7 @@ func TestCreateFrom(t *testing.T) {
 		}
 	}
 
-	if err := CreateFrom(tdir, filepath.Join("testdata", "pack-does-not-exist"), ""); err == nil {
+	if err := CreateFrom(tdir, filepath.Join("testdata", "pack-does-not-exist"), "", nil); err == nil {
 		t.Error("expected err to be non-nil with an invalid source pack")
 	}
 }
@@ -34,7 +34,11 @@ func FromDir(dir string) (*Pack, error) {
 
 	// load all files in pack directory
 	for _, fInfo := range files {
-		if !fInfo.IsDir() {
+		if fInfo.Name() == PlaceholdersFileName {
+			if pack.Placeholders, err = loadPlaceholders(filepath.Join(topdir, fInfo.Name())); err != nil {
+				return nil, err
+			}
+		} else if !fInfo.IsDir() {
 			f, err := os.Open(filepath.Join(topdir, fInfo.Name()))
 			if err != nil {
 				return nil, err
@@ -39,6 +39,16 @@ const (
 	//TargetTasksFileName is the name of the file where the tasks file from the
 	//  draft pack will be copied to
 	TargetTasksFileName = ".draft-tasks.toml"
+	// DebugFileName is the name of the debug config file in a draft pack
+	DebugFileName = "debug.toml"
+	// TargetDebugFileName is the name of the file where the debug config file from
+	//  the draft pack will be copied to
+	TargetDebugFileName = ".draft-debug.toml"
+	// PlaceholdersFileName is the name of the file declaring the placeholders of the
+	//  Dockerfile and values.yaml of a draft pack
+	PlaceholdersFileName = "placeholders.toml"
+	// DockerfileName is the name of the Dockerfile in a draft pack
+	DockerfileName = "Dockerfile"
 )
 
 // File defines a file inside the pack that will be installed
@@ -53,6 +63,8 @@ type Pack struct {
 	Chart *chart.Chart
 	// Files are the files inside the Pack that will be installed.
 	Files map[string]File
+	// Placeholders are the placeholders of the Dockerfile and values.yaml, by name.
+	Placeholders map[string]Placeholder
 }
 
 // SaveDir saves a pack as files in a directory.
@@ -94,6 +106,12 @@ func (p *Pack) SaveDir(dest string) error {
 
 	delete(p.Files, TasksFileName)
 
+	// the debug config is read by draft debug
+	if f, ok := p.Files[DebugFileName]; ok {
+		p.Files[TargetDebugFileName] = f
+		delete(p.Files, DebugFileName)
+	}
+
 	// save the rest of the files
 	for relPath, f := range p.Files {
 		path := filepath.Join(dest, relPath)
@@ -37,6 +37,7 @@ func TestSaveDir(t *testing.T) {
 		Files: map[string]File{
 			dockerfileName: {ioutil.NopCloser(bytes.NewBufferString(testDockerfile)), dockerPerm},
 			TasksFileName:  {ioutil.NopCloser(bytes.NewBufferString(testTasksFile)), tasksPerm},
+			DebugFileName:  {ioutil.NopCloser(bytes.NewBufferString(`language = "python"`)), tasksPerm},
 		},
 	}
 	dir, err := ioutil.TempDir("", "draft-pack-test")
@@ -87,6 +88,13 @@ func TestSaveDir(t *testing.T) {
 	if string(data) == "" {
 		t.Error("Expected content in .draft-tasks.toml, got empty string")
 	}
+
+	if _, err := os.Stat(filepath.Join(dir, TargetDebugFileName)); err != nil {
+		t.Errorf("Expected %s to have been created: %v", TargetDebugFileName, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, DebugFileName)); !os.IsNotExist(err) {
+		t.Errorf("Expected %s not to be copied as is", DebugFileName)
+	}
 }
 
 func TestSaveDirDockerfileExistsInAppDir(t *testing.T) {
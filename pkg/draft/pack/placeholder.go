@@ -0,0 +1,127 @@
+package pack
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+const (
+	// PortPlaceholder is filled with the port the application listens on.
+	PortPlaceholder = "port"
+	// RuntimeVersionPlaceholder is filled with the version of the language runtime the
+	// application requires, such as the Go version of go.mod or engines.node of package.json.
+	RuntimeVersionPlaceholder = "runtime-version"
+	// StartCommandPlaceholder is filled with the command starting the application.
+	StartCommandPlaceholder = "start-command"
+)
+
+// rePlaceholder matches a placeholder such as [[port]] in the Dockerfile or in values.yaml
+// of a pack. The brackets keep values.yaml valid YAML before it is rendered.
+var rePlaceholder = regexp.MustCompile(`\[\[([a-z0-9-]+)\]\]`)
+
+// Placeholder is a value of the Dockerfile and values.yaml of a pack filled in by draft create,
+// declared in the placeholders file of the pack.
+type Placeholder struct {
+	// Description describes the value for users prompted for it.
+	Description string `toml:"description"`
+	// Default is used when no value was detected for the placeholder.
+	Default string `toml:"default"`
+}
+
+// Placeholders returns the placeholders declared by the pack in dir, without loading the rest
+// of the pack. It returns an empty map if the pack declares none.
+func Placeholders(dir string) (map[string]Placeholder, error) {
+	path := filepath.Join(dir, PlaceholdersFileName)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return map[string]Placeholder{}, nil
+	}
+	return loadPlaceholders(path)
+}
+
+// loadPlaceholders reads the placeholders declared in a placeholders file.
+func loadPlaceholders(path string) (map[string]Placeholder, error) {
+	placeholders := make(map[string]Placeholder)
+	if _, err := toml.DecodeFile(path, &placeholders); err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", PlaceholdersFileName, err)
+	}
+	for name := range placeholders {
+		if !rePlaceholder.MatchString("[[" + name + "]]") {
+			return nil, fmt.Errorf("invalid placeholder name %q in %s: only lower case letters, digits and dashes are allowed", name, PlaceholdersFileName)
+		}
+	}
+	return placeholders, nil
+}
+
+// Render fills the placeholders declared by the pack in its Dockerfile and values.yaml
+// with values, falling back to the default of each placeholder. Undeclared placeholders are
+// left as they are. It fails if a placeholder used has neither a value nor a default.
+func (p *Pack) Render(values map[string]string) error {
+	if len(p.Placeholders) == 0 {
+		return nil
+	}
+	missing := make(map[string]bool)
+	render := func(data []byte) []byte {
+		return rePlaceholder.ReplaceAllFunc(data, func(m []byte) []byte {
+			name := string(m[2 : len(m)-2])
+			placeholder, ok := p.Placeholders[name]
+			if !ok {
+				return m
+			}
+			v := values[name]
+			if v == "" {
+				v = placeholder.Default
+			}
+			if v == "" {
+				missing[name] = true
+				return m
+			}
+			return []byte(v)
+		})
+	}
+
+	if f, ok := p.Files[DockerfileName]; ok {
+		data, err := ioutil.ReadAll(f.file)
+		f.file.Close()
+		if err != nil {
+			return err
+		}
+		p.Files[DockerfileName] = File{ioutil.NopCloser(bytes.NewReader(render(data))), f.perm}
+	}
+
+	var valuesFile *chart.File
+	if p.Chart != nil {
+		for _, f := range p.Chart.Raw {
+			if f.Name == ValuesfileName {
+				f.Data = render(f.Data)
+				valuesFile = f
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("no value for the placeholders %s of the pack", strings.Join(names, ", "))
+	}
+	if valuesFile != nil {
+		vals, err := chartutil.ReadValues(valuesFile.Data)
+		if err != nil {
+			return fmt.Errorf("%s is invalid once rendered: %v", ValuesfileName, err)
+		}
+		p.Chart.Values = vals
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package pack
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     map[string]string
+		dockerfile string
+		port       float64
+	}{
+		{"defaults", nil, "FROM golang:1.14\n", 8080},
+		{"detected", map[string]string{PortPlaceholder: "9000", RuntimeVersionPlaceholder: "1.15"}, "FROM golang:1.15\n", 9000},
+		{"undeclared", map[string]string{"undeclared": "x"}, "FROM golang:1.14\n", 8080},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := FromDir(filepath.Join("testdata", "pack-go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Render(tt.values); err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadAll(p.Files[DockerfileName].file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(data), tt.dockerfile) {
+				t.Errorf("expected Dockerfile to start with %q, got %q", tt.dockerfile, data)
+			}
+			service := p.Chart.Values["service"].(map[string]interface{})
+			if service["internalPort"] != tt.port {
+				t.Errorf("expected internalPort %v, got %v", tt.port, service["internalPort"])
+			}
+		})
+	}
+}
+
+func TestRenderMissingValue(t *testing.T) {
+	p := &Pack{
+		Files: map[string]File{
+			DockerfileName: {ioutil.NopCloser(bytes.NewBufferString("FROM node:[[runtime-version]]\nCMD [[start-command]]\n")), 0644},
+		},
+		Placeholders: map[string]Placeholder{
+			RuntimeVersionPlaceholder: {},
+			StartCommandPlaceholder:   {},
+		},
+	}
+	err := p.Render(map[string]string{RuntimeVersionPlaceholder: "14"})
+	if err == nil || !strings.Contains(err.Error(), "start-command") {
+		t.Errorf("expected an error for the missing start-command, got %v", err)
+	}
+}
+
+func TestLoadPlaceholders(t *testing.T) {
+	p, err := FromDir(filepath.Join("testdata", "pack-go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.Files[PlaceholdersFileName]; ok {
+		t.Errorf("expected %s not to be installed", PlaceholdersFileName)
+	}
+	expected := Placeholder{Description: "the port the application listens on", Default: "8080"}
+	if !reflect.DeepEqual(p.Placeholders[PortPlaceholder], expected) {
+		t.Errorf("expected placeholder %+v, got %+v", expected, p.Placeholders[PortPlaceholder])
+	}
+
+	placeholders, err := Placeholders(filepath.Join("testdata", "pack-go"))
+	if err != nil || !reflect.DeepEqual(placeholders, p.Placeholders) {
+		t.Errorf("expected placeholders %v, got %v and %v", p.Placeholders, placeholders, err)
+	}
+	if placeholders, err := Placeholders(filepath.Join("testdata", "pack-python")); err != nil || len(placeholders) != 0 {
+		t.Errorf("expected no placeholders, got %v and %v", placeholders, err)
+	}
+
+	dir, err := ioutil.TempDir("", "draft-placeholders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, PlaceholdersFileName)
+	if err := ioutil.WriteFile(path, []byte("[Port]\ndefault = \"80\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadPlaceholders(path); err == nil {
+		t.Error("expected an error for an invalid placeholder name")
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"net"
 	"net/http"
 	"strconv"
+	"sync"
 
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/portforward"
@@ -39,6 +40,8 @@ type Tunnel struct {
 	Out       io.Writer
 	stopChan  chan struct{}
 	readyChan chan struct{}
+	doneChan  chan struct{}
+	closeOnce sync.Once
 	config    *rest.Config
 	client    rest.Interface
 }
@@ -53,6 +56,7 @@ func NewTunnel(client rest.Interface, config *rest.Config, namespace, podName st
 		Remote:    remote,
 		stopChan:  make(chan struct{}, 1),
 		readyChan: make(chan struct{}, 1),
+		doneChan:  make(chan struct{}),
 		Out:       ioutil.Discard,
 	}
 }
@@ -68,14 +72,21 @@ func NewWithLocalTunnel(client rest.Interface, config *rest.Config, namespace, p
 		Local:     local,
 		stopChan:  make(chan struct{}, 1),
 		readyChan: make(chan struct{}, 1),
+		doneChan:  make(chan struct{}),
 		Out:       ioutil.Discard,
 	}
 }
 
 // Close disconnects a tunnel connection
 func (t *Tunnel) Close() {
-	close(t.stopChan)
-	close(t.readyChan)
+	// the ready channel is closed by the port forwarder.
+	t.closeOnce.Do(func() { close(t.stopChan) })
+}
+
+// Done returns a channel that is closed once a forwarded tunnel is disconnected, either
+// by Close or because the connection to the pod was lost.
+func (t *Tunnel) Done() <-chan struct{} {
+	return t.doneChan
 }
 
 // ForwardPort opens a tunnel to a kubernetes pod
@@ -107,7 +118,7 @@ func (t *Tunnel) ForwardPort() error {
 		return err
 	}
 
-	errChan := make(chan error)
+	errChan := make(chan error, 1)
 	go func() {
 		errChan <- pf.ForwardPorts()
 	}()
@@ -116,6 +127,10 @@ func (t *Tunnel) ForwardPort() error {
 	case err = <-errChan:
 		return fmt.Errorf("forwarding ports: %v", err)
 	case <-pf.Ready:
+		go func() {
+			<-errChan
+			close(t.doneChan)
+		}()
 		return nil
 	}
 }
@@ -0,0 +1,70 @@
+// Package workspace reads and writes Draft workspace files.
+//
+// A workspace file lists the Draft applications of a repository holding several
+// applications, such as a monorepo, so they can be managed together.
+package workspace
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DefaultFilename is the name of the workspace file at the root of a repository.
+const DefaultFilename = "draft-workspace.toml"
+
+// Workspace represents a draft-workspace.toml
+type Workspace struct {
+	Apps []*App `toml:"apps"`
+}
+
+// App is an application of a workspace.
+type App struct {
+	// Name identifies the application in the workspace.
+	Name string `toml:"name"`
+	// Path is the application directory containing its draft.toml. Relative paths
+	// are relative to the directory of the workspace file.
+	Path string `toml:"path"`
+	// Environment is the draft.toml environment used for the application. If empty,
+	// the environment given on the command line is used.
+	Environment string `toml:"environment,omitempty"`
+}
+
+// Load opens the named workspace file for reading. If successful, the workspace is
+// returned with the application paths resolved relative to the workspace file.
+func Load(name string) (*Workspace, error) {
+	var w Workspace
+	if _, err := toml.DecodeFile(name, &w); err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(name)
+	for _, app := range w.Apps {
+		if app.Name == "" {
+			return nil, fmt.Errorf("%s: application without a name", name)
+		}
+		if app.Path == "" {
+			app.Path = app.Name
+		}
+		if !filepath.IsAbs(app.Path) {
+			app.Path = filepath.Join(dir, app.Path)
+		}
+	}
+	return &w, nil
+}
+
+// Save writes the workspace to the named file.
+func (w *Workspace) Save(name string) error {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(w); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, buf.Bytes(), 0644)
+}
+
+// DraftToml returns the path to the application's draft.toml.
+func (a *App) DraftToml() string {
+	return filepath.Join(a.Path, "draft.toml")
+}
@@ -0,0 +1,49 @@
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	w, err := Load(filepath.Join("testdata", DefaultFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*App{
+		{Name: "api", Path: filepath.Join("testdata", "services", "api")},
+		{Name: "auth", Path: filepath.Join("testdata", "services", "auth"), Environment: "staging"},
+		{Name: "frontend", Path: filepath.Join("testdata", "frontend")},
+	}
+	if !reflect.DeepEqual(w.Apps, expected) {
+		t.Errorf("Expected apps %v, got %v", expected, w.Apps)
+	}
+	if got := w.Apps[0].DraftToml(); got != filepath.Join("testdata", "services", "api", "draft.toml") {
+		t.Errorf("Expected draft.toml in the application path, got %q", got)
+	}
+}
+
+func TestSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "draft-workspace-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := &Workspace{Apps: []*App{{Name: "api", Path: "services/api"}}}
+	name := filepath.Join(dir, DefaultFilename)
+	if err := w.Save(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Apps) != 1 || loaded.Apps[0].Path != filepath.Join(dir, "services", "api") {
+		t.Errorf("Expected the saved workspace to round-trip, got %v", loaded.Apps)
+	}
+}
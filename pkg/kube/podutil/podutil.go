@@ -85,14 +85,19 @@ func GetPod(namespace string, draftLabelKey, name, annotationKey, buildID string
 // ListPods returns pods in the given namespace that match the labels and
 //    annotations given
 func ListPods(namespace string, labels, annotations map[string]string, clientset kubernetes.Interface) ([]v1.Pod, error) {
-	pods := []v1.Pod{}
-
-	labelSet := klabels.Set{}
-	for k, v := range labels {
-		labelSet[k] = v
+	podList, err := ListPodsContext(context.Background(), namespace, labels, annotations, clientset)
+	if err != nil {
+		return nil, err
 	}
+	return podList.Items, nil
+}
 
-	podList, err := clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: labelSet.AsSelector().String()})
+// ListPodsContext is like ListPods, and returns the pod list with its resource version, so
+//    that the pods can be watched from there.
+func ListPodsContext(ctx context.Context, namespace string, labels, annotations map[string]string, clientset kubernetes.Interface) (*v1.PodList, error) {
+	pods := []v1.Pod{}
+
+	podList, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: Selector(labels)})
 	if err != nil {
 		return nil, err
 	}
@@ -114,7 +119,17 @@ func ListPods(namespace string, labels, annotations map[string]string, clientset
 		}
 	}
 
-	return pods, nil
+	podList.Items = pods
+	return podList, nil
+}
+
+// Selector returns the label selector matching the labels given
+func Selector(labels map[string]string) string {
+	labelSet := klabels.Set{}
+	for k, v := range labels {
+		labelSet[k] = v
+	}
+	return labelSet.AsSelector().String()
 }
 
 // ListPodNames returns pod names from given namespace that match labels and
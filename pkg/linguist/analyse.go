@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"log"
 	"math"
+	"sync"
 
 	"github.com/Azure/draft/pkg/linguist/data"
 	"github.com/Azure/draft/pkg/linguist/tokenizer"
@@ -11,7 +12,7 @@ import (
 )
 
 var classifier *bayesian.Classifier
-var classifierInitialized = false
+var classifierOnce sync.Once
 
 // Gets the baysian.Classifier which has been trained on programming language
 // samples from github.com/github/linguist after running the generator
@@ -21,7 +22,7 @@ func getClassifier() *bayesian.Classifier {
 	// NOTE(tso): this could probably go into an init() function instead
 	// but this lazy loading approach works, and it's conceivable that the
 	// analyse() function might not invoked in an actual runtime anyway
-	if !classifierInitialized {
+	classifierOnce.Do(func() {
 		d, err := data.Asset("classifier")
 		if err != nil {
 			log.Panicln(err)
@@ -31,8 +32,7 @@ func getClassifier() *bayesian.Classifier {
 		if err != nil {
 			log.Panicln(err)
 		}
-		classifierInitialized = true
-	}
+	})
 	return classifier
 }
 
@@ -0,0 +1,375 @@
+package linguist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/builder/dockerignore"
+	"github.com/docker/docker/pkg/fileutils"
+	log "github.com/sirupsen/logrus"
+)
+
+// pattern is a pattern of a .gitignore, .draftignore or .gitattributes file.
+type pattern struct {
+	re *regexp.Regexp
+	// base is the directory of the file declaring the pattern, relative to the processed
+	// directory, with slashes, and "" for the processed directory itself.
+	base string
+	// negate re-includes the paths the pattern matches.
+	negate bool
+	// dirOnly only matches directories.
+	dirOnly bool
+}
+
+// compilePattern compiles a pattern with the gitignore semantics: a pattern without a slash
+// matches at any depth below base, otherwise it is relative to base; a trailing slash only
+// matches directories; * and ? match anything but a slash, ** matches any number of
+// directories and [...] matches a character class. It returns nil for blank lines and comments.
+func compilePattern(line, base string) *pattern {
+	line = trimTrailingSpaces(line)
+	if line == "" || line[0] == '#' {
+		return nil
+	}
+	p := &pattern{base: base}
+	if line[0] == '!' {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return nil
+	}
+
+	var re strings.Builder
+	re.WriteString("^")
+	if strings.HasPrefix(line, "/") {
+		line = strings.TrimLeft(line, "/")
+	} else if !strings.Contains(line, "/") {
+		re.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; c {
+		case '*':
+			if strings.HasPrefix(line[i:], "**") {
+				start, end := i == 0 || line[i-1] == '/', i+2 == len(line) || line[i+2] == '/'
+				if start && end {
+					switch {
+					case i+2 == len(line):
+						// a trailing /** matches everything inside.
+						re.WriteString(".*")
+					default:
+						// a leading **/ or /**/ matches zero or more directories.
+						re.WriteString("(?:.*/)?")
+						i++
+					}
+					i++
+					continue
+				}
+			}
+			re.WriteString("[^/]*")
+			for i+1 < len(line) && line[i+1] == '*' {
+				i++
+			}
+		case '?':
+			re.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(line) {
+				i++
+				re.WriteString(regexp.QuoteMeta(line[i : i+1]))
+			}
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+	compiled, err := regexp.Compile(re.String())
+	if err != nil {
+		// an invalid character class never matches, as with git.
+		return nil
+	}
+	p.re = compiled
+	return p
+}
+
+// trimTrailingSpaces removes the trailing spaces of a line, unless they are escaped.
+func trimTrailingSpaces(line string) string {
+	line = strings.TrimRight(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// match reports whether the pattern matches rel, a path relative to the processed directory
+// with slashes.
+func (p *pattern) match(rel string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	if p.base != "" {
+		if !strings.HasPrefix(rel, p.base+"/") {
+			return false
+		}
+		rel = rel[len(p.base)+1:]
+	}
+	return p.re.MatchString(rel)
+}
+
+// ignoreList is the list of the ignore patterns applying in a directory, from the ignore files
+// of the processed directory and of its subdirectories, in order of precedence.
+type ignoreList []*pattern
+
+// ignored reports whether rel, a path relative to the processed directory with slashes, is
+// ignored: the last pattern matching it decides, so patterns of nested files win over their
+// parents'.
+func (l ignoreList) ignored(rel string, isDir bool) bool {
+	for i := len(l) - 1; i >= 0; i-- {
+		if l[i].match(rel, isDir) {
+			return !l[i].negate
+		}
+	}
+	return false
+}
+
+// withFile returns the ignore list extended with the patterns of the named gitignore-style
+// file of the directory base, if the file exists. The receiver is left unchanged.
+func (l ignoreList) withFile(name, base string) (ignoreList, error) {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return l, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	patterns, err := readPatterns(f, base)
+	if err != nil || len(patterns) == 0 {
+		return l, err
+	}
+	list := make(ignoreList, 0, len(l)+len(patterns))
+	return append(append(list, l...), patterns...), nil
+}
+
+// Ignore tells whether paths below a directory are ignored by the .draftignore file of the
+// directory, or by the .gitignore files of the directory and of its subdirectories, with the
+// same rules as ProcessDirContext. The .gitignore files are read as they are needed. It is
+// safe for concurrent use.
+type Ignore struct {
+	root        string
+	draftignore ignoreList
+
+	mu sync.Mutex
+	// gitignore caches the patterns of the .gitignore files applying in a directory, by
+	// path of the directory relative to root.
+	gitignore map[string]ignoreList
+}
+
+// LoadIgnore reads the .draftignore file of dir.
+func LoadIgnore(dir string) (*Ignore, error) {
+	draftignore, err := ignoreList(nil).withFile(filepath.Join(dir, ".draftignore"), "")
+	if err != nil {
+		return nil, fmt.Errorf("error reading .draftignore: %v", err)
+	}
+	return &Ignore{root: dir, draftignore: draftignore, gitignore: make(map[string]ignoreList)}, nil
+}
+
+// Ignored reports whether rel, a path relative to the directory with slashes, is ignored,
+// either itself or as one of its parent directories is. The .git directory is ignored.
+func (i *Ignore) Ignored(rel string, isDir bool) bool {
+	parts := strings.Split(rel, "/")
+	if parts[0] == ".git" {
+		return true
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	dir := ""
+	for n := range parts {
+		p := strings.Join(parts[:n+1], "/")
+		last := n == len(parts)-1
+		gitignore := i.gitignoreIn(dir)
+		if i.draftignore.ignored(p, isDir || !last) || gitignore.ignored(p, isDir || !last) {
+			return true
+		}
+		dir = p
+	}
+	return false
+}
+
+// gitignoreIn returns the patterns of the .gitignore files applying in dir, relative to the
+// root. Unreadable .gitignore files are skipped.
+func (i *Ignore) gitignoreIn(dir string) ignoreList {
+	if l, ok := i.gitignore[dir]; ok {
+		return l
+	}
+	var parent ignoreList
+	if dir != "" {
+		parentDir := ""
+		if slash := strings.LastIndex(dir, "/"); slash >= 0 {
+			parentDir = dir[:slash]
+		}
+		parent = i.gitignoreIn(parentDir)
+	}
+	l, err := parent.withFile(filepath.Join(i.root, filepath.FromSlash(dir), ".gitignore"), dir)
+	if err != nil {
+		log.Debugf("error reading .gitignore of %q: %v", dir, err)
+		l = parent
+	}
+	i.gitignore[dir] = l
+	return l
+}
+
+func readPatterns(r io.Reader, base string) ([]*pattern, error) {
+	var patterns []*pattern
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if p := compilePattern(s.Text(), base); p != nil {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns, s.Err()
+}
+
+// dockerIgnore matches the paths excluded from the Docker build context by a .dockerignore file.
+// It is not safe for concurrent use.
+type dockerIgnore struct {
+	pm *fileutils.PatternMatcher
+}
+
+// loadDockerignore reads the .dockerignore file of dir. It returns nil if there is none.
+func loadDockerignore(dir string) (*dockerIgnore, error) {
+	f, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	patterns, err := dockerignore.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	pm, err := fileutils.NewPatternMatcher(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return &dockerIgnore{pm}, nil
+}
+
+// ignored reports whether path, relative to the processed directory with slashes, is excluded.
+// An excluded directory is only skipped if no pattern re-includes paths, as the contents of an
+// excluded directory can be re-included.
+func (d *dockerIgnore) ignored(p string, isDir bool) (ignored, skipDir bool) {
+	if d == nil {
+		return false, false
+	}
+	m, err := d.pm.Matches(filepath.FromSlash(path.Clean(p)))
+	if err != nil || !m {
+		return false, false
+	}
+	return true, isDir && !d.pm.Exclusions()
+}
+
+// attributes are the linguist attributes set in the .gitattributes file of the processed
+// directory. They are safe for concurrent use.
+type attributes struct {
+	// ignored lists the paths marked as vendored, generated or documentation, with the
+	// paths marked as not being so as negated patterns.
+	ignored   ignoreList
+	languages []languageAttribute
+}
+
+// languageAttribute is a linguist-language attribute.
+type languageAttribute struct {
+	pattern  *pattern
+	language string
+}
+
+// loadAttributes reads the .gitattributes file of dir, if there is one.
+func loadAttributes(dir string) (*attributes, error) {
+	attrs := &attributes{}
+	f, err := os.Open(filepath.Join(dir, ".gitattributes"))
+	if os.IsNotExist(err) {
+		return attrs, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	var lineNumber int
+	for s.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(s.Text())
+		words := strings.Fields(line)
+		if len(words) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if len(words) < 2 {
+			log.Printf("invalid line in .gitattributes at L%d: '%s'\n", lineNumber, line)
+			continue
+		}
+		for _, attribute := range words[1:] {
+			name, value := attribute, "true"
+			if i := strings.IndexByte(attribute, '='); i >= 0 {
+				name, value = attribute[:i], attribute[i+1:]
+			}
+			if strings.HasPrefix(name, "-") {
+				name, value = name[1:], "false"
+			}
+			switch name {
+			case "linguist-documentation", "linguist-vendored", "linguist-generated":
+				p := compilePattern(words[0], "")
+				if p == nil {
+					continue
+				}
+				p.negate = strings.ToLower(value) == "false"
+				attrs.ignored = append(attrs.ignored, p)
+			case "linguist-language":
+				if value == "" || value == "true" {
+					log.Printf("invalid line in .gitattributes at L%d: '%s'\n", lineNumber, line)
+					continue
+				}
+				if p := compilePattern(words[0], ""); p != nil {
+					attrs.languages = append(attrs.languages, languageAttribute{p, value})
+				}
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("error reading .gitattributes: %v", err)
+	}
+	return attrs, nil
+}
+
+// language returns the language set for rel with linguist-language, or "" if there is none.
+func (a *attributes) language(rel string) string {
+	for i := len(a.languages) - 1; i >= 0; i-- {
+		if a.languages[i].pattern.match(rel, false) {
+			return a.languages[i].language
+		}
+	}
+	return ""
+}
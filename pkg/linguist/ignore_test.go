@@ -0,0 +1,215 @@
+package linguist
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompilePattern(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		base    string
+		path    string
+		isDir   bool
+		match   bool
+	}{
+		{"*.log", "", "debug.log", false, true},
+		{"*.log", "", "logs/debug.log", false, true},
+		{"*.log", "", "debug.logs", false, false},
+		{"/debug.log", "", "logs/debug.log", false, false},
+		{"/debug.log", "", "debug.log", false, true},
+		{"logs/debug.log", "", "src/logs/debug.log", false, false},
+		{"build/", "", "build", true, true},
+		{"build/", "", "build", false, false},
+		{"build/", "", "src/build", true, true},
+		{"**/logs", "", "a/b/logs", true, true},
+		{"**/logs", "", "logs", true, true},
+		{"logs/**", "", "logs/a/debug.log", false, true},
+		{"a/**/b", "", "a/b", false, true},
+		{"a/**/b", "", "a/x/y/b", false, true},
+		{"a/*/b", "", "a/x/y/b", false, false},
+		{"debug?.log", "", "debug1.log", false, true},
+		{"debug?.log", "", "debug/.log", false, false},
+		{"debug[0-9].log", "", "debug1.log", false, true},
+		{"debug[!0-9].log", "", "debug1.log", false, false},
+		{`\#notes`, "", "#notes", false, true},
+		{"*.log", "lib", "lib/debug.log", false, true},
+		{"*.log", "lib", "debug.log", false, false},
+		{"/generated.py", "lib", "lib/generated.py", false, true},
+		{"/generated.py", "lib", "lib/sub/generated.py", false, false},
+	}
+	for _, tc := range testCases {
+		p := compilePattern(tc.pattern, tc.base)
+		if p == nil {
+			t.Errorf("expected %q to compile", tc.pattern)
+			continue
+		}
+		if m := p.match(tc.path, tc.isDir); m != tc.match {
+			t.Errorf("expected %q in %q matching %q (dir: %v) to be %v, got %v", tc.pattern, tc.base, tc.path, tc.isDir, tc.match, m)
+		}
+	}
+
+	for _, line := range []string{"", "   ", "# comment", "/"} {
+		if p := compilePattern(line, ""); p != nil {
+			t.Errorf("expected %q not to be a pattern", line)
+		}
+	}
+}
+
+func TestIgnoreList(t *testing.T) {
+	l := ignoreList{compilePattern("*.log", ""), compilePattern("!keep.log", "lib")}
+	for path, expected := range map[string]bool{
+		"debug.log":     true,
+		"lib/debug.log": true,
+		"lib/keep.log":  false,
+		"keep.log":      true,
+		"app.py":        false,
+	} {
+		if l.ignored(path, false) != expected {
+			t.Errorf("expected %s to be ignored: %v", path, expected)
+		}
+	}
+}
+
+func TestProcessDirIgnoreFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "draft-linguist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	python := "import os\n\nprint(os.getcwd())\n"
+	for name, contents := range map[string]string{
+		".gitignore":       "*.log\nbuild/\n",
+		".draftignore":     "docs/\n",
+		".dockerignore":    "tmp\n!tmp/keep.py\n",
+		"app.py":           python,
+		"debug.log":        "debug",
+		"generated.py":     python,
+		"build/out.py":     python,
+		"docs/index.html":  "<html></html>",
+		"lib/.gitignore":   "!keep.log\n/generated.py\n",
+		"lib/keep.log":     "keep",
+		"lib/generated.py": python,
+		"lib/lib.py":       python,
+		"tmp/scratch.py":   python,
+		"tmp/keep.py":      python,
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, files, err := ProcessDirContext(context.Background(), dir, Options{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reasons := make(map[string]string)
+	for i, f := range files {
+		reasons[f.Path] = f.Reason
+		if i > 0 && files[i-1].Path >= f.Path {
+			t.Errorf("expected the files to be sorted by path, got %s before %s", files[i-1].Path, f.Path)
+		}
+	}
+	expected := map[string]string{
+		"app.py":           ReasonFilename,
+		"debug.log":        ReasonIgnored,
+		"generated.py":     ReasonFilename,
+		"build":            ReasonIgnored,
+		"docs":             ReasonIgnored,
+		"lib/generated.py": ReasonIgnored,
+		"lib/lib.py":       ReasonFilename,
+		"tmp/scratch.py":   ReasonIgnored,
+		"tmp/keep.py":      ReasonFilename,
+	}
+	for path, reason := range expected {
+		if reasons[path] != reason {
+			t.Errorf("expected %s to be decided by %s, got %q", path, reason, reasons[path])
+		}
+	}
+	if reason := reasons["lib/keep.log"]; reason == "" || reason == ReasonIgnored {
+		t.Errorf("expected lib/keep.log to be re-included, got %q", reason)
+	}
+	for path := range reasons {
+		if strings.HasPrefix(path, "build/") || strings.HasPrefix(path, "docs/") {
+			t.Errorf("expected the files of ignored directories to be skipped, got %s", path)
+		}
+	}
+}
+
+func TestProcessDirContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := ProcessDirContext(ctx, appPythonPath, Options{}); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestReadHead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "draft-linguist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(path, []byte(strings.Repeat("a", 2000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for max, expected := range map[int]int{100: 100, 2000: 2000, 4096: 2000} {
+		contents, err := readHead(path, max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(contents) != expected {
+			t.Errorf("expected %d bytes reading up to %d, got %d", expected, max, len(contents))
+		}
+	}
+}
+
+func TestIgnore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "draft-linguist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, contents := range map[string]string{
+		".gitignore":     "*.log\nbuild/\n",
+		".draftignore":   "docs/\n",
+		"lib/.gitignore": "!keep.log\n/generated.py\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ignore, err := LoadIgnore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rel, expected := range map[string]bool{
+		"app.py":           false,
+		"debug.log":        true,
+		"build/out.py":     true,
+		"docs/index.html":  true,
+		".git/HEAD":        true,
+		"lib/generated.py": true,
+		"lib/lib.py":       false,
+		"lib/keep.log":     false,
+		"generated.py":     false,
+	} {
+		if ignored := ignore.Ignored(rel, false); ignored != expected {
+			t.Errorf("expected %s to be ignored: %v, got %v", rel, expected, ignored)
+		}
+	}
+}
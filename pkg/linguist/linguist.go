@@ -1,24 +1,21 @@
 package linguist
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/Azure/draft/pkg/osutil"
 	log "github.com/sirupsen/logrus"
 )
 
-var (
-	isIgnored                 func(string) bool
-	isDetectedInGitAttributes func(filename string) string
-)
-
 // used for displaying results
 type (
 	// Language is the programming langage and the percentage on how sure linguist feels about its
@@ -26,9 +23,44 @@ type (
 	Language struct {
 		Language string  `json:"language"`
 		Percent  float64 `json:"percent"`
+		// Bytes is the size of the files of the language.
+		Bytes int64 `json:"bytes"`
 		// Color represents the color associated with the language in HTML hex notation.
 		Color string `json:"color"`
 	}
+
+	// File is the decision linguist made for a file, or for a directory it skipped.
+	File struct {
+		// Path is the path of the file, relative to the processed directory.
+		Path string `json:"path"`
+		// Language is the language of the file, empty if the file was skipped.
+		Language string `json:"language,omitempty"`
+		// Bytes is the size of the file.
+		Bytes int64 `json:"bytes"`
+		// Reason tells how the language was decided, or why the file was skipped.
+		Reason string `json:"reason"`
+	}
+)
+
+// The reasons of the decisions linguist makes for files.
+const (
+	// ReasonGitAttributes means the language was set with linguist-language in .gitattributes.
+	ReasonGitAttributes = "gitattributes"
+	// ReasonFilename means the language was decided from the name or extension of the file.
+	ReasonFilename = "filename"
+	// ReasonInterpreter means the language was decided from the interpreter of a shebang line.
+	ReasonInterpreter = "interpreter"
+	// ReasonClassifier means the language was decided by the classifier, from the contents.
+	ReasonClassifier = "classifier"
+	// ReasonUnknown means no language could be decided.
+	ReasonUnknown = "unknown"
+	// ReasonIgnored means the file was skipped as it is ignored by .gitignore, .draftignore or
+	// .dockerignore, or marked as vendored, generated or documentation in .gitattributes.
+	ReasonIgnored = "ignored"
+	// ReasonVendored means the file was skipped as it is vendored or documentation.
+	ReasonVendored = "vendored"
+	// ReasonBinary means the file was skipped as its contents are binary.
+	ReasonBinary = "binary"
 )
 
 // sortableResult is a list or programming languages, sorted based on the likelihood of the
@@ -47,159 +79,145 @@ func (s sortableResult) Swap(i, j int) {
 	s[i], s[j] = s[j], s[i]
 }
 
-func initLinguistAttributes(dir string) error {
-	ignore := []string{}
-	except := []string{}
-	detected := make(map[string]string)
+// DefaultMaxBytes is the number of bytes read from a file to classify its contents, unless
+// Options sets another one. It is large enough for the classifier to see more than the
+// license header of a source file.
+const DefaultMaxBytes = 64 << 10
+
+// Options configure how a directory is processed.
+type Options struct {
+	// Workers is the number of files read and classified at the same time. It defaults to the
+	// number of CPUs.
+	Workers int
+	// MaxBytes is the number of bytes read from a file to classify its contents. It defaults to
+	// DefaultMaxBytes.
+	MaxBytes int
+}
+
+// ProcessDir walks through a directory and returns a list of sorted languages within that directory.
+func ProcessDir(dirname string) ([]*Language, error) {
+	langs, _, err := ProcessDirWithFiles(dirname)
+	return langs, err
+}
+
+// ProcessDirWithFiles is like ProcessDir, and also returns the decision made for every file,
+// sorted by path. Empty files and the .git directory are left out.
+func ProcessDirWithFiles(dirname string) ([]*Language, []*File, error) {
+	return ProcessDirContext(context.Background(), dirname, Options{})
+}
 
-	gitignoreExists, err := osutil.Exists(filepath.Join(dir, ".gitignore"))
+// ProcessDirContext is like ProcessDirWithFiles, reading and classifying files concurrently.
+// Files and directories are skipped if the .gitignore files of the directory and of its
+// subdirectories, or its .draftignore or .dockerignore files ignore them. It stops and returns
+// the error of the context when the context is done.
+func ProcessDirContext(ctx context.Context, dirname string, opts Options) ([]*Language, []*File, error) {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	w, err := newWalker(dirname)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	if gitignoreExists {
-		log.Debugln("found .gitignore")
-
-		f, err := os.Open(filepath.Join(dir, ".gitignore"))
-		if err != nil {
-			return err
-		}
-		defer f.Close()
-
-		ignoreScanner := bufio.NewScanner(f)
-		for ignoreScanner.Scan() {
-			var isExcept bool
-			path := strings.TrimSpace(ignoreScanner.Text())
-			// if it's whitespace or a comment
-			if len(path) == 0 || string(path[0]) == "#" {
-				continue
-			}
-			if string(path[0]) == "!" {
-				isExcept = true
-				path = path[1:]
-			}
-			p := strings.Trim(path, string(filepath.Separator))
-			if isExcept {
-				except = append(except, p)
-			} else {
-				ignore = append(ignore, p)
-			}
-		}
-		if err := ignoreScanner.Err(); err != nil {
-			return fmt.Errorf("error reading .gitignore: %v", err)
+	w.maxBytes, w.jobs = opts.MaxBytes, make(chan *File)
+	w.visit = func(f *File, _ os.FileInfo) error {
+		w.decide(f)
+		select {
+		case w.jobs <- f:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 
-	gitAttributesExists, err := osutil.Exists(filepath.Join(dir, ".gitattributes"))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range w.jobs {
+				w.classify(f)
+			}
+		}()
+	}
+	err = w.walk(ctx, dirname, "", nil)
+	close(w.jobs)
+	wg.Wait()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	if gitAttributesExists {
-		log.Debugln("found .gitattributes")
 
-		f, err := os.Open(filepath.Join(dir, ".gitattributes"))
-		if err != nil {
-			return err
-		}
-		defer f.Close()
-
-		attributeScanner := bufio.NewScanner(f)
-		var lineNumber int
-		for attributeScanner.Scan() {
-			lineNumber++
-			line := strings.TrimSpace(attributeScanner.Text())
-			words := strings.Fields(line)
-			if len(words) != 2 {
-				log.Printf("invalid line in .gitattributes at L%d: '%s'\n", lineNumber, line)
-				continue
-			}
-			path := strings.Trim(words[0], string(filepath.Separator))
-			if runtime.GOOS == "windows" {
-				// on Windows, we also accept / as a path separator, so let's strip those as well
-				path = strings.Trim(words[0], "/")
-			}
-			attribute := words[1]
-			if strings.HasPrefix(attribute, "linguist-documentation") || strings.HasPrefix(attribute, "linguist-vendored") || strings.HasPrefix(attribute, "linguist-generated") {
-				if !strings.HasSuffix(strings.ToLower(attribute), "false") {
-					ignore = append(ignore, path)
-				}
-			} else if strings.HasPrefix(attribute, "linguist-language") {
-				attr := strings.Split(attribute, "=")
-				if len(attr) != 2 {
-					log.Printf("invalid line in .gitattributes at L%d: '%s'\n", lineNumber, line)
-					continue
-				}
-				language := attr[1]
-				detected[path] = language
-			}
+	var (
+		langs     = make(map[string]int64)
+		files     = make([]*File, 0, len(w.files))
+		totalSize int64
+	)
+	for _, f := range w.files {
+		if f.Reason == "" {
+			// the file could not be read.
+			continue
 		}
-		if err := attributeScanner.Err(); err != nil {
-			return fmt.Errorf("error reading .gitattributes: %v", err)
+		files = append(files, f)
+		if f.Language != "" {
+			langs[f.Language] += f.Bytes
+			totalSize += f.Bytes
 		}
 	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
 
-	isIgnored = func(filename string) bool {
-		for _, p := range ignore {
-			cleanPath, err := filepath.Rel(dir, filename)
-			if err != nil {
-				log.Debugf("could not get relative path: %v", err)
-				return false
-			}
-			if m, _ := filepath.Match(p, cleanPath); m {
-				for _, e := range except {
-					if m, _ := filepath.Match(e, cleanPath); m {
-						return false
-					}
-				}
-				return true
-			}
-		}
-		return false
-	}
-	isDetectedInGitAttributes = func(filename string) string {
-		for p, lang := range detected {
-			cleanPath, err := filepath.Rel(dir, filename)
-			if err != nil {
-				log.Debugf("could not get relative path: %v", err)
-				return ""
-			}
-			if m, _ := filepath.Match(p, cleanPath); m {
-				return lang
-			}
+	results := []*Language{}
+	for lang, size := range langs {
+		l := &Language{
+			Language: lang,
+			Percent:  (float64(size) / float64(totalSize)) * 100.0,
+			Bytes:    size,
+			Color:    LanguageColor(lang),
 		}
-		return ""
+		results = append(results, l)
+		log.Debugf("language: %s percent: %f color: %s", l.Language, l.Percent, l.Color)
 	}
-	return nil
+	sort.Sort(sort.Reverse(sortableResult(results)))
+	return results, files, nil
 }
 
-// shoutouts to php
-func fileGetContents(filename string) ([]byte, error) {
-	log.Debugln("reading contents of", filename)
-
-	// read only first 512 bytes of files
-	contents := make([]byte, 512)
-	f, err := os.Open(filename)
+// Walk walks the files of a directory in lexical order, calling fn with the path of every
+// file relative to the directory, with slashes. It skips the files ProcessDirContext skips
+// before reading them: empty files, symbolic links, vendored files and the files ignored
+// by the ignore files of the directory. It stops and returns the error of fn, if any, or
+// of the context when the context is done.
+func Walk(ctx context.Context, dirname string, fn func(rel string, info os.FileInfo) error) error {
+	w, err := newWalker(dirname)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	_, err = f.Read(contents)
-	f.Close()
-	if err != io.EOF {
-		if err != nil {
-			return nil, err
-		}
+	w.visit = func(f *File, info os.FileInfo) error {
+		return fn(f.Path, info)
 	}
-	return contents, nil
+	return w.walk(ctx, dirname, "", nil)
 }
 
-// ProcessDir walks through a directory and returns a list of sorted languages within that directory.
-func ProcessDir(dirname string) ([]*Language, error) {
-	var (
-		langs     = make(map[string]int)
-		totalSize int
-	)
-	if err := initLinguistAttributes(dirname); err != nil {
-		return nil, err
-	}
+// walker walks a directory in a single goroutine, and hands the files to visit, which
+// ProcessDirContext hands to workers to classify.
+type walker struct {
+	root         string
+	maxBytes     int
+	attrs        *attributes
+	draftignore  ignoreList
+	dockerignore *dockerIgnore
+	jobs         chan *File
+	visit        func(f *File, info os.FileInfo) error
+
+	mu sync.Mutex
+	// files are the decisions made, including the ones workers have yet to fill in.
+	files []*File
+}
+
+// newWalker returns a walker of dirname, with the ignore files of the directory loaded.
+func newWalker(dirname string) (*walker, error) {
 	exists, err := osutil.Exists(dirname)
 	if err != nil {
 		return nil, err
@@ -207,87 +225,173 @@ func ProcessDir(dirname string) ([]*Language, error) {
 	if !exists {
 		return nil, os.ErrNotExist
 	}
-	filepath.Walk(dirname, func(path string, file os.FileInfo, err error) error {
-		size := int(file.Size())
-		log.Debugf("with file: %s", path)
-		log.Debugln(path, "is", size, "bytes")
-		if isIgnored(path) {
+	w := &walker{root: dirname}
+	if w.attrs, err = loadAttributes(dirname); err != nil {
+		return nil, err
+	}
+	if w.draftignore, err = ignoreList(nil).withFile(filepath.Join(dirname, ".draftignore"), ""); err != nil {
+		return nil, fmt.Errorf("error reading .draftignore: %v", err)
+	}
+	if w.dockerignore, err = loadDockerignore(dirname); err != nil {
+		return nil, fmt.Errorf("error reading .dockerignore: %v", err)
+	}
+	return w, nil
+}
+
+// walk walks dir, whose path relative to the root is rel, with the patterns of the .gitignore
+// files of its parents.
+func (w *walker) walk(ctx context.Context, dir, rel string, gitignore ignoreList) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	gitignore, err := gitignore.withFile(filepath.Join(dir, ".gitignore"), rel)
+	if err != nil {
+		return fmt.Errorf("error reading .gitignore: %v", err)
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		entryRel := entry.Name()
+		if rel != "" {
+			entryRel = rel + "/" + entry.Name()
+		}
+		isDir := entry.IsDir()
+		log.Debugln(path, "is", entry.Size(), "bytes")
+
+		if isDir && entry.Name() == ".git" {
+			log.Debugln(".git directory, skipping")
+			continue
+		}
+		if ignored, skipDir := w.ignored(gitignore, entryRel, isDir); ignored {
 			log.Debugln(path, "is ignored, skipping")
-			if file.IsDir() {
-				return filepath.SkipDir
+			if !isDir || skipDir {
+				w.decide(&File{Path: entryRel, Reason: ReasonIgnored, Bytes: fileSize(entry)})
+				continue
 			}
-			return nil
 		}
-		if size == 0 {
+		if isDir {
+			if err := w.walk(ctx, path, entryRel, gitignore); err != nil {
+				return err
+			}
+			continue
+		}
+		if entry.Size() == 0 {
 			log.Debugln(path, "is empty file, skipping")
-			return nil
+			continue
 		}
-		if file.IsDir() {
-			if file.Name() == ".git" {
-				log.Debugln(".git directory, skipping")
-				return filepath.SkipDir
-			}
-		} else if (file.Mode() & os.ModeSymlink) == 0 {
-			if ShouldIgnoreFilename(path) {
-				log.Debugf("%s: filename should be ignored, skipping", path)
-				return nil
-			}
+		if entry.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		f := &File{Path: entryRel, Bytes: entry.Size()}
+		if ShouldIgnoreFilename(entryRel) {
+			log.Debugf("%s: filename should be ignored, skipping", path)
+			f.Reason = ReasonVendored
+			w.decide(f)
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := w.visit(f, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-			byGitAttr := isDetectedInGitAttributes(path)
-			if byGitAttr != "" {
-				log.Debugln(path, "got result by .gitattributes: ", byGitAttr)
-				langs[byGitAttr] += size
-				totalSize += size
-				return nil
-			}
+// ignored reports whether rel is ignored, and whether a directory can be skipped as a whole.
+// A directory excluded by .dockerignore is walked when .dockerignore re-includes paths.
+func (w *walker) ignored(gitignore ignoreList, rel string, isDir bool) (ignored, skipDir bool) {
+	if w.attrs.ignored.ignored(rel, isDir) || w.draftignore.ignored(rel, isDir) || gitignore.ignored(rel, isDir) {
+		return true, true
+	}
+	return w.dockerignore.ignored(rel, isDir)
+}
 
-			if byName := LanguageByFilename(path); byName != "" {
-				log.Debugln(path, "got result by name: ", byName)
-				langs[byName] += size
-				totalSize += size
-				return nil
-			}
+func (w *walker) decide(f *File) {
+	w.mu.Lock()
+	w.files = append(w.files, f)
+	w.mu.Unlock()
+}
 
-			contents, err := fileGetContents(path)
-			if err != nil {
-				return err
-			}
+// classify decides the language of a file. It leaves the reason empty if the file could not
+// be read.
+func (w *walker) classify(f *File) {
+	path := filepath.Join(w.root, filepath.FromSlash(f.Path))
+	if byGitAttr := w.attrs.language(f.Path); byGitAttr != "" {
+		log.Debugln(path, "got result by .gitattributes: ", byGitAttr)
+		f.Language, f.Reason = byGitAttr, ReasonGitAttributes
+		return
+	}
 
-			if ShouldIgnoreContents(contents) {
-				log.Debugln(path, ": contents should be ignored, skipping")
-				return nil
-			}
+	if byName := LanguageByFilename(f.Path); byName != "" {
+		log.Debugln(path, "got result by name: ", byName)
+		f.Language, f.Reason = byName, ReasonFilename
+		return
+	}
 
-			hints := LanguageHints(path)
-			log.Debugf("%s got language hints: %#v\n", path, hints)
-			byData := LanguageByContents(contents, hints)
+	contents, err := readHead(path, w.maxBytes)
+	if err != nil {
+		log.Debugf("could not read %s: %v", path, err)
+		return
+	}
 
-			if byData != "" {
-				log.Debugln(path, "got result by data: ", byData)
-				langs[byData] += size
-				totalSize += size
-				return nil
-			}
+	if ShouldIgnoreContents(contents) {
+		log.Debugln(path, ": contents should be ignored, skipping")
+		f.Reason = ReasonBinary
+		return
+	}
 
-			log.Debugln(path, "got no result!!")
-			langs["(unknown)"] += size
-			totalSize += size
+	if interpreter := detectInterpreter(contents); interpreter != "" {
+		if l := interpreters[interpreter]; len(l) == 1 {
+			log.Debugln(path, "got result by interpreter: ", l[0])
+			f.Language, f.Reason = l[0], ReasonInterpreter
+			return
 		}
-		return nil
-	})
+	}
 
-	results := []*Language{}
-	for lang, size := range langs {
-		l := &Language{
-			Language: lang,
-			Percent:  (float64(size) / float64(totalSize)) * 100.0,
-			Color:    LanguageColor(lang),
-		}
-		results = append(results, l)
-		log.Debugf("language: %s percent: %f color: %s", l.Language, l.Percent, l.Color)
+	hints := LanguageHints(f.Path)
+	log.Debugf("%s got language hints: %#v\n", path, hints)
+	if byData := Analyse(contents, hints); byData != "" {
+		log.Debugln(path, "got result by data: ", byData)
+		f.Language, f.Reason = byData, ReasonClassifier
+		return
 	}
-	sort.Sort(sort.Reverse(sortableResult(results)))
-	return results, nil
+
+	log.Debugln(path, "got no result!!")
+	f.Language, f.Reason = "(unknown)", ReasonUnknown
+}
+
+// readHead reads up to max bytes from the start of a file.
+func readHead(filename string, max int) ([]byte, error) {
+	log.Debugln("reading contents of", filename)
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// most files are smaller than max, so the buffer is only as large as the file.
+	if info, err := f.Stat(); err == nil && info.Size() < int64(max) {
+		max = int(info.Size())
+	}
+	contents := make([]byte, max)
+	n, err := io.ReadFull(f, contents)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return contents[:n], nil
+}
+
+// fileSize returns the size of a file, and 0 for directories.
+func fileSize(info os.FileInfo) int64 {
+	if info.IsDir() {
+		return 0
+	}
+	return info.Size()
 }
 
 // Alias returns the language name for a given known alias.
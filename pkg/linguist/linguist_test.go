@@ -2,6 +2,7 @@ package linguist
 
 import (
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -31,6 +32,44 @@ func TestProcessDir(t *testing.T) {
 	}
 }
 
+func TestProcessDirWithFiles(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected map[string]string
+	}{
+		{filepath.Join("testdata", "app-duck"), map[string]string{"main.duck": ReasonGitAttributes, ".gitattributes": ReasonVendored}},
+		{filepath.Join("testdata", "app-vendored"), map[string]string{"vendor.html": ReasonIgnored, "app.py": ReasonFilename}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			langs, files, err := ProcessDirWithFiles(tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reasons := make(map[string]string)
+			var size int64
+			for _, f := range files {
+				reasons[f.Path] = f.Reason
+				if f.Language != "" {
+					size += f.Bytes
+				}
+			}
+			for path, reason := range tc.expected {
+				if reasons[path] != reason {
+					t.Errorf("expected %s to be decided by %s, got %q", path, reason, reasons[path])
+				}
+			}
+			var total int64
+			for _, l := range langs {
+				total += l.Bytes
+			}
+			if total != size {
+				t.Errorf("expected the languages to total %d bytes, got %d", size, total)
+			}
+		})
+	}
+}
+
 func TestGitAttributes(t *testing.T) {
 	testCases := []struct {
 		path         string
@@ -59,11 +98,21 @@ func TestGitAttributes(t *testing.T) {
 //TestDirectoryIsIgnored checks to see if directory paths such as 'docs/' are ignored from being classified by linguist when added to the "ignore" list.
 func TestDirectoryIsIgnored(t *testing.T) {
 	path := filepath.Join("testdata", "app-documentation")
-	// populate isIgnored
-	ProcessDir(path)
-	ignorePath := filepath.Join(path, "docs")
-	if !isIgnored(ignorePath) {
-		t.Errorf("expected dir '%s' to be ignored", ignorePath)
+	_, files, err := ProcessDirWithFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ignored bool
+	for _, f := range files {
+		if f.Path == "docs" && f.Reason == ReasonIgnored {
+			ignored = true
+		}
+		if strings.HasPrefix(f.Path, "docs/") {
+			t.Errorf("expected the files of the ignored dir 'docs' to be skipped, got %s", f.Path)
+		}
+	}
+	if !ignored {
+		t.Errorf("expected dir '%s' to be ignored", filepath.Join(path, "docs"))
 	}
 }
 
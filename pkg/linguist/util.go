@@ -86,7 +86,7 @@ func LanguageColor(language string) string {
 //
 // Returns the empty string in ambiguous or unrecognized cases.
 func LanguageByFilename(filename string) string {
-	if l := filenames[filename]; len(l) == 1 {
+	if l := filenames[filepath.Base(filename)]; len(l) == 1 {
 		return l[0]
 	}
 	ext := filepath.Ext(filename)
@@ -106,7 +106,7 @@ func LanguageByFilename(filename string) string {
 //
 // May return an empty slice.
 func LanguageHints(filename string) (hints []string) {
-	if l, ok := filenames[filename]; ok {
+	if l, ok := filenames[filepath.Base(filename)]; ok {
 		hints = append(hints, l...)
 	}
 	if ext := filepath.Ext(filename); ext != "" {
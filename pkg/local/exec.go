@@ -0,0 +1,80 @@
+package local
+
+import (
+	"io"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/kubectl/pkg/util/term"
+
+	"github.com/Azure/draft/pkg/kube/podutil"
+)
+
+// ExecOptions configures a command executed by App.Exec.
+type ExecOptions struct {
+	// Container is the container to run the command in. Defaults to the first container of the pod.
+	Container string
+	// Command is the command and its arguments.
+	Command []string
+	// Stdin is passed to the command if not nil.
+	Stdin io.Reader
+	// Stdout and Stderr receive the output of the command. With TTY set, the
+	// output of the terminal is written to Stdout.
+	Stdout io.Writer
+	Stderr io.Writer
+	// TTY allocates a terminal for the command. Stdin is put into raw mode and
+	// terminal resizes are forwarded to the pod.
+	TTY bool
+}
+
+// Pod waits for a ready pod running the build given by buildID and returns it. Pods are
+// selected the same way as for Connect.
+func (a *App) Pod(clientset kubernetes.Interface, buildID string) (*v1.Pod, error) {
+	return podutil.GetPod(a.Namespace, DraftLabelKey, a.Name, BuildIDKey, buildID, clientset)
+}
+
+// Exec executes a command in a container of the application pod given by podName.
+//
+// If the command exits with a non-zero status, the returned error implements
+// k8s.io/client-go/util/exec.ExitError.
+func (a *App) Exec(clientset kubernetes.Interface, clientConfig *restclient.Config, podName string, opts ExecOptions) error {
+	t := term.TTY{In: opts.Stdin, Out: opts.Stdout, Raw: opts.TTY}
+	stderr := opts.Stderr
+	var sizeQueue remotecommand.TerminalSizeQueue
+	if opts.TTY {
+		// the terminal multiplexes stdout and stderr.
+		stderr = nil
+		sizeQueue = t.MonitorSize(t.GetSize())
+	}
+
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(a.Namespace).
+		SubResource("exec").
+		VersionedParams(&v1.PodExecOptions{
+			Container: opts.Container,
+			Command:   opts.Command,
+			Stdin:     opts.Stdin != nil,
+			Stdout:    opts.Stdout != nil,
+			Stderr:    stderr != nil,
+			TTY:       opts.TTY,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(clientConfig, "POST", req.URL())
+	if err != nil {
+		return err
+	}
+	return t.Safe(func() error {
+		return executor.Stream(remotecommand.StreamOptions{
+			Stdin:             opts.Stdin,
+			Stdout:            opts.Stdout,
+			Stderr:            stderr,
+			Tty:               opts.TTY,
+			TerminalSizeQueue: sizeQueue,
+		})
+	})
+}
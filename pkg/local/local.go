@@ -34,11 +34,15 @@ const (
 //  Container is the name the name of the application container to connect to
 //  OverridePorts contains mappings of which local port to map a remote port to
 //    and will be in the form local_port:remote_port i.e. 8080:8081
+//  KubeContexts are the kubeconfig contexts the environment is bound to
+//  StorageNamespace is the Kubernetes namespace build records are stored in
 type App struct {
-	Name          string
-	Namespace     string
-	Container     string
-	OverridePorts []string
+	Name             string
+	Namespace        string
+	Container        string
+	OverridePorts    []string
+	KubeContexts     []string
+	StorageNamespace string
 }
 
 // Connection encapsulated information to connect to an application
@@ -69,9 +73,11 @@ func DeployedApplication(draftTomlPath, draftEnvironment string) (*App, error) {
 	}
 
 	return &App{
-		Name:          appConfig.Name,
-		Namespace:     appConfig.Namespace,
-		OverridePorts: appConfig.OverridePorts}, nil
+		Name:             appConfig.Name,
+		Namespace:        appConfig.Namespace,
+		OverridePorts:    appConfig.OverridePorts,
+		KubeContexts:     appConfig.Contexts(),
+		StorageNamespace: appConfig.StorageNamespace}, nil
 }
 
 // Connect tunnels to a Kubernetes pod running the application and returns the connection information
@@ -0,0 +1,238 @@
+package local
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/Azure/draft/pkg/kube/podutil"
+)
+
+// LogOptions configures which runtime logs App.StreamLogs streams.
+type LogOptions struct {
+	// Container limits the logs to the container with this name.
+	Container string
+	// Since only returns logs newer than this duration.
+	Since time.Duration
+	// Grep only returns log lines matching this expression.
+	Grep *regexp.Regexp
+	// Previous returns the logs of the previous instance of each container.
+	Previous bool
+	// Follow keeps streaming logs, including those of pods created after a rollout.
+	Follow bool
+}
+
+var logColors = []color.Attribute{
+	color.FgCyan,
+	color.FgGreen,
+	color.FgMagenta,
+	color.FgYellow,
+	color.FgBlue,
+	color.FgRed,
+}
+
+// StreamLogs writes the runtime logs of every container in every pod of the application
+// to out. Each line is prefixed with the colorized pod and container name.
+//
+// If opts.Follow is set, StreamLogs watches the application pods and streams the logs of
+// new pods and restarted containers until ctx is cancelled.
+func (a *App) StreamLogs(ctx context.Context, clientset kubernetes.Interface, out io.Writer, opts LogOptions) error {
+	s := &logStreamer{
+		out:       out,
+		opts:      opts,
+		streaming: make(map[string]bool),
+		openLogs: func(ctx context.Context, podName string, opts *v1.PodLogOptions) (io.ReadCloser, error) {
+			return clientset.CoreV1().Pods(a.Namespace).GetLogs(podName, opts).Stream(ctx)
+		},
+	}
+	labels := map[string]string{DraftLabelKey: a.Name}
+	list := func(ctx context.Context) (*v1.PodList, error) {
+		return podutil.ListPodsContext(ctx, a.Namespace, labels, nil, clientset)
+	}
+
+	podList, err := list(ctx)
+	if err != nil {
+		return err
+	}
+	if len(podList.Items) == 0 && !opts.Follow {
+		return fmt.Errorf("no pods found for application %q in namespace %q", a.Name, a.Namespace)
+	}
+	for i := range podList.Items {
+		s.streamPod(ctx, &podList.Items[i])
+	}
+	if !opts.Follow {
+		s.wg.Wait()
+		return nil
+	}
+
+	err = s.follow(ctx, podList.ResourceVersion, list, func(ctx context.Context, resourceVersion string) (watch.Interface, error) {
+		return clientset.CoreV1().Pods(a.Namespace).Watch(ctx, metav1.ListOptions{LabelSelector: podutil.Selector(labels), ResourceVersion: resourceVersion})
+	})
+	s.wg.Wait()
+	return err
+}
+
+// watchRetryInterval is how long StreamLogs and Connection.Lost wait before watching pods
+// again after failing to.
+var watchRetryInterval = 2 * time.Second
+
+// follow watches the application pods from resourceVersion and streams the logs of new pods
+// and restarted containers until ctx is done. When the watch closes, as the API server ends
+// watches after a while, it is watched again from the last resource version seen, and the
+// pods are listed again if that version expired.
+func (s *logStreamer) follow(ctx context.Context, resourceVersion string, list func(context.Context) (*v1.PodList, error), watchPods func(context.Context, string) (watch.Interface, error)) error {
+	w, err := watchPods(ctx, resourceVersion)
+	if err != nil {
+		return err
+	}
+	for {
+		var expired bool
+		resourceVersion, expired = s.watch(ctx, w, resourceVersion)
+		for ctx.Err() == nil {
+			if expired {
+				podList, err := list(ctx)
+				if err == nil {
+					for i := range podList.Items {
+						s.streamPod(ctx, &podList.Items[i])
+					}
+					resourceVersion, expired = podList.ResourceVersion, false
+				}
+			}
+			if !expired {
+				if w, err = watchPods(ctx, resourceVersion); err == nil {
+					break
+				}
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(watchRetryInterval):
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// watch streams the logs of the pods sent by w until it closes or ctx is done. It returns
+// the last resource version seen, and whether the watch failed as that version expired.
+func (s *logStreamer) watch(ctx context.Context, w watch.Interface, resourceVersion string) (string, bool) {
+	defer w.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion, false
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return resourceVersion, false
+			}
+			if event.Type == watch.Error {
+				return resourceVersion, true
+			}
+			pod, isPod := event.Object.(*v1.Pod)
+			if !isPod {
+				continue
+			}
+			resourceVersion = pod.ResourceVersion
+			if event.Type == watch.Added || event.Type == watch.Modified {
+				s.streamPod(ctx, pod)
+			}
+		}
+	}
+}
+
+// logStreamer streams the logs of pod containers, streaming each container instance once.
+type logStreamer struct {
+	out      io.Writer
+	opts     LogOptions
+	openLogs func(ctx context.Context, podName string, opts *v1.PodLogOptions) (io.ReadCloser, error)
+
+	mu        sync.Mutex
+	streaming map[string]bool
+	wg        sync.WaitGroup
+}
+
+func (s *logStreamer) streamPod(ctx context.Context, pod *v1.Pod) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if s.opts.Container != "" && status.Name != s.opts.Container {
+			continue
+		}
+		// logs of containers which have not started yet are not available.
+		if !s.opts.Previous && status.State.Running == nil && status.State.Terminated == nil {
+			continue
+		}
+		// a restarted container is a new instance with its own logs.
+		key := fmt.Sprintf("%s/%s/%d", pod.Name, status.Name, status.RestartCount)
+		s.mu.Lock()
+		seen := s.streaming[key]
+		s.streaming[key] = true
+		s.mu.Unlock()
+		if seen {
+			continue
+		}
+
+		s.wg.Add(1)
+		go func(podName, container string) {
+			defer s.wg.Done()
+			if err := s.stream(ctx, podName, container); err != nil && ctx.Err() == nil {
+				s.writeLine(podName, container, fmt.Sprintf("error streaming logs: %v\n", err))
+			}
+		}(pod.Name, status.Name)
+	}
+}
+
+func (s *logStreamer) stream(ctx context.Context, podName, container string) error {
+	logOpts := &v1.PodLogOptions{
+		Container: container,
+		Follow:    s.opts.Follow && !s.opts.Previous,
+		Previous:  s.opts.Previous,
+	}
+	if s.opts.Since > 0 {
+		since := int64(s.opts.Since.Seconds())
+		logOpts.SinceSeconds = &since
+	}
+	rc, err := s.openLogs(ctx, podName, logOpts)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	r := bufio.NewReader(rc)
+	for {
+		line, err := r.ReadString('\n')
+		if line != "" && (s.opts.Grep == nil || s.opts.Grep.MatchString(line)) {
+			if line[len(line)-1] != '\n' {
+				line += "\n"
+			}
+			s.writeLine(podName, container, line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeLine writes a prefixed log line. Lines of concurrent streams are not interleaved.
+func (s *logStreamer) writeLine(podName, container, line string) {
+	h := fnv.New32a()
+	h.Write([]byte(podName))
+	prefix := color.New(logColors[h.Sum32()%uint32(len(logColors))]).Sprintf("[%s/%s]", podName, container)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.out, "%s %s", prefix, line)
+}
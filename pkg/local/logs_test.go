@@ -0,0 +1,162 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestLogStreamer(t *testing.T) {
+	color.NoColor = true
+
+	running := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	waiting := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{}}
+	pod := func(name string, statuses ...v1.ContainerStatus) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     v1.PodStatus{ContainerStatuses: statuses},
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		opts     LogOptions
+		pods     []*v1.Pod
+		expected []string
+	}{
+		{
+			name: "all containers of all pods",
+			pods: []*v1.Pod{
+				pod("web-1", v1.ContainerStatus{Name: "app", State: running}, v1.ContainerStatus{Name: "sidecar", State: running}),
+				pod("web-2", v1.ContainerStatus{Name: "app", State: running}),
+			},
+			expected: []string{
+				"[web-1/app] hello from web-1/app",
+				"[web-1/app] listening on :8080",
+				"[web-1/sidecar] hello from web-1/sidecar",
+				"[web-1/sidecar] listening on :8080",
+				"[web-2/app] hello from web-2/app",
+				"[web-2/app] listening on :8080",
+			},
+		},
+		{
+			name: "container filter and grep",
+			opts: LogOptions{Container: "app", Grep: regexp.MustCompile("hello")},
+			pods: []*v1.Pod{
+				pod("web-1", v1.ContainerStatus{Name: "app", State: running}, v1.ContainerStatus{Name: "sidecar", State: running}),
+			},
+			expected: []string{"[web-1/app] hello from web-1/app"},
+		},
+		{
+			name: "containers are streamed once per instance",
+			pods: []*v1.Pod{
+				pod("web-1", v1.ContainerStatus{Name: "app", State: running}),
+				pod("web-1", v1.ContainerStatus{Name: "app", State: running}),
+				pod("web-1", v1.ContainerStatus{Name: "app", State: running, RestartCount: 1}),
+				pod("web-2", v1.ContainerStatus{Name: "app", State: waiting}),
+			},
+			expected: []string{
+				"[web-1/app] hello from web-1/app",
+				"[web-1/app] hello from web-1/app",
+				"[web-1/app] listening on :8080",
+				"[web-1/app] listening on :8080",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			s := &logStreamer{
+				out:       &out,
+				opts:      tc.opts,
+				streaming: make(map[string]bool),
+				openLogs: func(ctx context.Context, podName string, opts *v1.PodLogOptions) (io.ReadCloser, error) {
+					logs := "hello from " + podName + "/" + opts.Container + "\nlistening on :8080"
+					return ioutil.NopCloser(strings.NewReader(logs)), nil
+				},
+			}
+			for _, p := range tc.pods {
+				s.streamPod(context.Background(), p)
+			}
+			s.wg.Wait()
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			sort.Strings(lines)
+			if strings.Join(lines, "\n") != strings.Join(tc.expected, "\n") {
+				t.Errorf("Expected log lines\n%s\ngot\n%s", strings.Join(tc.expected, "\n"), strings.Join(lines, "\n"))
+			}
+		})
+	}
+}
+
+func TestLogStreamerFollowRewatches(t *testing.T) {
+	color.NoColor = true
+
+	running := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	pod := func(name, resourceVersion string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: resourceVersion},
+			Status:     v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{Name: "app", State: running}}},
+		}
+	}
+
+	var out bytes.Buffer
+	s := &logStreamer{
+		out:       &out,
+		opts:      LogOptions{Follow: true},
+		streaming: make(map[string]bool),
+		openLogs: func(ctx context.Context, podName string, opts *v1.PodLogOptions) (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader("hello from " + podName + "\n")), nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var resourceVersions []string
+	list := func(ctx context.Context) (*v1.PodList, error) {
+		return &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "10"}, Items: []v1.Pod{*pod("web-3", "9")}}, nil
+	}
+	watchPods := func(ctx context.Context, resourceVersion string) (watch.Interface, error) {
+		resourceVersions = append(resourceVersions, resourceVersion)
+		w, n := watch.NewFake(), len(resourceVersions)
+		go func() {
+			switch n {
+			case 1:
+				// the API server ends the watch.
+				w.Add(pod("web-1", "2"))
+				w.Stop()
+			case 2:
+				// the resource version expired.
+				w.Error(&metav1.Status{Code: 410})
+			case 3:
+				w.Add(pod("web-2", "11"))
+				cancel()
+			}
+		}()
+		return w, nil
+	}
+	if err := s.follow(ctx, "1", list, watchPods); err != nil {
+		t.Fatal(err)
+	}
+	s.wg.Wait()
+
+	if expected := []string{"1", "2", "10"}; strings.Join(resourceVersions, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected watches from resource versions %v, got %v", expected, resourceVersions)
+	}
+	for _, name := range []string{"web-1", "web-3"} {
+		if !strings.Contains(out.String(), "["+name+"/app] hello from "+name) {
+			t.Errorf("expected the logs of %s, got\n%s", name, out.String())
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package local
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// PortAssignments records the local ports assigned to the connections of each application,
+// so that an application is reachable on the same local ports every time it is connected.
+type PortAssignments struct {
+	path string
+	mu   sync.Mutex
+	// Apps maps an application, in the form namespace/name, or one of its Services, in the
+	// form namespace/name/service, to its port mappings in the form local_port:remote_port.
+	Apps map[string][]string `json:"apps"`
+}
+
+// LoadPortAssignments reads the port assignments stored at path. If the file does not
+// exist, no ports are assigned yet.
+func LoadPortAssignments(path string) (*PortAssignments, error) {
+	p := &PortAssignments{path: path, Apps: make(map[string][]string)}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, err
+	}
+	if p.Apps == nil {
+		p.Apps = make(map[string][]string)
+	}
+	return p, nil
+}
+
+// Get returns the port mappings assigned to the application, or to the application's
+// Service given by service if not empty.
+func (p *PortAssignments) Get(app *App, service string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.Apps[app.portKey(service)]
+}
+
+// Set assigns the port mappings to the application, or to the application's Service
+// given by service if not empty, and saves the assignments. As several draft connect
+// processes may assign ports at once, the assignments are read again and merged under a
+// lock, and written atomically.
+func (p *PortAssignments) Set(app *App, service string, mappings []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	unlock, err := lockFile(p.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := LoadPortAssignments(p.path)
+	if err != nil {
+		return err
+	}
+	p.Apps = current.Apps
+	p.Apps[app.portKey(service)] = mappings
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p.path, b)
+}
+
+const (
+	// lockTimeout is how long lockFile waits for a lock held by another process.
+	lockTimeout = 15 * time.Second
+	// staleLockAge is the age from which a lock is considered left behind by a process
+	// which died while holding it.
+	staleLockAge = 10 * time.Second
+)
+
+// lockFile takes a lock on path held across processes, by creating path.lock, and returns
+// the function releasing it.
+func lockFile(path string) (func(), error) {
+	lock := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for the lock %s", lock)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it to path, so
+// readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func (a *App) portKey(service string) string {
+	if service != "" {
+		return a.Namespace + "/" + a.Name + "/" + service
+	}
+	return a.Namespace + "/" + a.Name
+}
@@ -0,0 +1,87 @@
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestPortAssignments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "draft-ports-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ports.json")
+
+	ports, err := LoadPortAssignments(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := &App{Name: "api", Namespace: "default"}
+	if got := ports.Get(api, ""); got != nil {
+		t.Errorf("Expected no ports assigned yet, got %v", got)
+	}
+	if err := ports.Set(api, "", []string{"8080:80"}); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := LoadPortAssignments(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.Get(api, ""); !reflect.DeepEqual(got, []string{"8080:80"}) {
+		t.Errorf("Expected the assigned ports to be persisted, got %v", got)
+	}
+	if got := reloaded.Get(&App{Name: "api", Namespace: "staging"}, ""); got != nil {
+		t.Errorf("Expected applications in other namespaces to have their own ports, got %v", got)
+	}
+	if got := reloaded.Get(api, "api-web"); got != nil {
+		t.Errorf("Expected services to have their own ports, got %v", got)
+	}
+}
+
+func TestPortAssignmentsConcurrentSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "draft-ports-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ports.json")
+
+	// every process loads the assignments before any of them assigns ports.
+	const processes = 5
+	loaded := make([]*PortAssignments, processes)
+	for i := range loaded {
+		if loaded[i], err = LoadPortAssignments(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var wg sync.WaitGroup
+	for i, ports := range loaded {
+		wg.Add(1)
+		go func(i int, ports *PortAssignments) {
+			defer wg.Done()
+			app := &App{Name: "app" + strconv.Itoa(i), Namespace: "default"}
+			if err := ports.Set(app, "", []string{strconv.Itoa(8080+i) + ":80"}); err != nil {
+				t.Error(err)
+			}
+		}(i, ports)
+	}
+	wg.Wait()
+
+	reloaded, err := LoadPortAssignments(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.Apps) != processes {
+		t.Errorf("Expected the ports of %d applications to be kept, got %v", processes, reloaded.Apps)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock to be released, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package local
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProxyDomain is the domain the hostnames of connected applications are served under.
+const ProxyDomain = "localhost"
+
+// ProxyHostname returns the hostname routed to an application, or to one of its Services:
+// <app>.localhost and <service>.<app>.localhost respectively.
+func ProxyHostname(app, service string) string {
+	host := strings.ToLower(app) + "." + ProxyDomain
+	if service != "" {
+		host = strings.ToLower(service) + "." + host
+	}
+	return host
+}
+
+// Proxy is a local HTTP reverse proxy routing the hostnames of connected applications to
+// the local ports of their tunnels. Routes may change while the proxy is serving, so the
+// URLs of applications stay the same when a connection is re-established on another port.
+type Proxy struct {
+	// Log receives a line per proxied request. No requests are logged when it is nil.
+	Log io.Writer
+
+	mu      sync.RWMutex
+	logMu   sync.Mutex
+	routes  map[string]int
+	servers []*http.Server
+}
+
+// NewProxy returns a Proxy without routes, logging requests to log.
+func NewProxy(log io.Writer) *Proxy {
+	return &Proxy{Log: log, routes: make(map[string]int)}
+}
+
+// Route routes requests for host to the given local port.
+func (p *Proxy) Route(host string, port int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.routes[strings.ToLower(host)] = port
+}
+
+// Unroute removes the route of host.
+func (p *Proxy) Unroute(host string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.routes, strings.ToLower(host))
+}
+
+// Hosts returns the routed hostnames, sorted.
+func (p *Proxy) Hosts() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	hosts := make([]string, 0, len(p.routes))
+	for host := range p.routes {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+func (p *Proxy) lookup(host string) (int, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	port, ok := p.routes[strings.ToLower(host)]
+	return port, ok
+}
+
+// ServeHTTP proxies the request to the local port routed to its hostname. WebSocket
+// upgrades are passed through.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w}
+	defer func() {
+		p.logRequest(r, rec.status, time.Since(start))
+	}()
+
+	port, ok := p.lookup(r.Host)
+	if !ok {
+		http.Error(rec, fmt.Sprintf("no application is connected for host %q", r.Host), http.StatusBadGateway)
+		return
+	}
+	target := &url.URL{Scheme: "http", Host: fmt.Sprintf("127.0.0.1:%d", port)}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		http.Error(w, fmt.Sprintf("could not reach %s: %v", r.Host, err), http.StatusBadGateway)
+	}
+	// flush streamed responses, e.g. server-sent events, right away.
+	proxy.FlushInterval = -1
+	proxy.ServeHTTP(rec, r)
+}
+
+func (p *Proxy) logRequest(r *http.Request, status int, latency time.Duration) {
+	if p.Log == nil {
+		return
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	p.logMu.Lock()
+	defer p.logMu.Unlock()
+	fmt.Fprintf(p.Log, "%s %s%s %d %s\n", r.Method, r.Host, r.URL.RequestURI(), status, latency.Round(time.Millisecond))
+}
+
+// Listen serves the proxy on the given port of the loopback interfaces. Serving on the
+// IPv6 loopback interface is best effort, as some systems do not have one.
+func (p *Proxy) Listen(port int) error {
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return fmt.Errorf("could not start proxy on port %d: %v", port, err)
+	}
+	listeners := []net.Listener{l}
+	if l6, err := net.Listen("tcp", fmt.Sprintf("[::1]:%d", port)); err == nil {
+		listeners = append(listeners, l6)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, l := range listeners {
+		srv := &http.Server{Handler: p}
+		p.servers = append(p.servers, srv)
+		go srv.Serve(l)
+	}
+	return nil
+}
+
+// Close stops serving the proxy.
+func (p *Proxy) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var err error
+	for _, srv := range p.servers {
+		if e := srv.Close(); e != nil {
+			err = e
+		}
+	}
+	p.servers = nil
+	return err
+}
+
+// statusRecorder records the status code of a response. It lets the connection be
+// hijacked for WebSocket upgrades and streamed responses be flushed.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("connection cannot be hijacked")
+	}
+	if r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
@@ -0,0 +1,162 @@
+package local
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestProxyHostname(t *testing.T) {
+	tests := []struct {
+		app, service, expected string
+	}{
+		{"api", "", "api.localhost"},
+		{"Api", "", "api.localhost"},
+		{"api", "api-metrics", "api-metrics.api.localhost"},
+	}
+	for _, tt := range tests {
+		if got := ProxyHostname(tt.app, tt.service); got != tt.expected {
+			t.Errorf("ProxyHostname(%q, %q): expected %q, got %q", tt.app, tt.service, tt.expected, got)
+		}
+	}
+}
+
+// backendPort returns the local port a test server listens on.
+func backendPort(t *testing.T, srv *httptest.Server) int {
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return port
+}
+
+func TestProxyRoutesByHostname(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "api %s %s", r.Host, r.URL.Path)
+	}))
+	defer api.Close()
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer auth.Close()
+
+	var log bytes.Buffer
+	p := NewProxy(&log)
+	p.Route("api.localhost", backendPort(t, api))
+	p.Route("auth.localhost", backendPort(t, auth))
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+
+	tests := []struct {
+		host   string
+		status int
+		body   string
+	}{
+		{"api.localhost:8888", http.StatusOK, "api api.localhost:8888 /users"},
+		{"API.localhost", http.StatusOK, "api API.localhost /users"},
+		{"auth.localhost", http.StatusUnauthorized, ""},
+		{"web.localhost", http.StatusBadGateway, "no application is connected"},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("GET", srv.URL+"/users", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Host = tt.host
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.host, tt.status, resp.StatusCode)
+		}
+		if !strings.Contains(string(body), tt.body) {
+			t.Errorf("%s: expected body to contain %q, got %q", tt.host, tt.body, body)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != len(tests) {
+		t.Fatalf("expected %d logged requests, got %q", len(tests), lines)
+	}
+	if !strings.HasPrefix(lines[0], "GET api.localhost:8888/users 200 ") {
+		t.Errorf("unexpected log line %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[2], "GET auth.localhost/users 401 ") {
+		t.Errorf("unexpected log line %q", lines[2])
+	}
+
+	p.Unroute("auth.localhost")
+	if hosts := p.Hosts(); len(hosts) != 1 || hosts[0] != "api.localhost" {
+		t.Errorf("expected only api.localhost to be routed, got %v", hosts)
+	}
+}
+
+func TestProxyWebSocketUpgrade(t *testing.T) {
+	// the backend switches protocols and echoes whatever it receives.
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" {
+			http.Error(w, "expected an upgrade", http.StatusBadRequest)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		rw.WriteString("echo: " + line)
+		rw.Flush()
+	}))
+	defer backend.Close()
+
+	var log bytes.Buffer
+	p := NewProxy(&log)
+	p.Route("ws.localhost", backendPort(t, backend))
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "GET /socket HTTP/1.1\r\nHost: ws.localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected status 101, got %d", resp.StatusCode)
+	}
+	fmt.Fprint(conn, "hello\n")
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "echo: hello\n" {
+		t.Errorf("expected the backend to echo, got %q", line)
+	}
+	conn.Close()
+}
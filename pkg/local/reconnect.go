@@ -0,0 +1,143 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/Azure/draft/pkg/kube/podutil"
+)
+
+// Lost returns a channel that is closed when the connection's pod is deleted, terminating
+// or no longer ready, or when one of the connection's tunnels is disconnected.
+//
+// As the API server ends watches after a while, the pod is watched again from the last
+// resource version seen, and read again if that version expired. Watching stops when ctx
+// is cancelled.
+func (c *Connection) Lost(ctx context.Context, namespace string) (<-chan struct{}, error) {
+	pods := c.Clientset.CoreV1().Pods(namespace)
+	watchPod := func(resourceVersion string) (watch.Interface, error) {
+		return pods.Watch(ctx, metav1.ListOptions{
+			FieldSelector:   fields.OneTermEqualSelector("metadata.name", c.PodName).String(),
+			ResourceVersion: resourceVersion,
+		})
+	}
+	w, err := watchPod("")
+	if err != nil {
+		return nil, err
+	}
+
+	lost := make(chan struct{})
+	tunnelDone := c.tunnelDone()
+	go func() {
+		defer close(lost)
+		var resourceVersion string
+		for {
+			var gone, expired bool
+			resourceVersion, gone, expired = watchUntilGone(ctx, w, tunnelDone, resourceVersion)
+			if gone {
+				return
+			}
+			for {
+				if expired {
+					pod, err := pods.Get(ctx, c.PodName, metav1.GetOptions{})
+					if apierrors.IsNotFound(err) || (err == nil && podUnavailable(pod)) {
+						return
+					}
+					if err == nil {
+						resourceVersion, expired = pod.ResourceVersion, false
+					}
+				}
+				if !expired {
+					if w, err = watchPod(resourceVersion); err == nil {
+						break
+					}
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-tunnelDone:
+					return
+				case <-time.After(watchRetryInterval):
+				}
+			}
+		}
+	}()
+	return lost, nil
+}
+
+// watchUntilGone reads the events of w until the pod is gone, a tunnel is disconnected, ctx
+// is done or w closes. It returns the last resource version seen, whether the connection is
+// lost, and whether the watch failed as that version expired.
+func watchUntilGone(ctx context.Context, w watch.Interface, tunnelDone <-chan struct{}, resourceVersion string) (string, bool, bool) {
+	defer w.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion, true, false
+		case <-tunnelDone:
+			return resourceVersion, true, false
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return resourceVersion, false, false
+			}
+			if event.Type == watch.Error {
+				return resourceVersion, false, true
+			}
+			if podGone(event) {
+				return resourceVersion, true, false
+			}
+			if pod, isPod := event.Object.(*v1.Pod); isPod {
+				resourceVersion = pod.ResourceVersion
+			}
+		}
+	}
+}
+
+// tunnelDone returns a channel that is closed when any tunnel of the connection is disconnected.
+func (c *Connection) tunnelDone() <-chan struct{} {
+	var once sync.Once
+	done := make(chan struct{})
+	for _, cc := range c.ContainerConnections {
+		for _, t := range cc.Tunnels {
+			go func(tunnelDone <-chan struct{}) {
+				<-tunnelDone
+				once.Do(func() { close(done) })
+			}(t.Done())
+		}
+	}
+	return done
+}
+
+// podGone reports whether a watch event means the pod can no longer serve a connection.
+func podGone(event watch.Event) bool {
+	if event.Type == watch.Deleted {
+		return true
+	}
+	pod, ok := event.Object.(*v1.Pod)
+	return ok && podUnavailable(pod)
+}
+
+// podUnavailable reports whether a pod is terminating or not ready.
+func podUnavailable(pod *v1.Pod) bool {
+	return pod.DeletionTimestamp != nil || !podutil.IsPodReady(pod)
+}
+
+// PortMappings returns the local and remote ports of the connection's tunnels in the
+// form local_port:remote_port, so a new connection can reuse the same local ports.
+func (c *Connection) PortMappings() []string {
+	var mappings []string
+	for _, cc := range c.ContainerConnections {
+		for _, t := range cc.Tunnels {
+			mappings = append(mappings, fmt.Sprintf("%d:%d", t.Local, t.Remote))
+		}
+	}
+	return mappings
+}
@@ -0,0 +1,115 @@
+package local
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/Azure/draft/pkg/draft/tunnel"
+)
+
+func TestPodGone(t *testing.T) {
+	now := metav1.Now()
+	ready := v1.PodStatus{Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}}
+
+	testCases := []struct {
+		name     string
+		event    watch.Event
+		expected bool
+	}{
+		{"ready", watch.Event{Type: watch.Modified, Object: &v1.Pod{Status: ready}}, false},
+		{"not ready", watch.Event{Type: watch.Modified, Object: &v1.Pod{}}, true},
+		{"terminating", watch.Event{Type: watch.Modified, Object: &v1.Pod{ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now}, Status: ready}}, true},
+		{"deleted", watch.Event{Type: watch.Deleted, Object: &v1.Pod{Status: ready}}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := podGone(tc.event); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestConnectionLostRewatches(t *testing.T) {
+	ready := v1.PodStatus{Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}}
+	pod := func(resourceVersion string, status v1.PodStatus) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", ResourceVersion: resourceVersion}, Status: status}
+	}
+
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, pod("7", ready), nil
+	})
+	var (
+		mu               sync.Mutex
+		resourceVersions []string
+	)
+	clientset.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		resourceVersions = append(resourceVersions, action.(k8stesting.WatchAction).GetWatchRestrictions().ResourceVersion)
+		w, n := watch.NewFake(), len(resourceVersions)
+		go func() {
+			switch n {
+			case 1:
+				// the API server ends the watch.
+				w.Modify(pod("5", ready))
+				w.Stop()
+			case 2:
+				// the resource version expired.
+				w.Error(&metav1.Status{Code: 410})
+			case 3:
+				w.Modify(pod("8", v1.PodStatus{}))
+			}
+		}()
+		return true, w, nil
+	})
+
+	c := &Connection{PodName: "web-1", Clientset: clientset}
+	lost, err := c.Lost(context.Background(), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-lost:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the connection to be lost once the pod is not ready")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if expected := []string{"", "5", "7"}; !reflect.DeepEqual(resourceVersions, expected) {
+		t.Errorf("Expected watches from resource versions %v, got %v", expected, resourceVersions)
+	}
+}
+
+func TestPortMappings(t *testing.T) {
+	c := &Connection{
+		ContainerConnections: []*ContainerConnection{
+			{ContainerName: "app", Tunnels: []*tunnel.Tunnel{{Local: 8080, Remote: 80}, {Local: 9229, Remote: 9229}}},
+			{ContainerName: "sidecar", Tunnels: []*tunnel.Tunnel{{Local: 9090, Remote: 90}}},
+		},
+	}
+	expected := []string{"8080:80", "9229:9229", "9090:90"}
+	if got := c.PortMappings(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	m, err := getPortMapping(c.PortMappings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m[80] != 8080 || m[90] != 9090 {
+		t.Errorf("Expected the port mappings to be reusable for a new connection, got %v", m)
+	}
+}
@@ -0,0 +1,118 @@
+package local
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DetachedConnection is a connection started in the background by draft connect --detach.
+type DetachedConnection struct {
+	PID int `json:"pid"`
+	// ProcessStart is the start time of the process as reported by the operating system.
+	// As PIDs are reused, a process with the PID of the connection is the connection only
+	// if it started at the same time.
+	ProcessStart string        `json:"processStart,omitempty"`
+	Started      time.Time     `json:"started"`
+	Apps         []DetachedApp `json:"apps"`
+}
+
+// DetachedApp is an application, or one of its Services, connected by a detached connection.
+type DetachedApp struct {
+	Name        string   `json:"name"`
+	Namespace   string   `json:"namespace"`
+	Environment string   `json:"environment"`
+	Service     string   `json:"service,omitempty"`
+	Ports       []string `json:"ports"`
+}
+
+// HasApp returns whether the connection connects the application named name.
+func (c *DetachedConnection) HasApp(name string) bool {
+	for _, app := range c.Apps {
+		if app.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry keeps track of detached connections, one file per process.
+type Registry struct {
+	dir string
+}
+
+// NewRegistry returns a Registry storing detached connections in dir.
+func NewRegistry(dir string) *Registry {
+	return &Registry{dir: dir}
+}
+
+// Register records a detached connection.
+func (r *Registry) Register(c *DetachedConnection) error {
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path(c.PID), b, 0644)
+}
+
+// Unregister removes the detached connection of the process given by pid. Removing a
+// connection which is not registered is not an error.
+func (r *Registry) Unregister(pid int) error {
+	if err := os.Remove(r.path(pid)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List returns the registered detached connections, oldest first. Connections whose
+// process is no longer alive according to alive, or whose PID was reused by another
+// process, are stale; they are removed from the registry and not returned.
+func (r *Registry) List(alive func(c *DetachedConnection) bool) ([]*DetachedConnection, error) {
+	files, err := ioutil.ReadDir(r.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var conns []*DetachedConnection
+	for _, f := range files {
+		pid, err := strconv.Atoi(strings.TrimSuffix(f.Name(), ".json"))
+		if err != nil || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		b, err := ioutil.ReadFile(r.path(pid))
+		if os.IsNotExist(err) {
+			// the connection unregistered itself meanwhile.
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		var c DetachedConnection
+		if err := json.Unmarshal(b, &c); err != nil {
+			return nil, fmt.Errorf("invalid connection record %s: %v", f.Name(), err)
+		}
+		if c.PID != pid || !alive(&c) {
+			if err := r.Unregister(pid); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		conns = append(conns, &c)
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].Started.Before(conns[j].Started) })
+	return conns, nil
+}
+
+func (r *Registry) path(pid int) string {
+	return filepath.Join(r.dir, strconv.Itoa(pid)+".json")
+}
@@ -0,0 +1,57 @@
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "draft-registry-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := NewRegistry(dir)
+	now := time.Now()
+	for _, c := range []*DetachedConnection{
+		{PID: 200, Started: now, Apps: []DetachedApp{{Name: "auth", Ports: []string{"9000:9000"}}}},
+		{PID: 100, Started: now.Add(-time.Hour), Apps: []DetachedApp{{Name: "api", Ports: []string{"8080:80"}}}},
+		{PID: 300, Started: now, Apps: []DetachedApp{{Name: "stale"}}},
+		{PID: 400, ProcessStart: "1000", Started: now, Apps: []DetachedApp{{Name: "reused"}}},
+	} {
+		if err := r.Register(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the PID of connection 400 was reused by a process started later.
+	alive := func(c *DetachedConnection) bool { return c.PID != 300 && c.ProcessStart != "1000" }
+	conns, err := r.List(alive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conns) != 2 || conns[0].PID != 100 || conns[1].PID != 200 {
+		t.Fatalf("Expected connections 100 and 200 oldest first, got %v", conns)
+	}
+	if !conns[0].HasApp("api") || conns[0].HasApp("auth") {
+		t.Errorf("Expected connection 100 to connect api only, got %v", conns[0].Apps)
+	}
+	for _, pid := range []int{300, 400} {
+		if _, err := os.Stat(r.path(pid)); !os.IsNotExist(err) {
+			t.Errorf("Expected the stale connection %d to be pruned", pid)
+		}
+	}
+
+	if err := r.Unregister(100); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Unregister(100); err != nil {
+		t.Errorf("Expected unregistering twice to succeed, got %v", err)
+	}
+	if conns, _ = r.List(alive); len(conns) != 1 {
+		t.Errorf("Expected a single connection left, got %v", conns)
+	}
+}
@@ -0,0 +1,107 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
+
+	"github.com/Azure/draft/pkg/draft/tunnel"
+	"github.com/Azure/draft/pkg/kube/podutil"
+)
+
+// servicePodTimeout is how long ConnectService waits for a ready pod backing the Service.
+const servicePodTimeout = 5 * time.Minute
+
+// ConnectService tunnels to a ready pod backing the Service given by serviceName and returns
+// the connection information. Each Service port is forwarded to the pod port it targets.
+func (a *App) ConnectService(clientset kubernetes.Interface, clientConfig *restclient.Config, serviceName string, overridePorts []string) (*Connection, error) {
+	svc, err := clientset.CoreV1().Services(a.Namespace).Get(context.Background(), serviceName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if len(svc.Spec.Selector) == 0 {
+		return nil, fmt.Errorf("service %q does not select any pods", serviceName)
+	}
+	pod, err := waitForReadyPod(clientset, a.Namespace, svc.Spec.Selector, servicePodTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get a pod backing service %q: %v", serviceName, err)
+	}
+	m, err := getPortMapping(overridePorts)
+	if err != nil {
+		return nil, err
+	}
+
+	var cc []*ContainerConnection
+	byContainer := make(map[string]*ContainerConnection)
+	for _, sp := range svc.Spec.Ports {
+		container, remote, err := resolveTargetPort(pod, sp)
+		if err != nil {
+			return nil, fmt.Errorf("service %q: %v", serviceName, err)
+		}
+		c, ok := byContainer[container]
+		if !ok {
+			c = &ContainerConnection{ContainerName: container}
+			byContainer[container] = c
+			cc = append(cc, c)
+		}
+		t := tunnel.NewWithLocalTunnel(clientset.CoreV1().RESTClient(), clientConfig, a.Namespace, pod.Name, remote, m[remote])
+		c.Tunnels = append(c.Tunnels, t)
+	}
+
+	return &Connection{
+		ContainerConnections: cc,
+		PodName:              pod.Name,
+		Clientset:            clientset,
+	}, nil
+}
+
+// waitForReadyPod returns a ready pod matching the labels, checking every second until
+// the timeout is reached.
+func waitForReadyPod(clientset kubernetes.Interface, namespace string, labels map[string]string, timeout time.Duration) (*v1.Pod, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		pods, err := podutil.ListPods(namespace, labels, nil, clientset)
+		if err != nil {
+			return nil, err
+		}
+		for i := range pods {
+			if pods[i].DeletionTimestamp == nil && podutil.IsPodReady(&pods[i]) {
+				return &pods[i], nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no ready pod found: timed out")
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// resolveTargetPort returns the container and container port a Service port targets.
+func resolveTargetPort(pod *v1.Pod, sp v1.ServicePort) (string, int, error) {
+	target := sp.TargetPort
+	if target.Type == intstr.Int && target.IntValue() == 0 {
+		target = intstr.FromInt(int(sp.Port))
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if (target.Type == intstr.String && p.Name == target.StrVal) ||
+				(target.Type == intstr.Int && int(p.ContainerPort) == target.IntValue()) {
+				return c.Name, int(p.ContainerPort), nil
+			}
+		}
+	}
+	if target.Type == intstr.String {
+		return "", 0, fmt.Errorf("no container of pod %q exposes a port named %q", pod.Name, target.StrVal)
+	}
+	// ports need not be declared by containers to be reachable.
+	if len(pod.Spec.Containers) == 0 {
+		return "", 0, fmt.Errorf("pod %q has no containers", pod.Name)
+	}
+	return pod.Spec.Containers[0].Name, target.IntValue(), nil
+}
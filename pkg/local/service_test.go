@@ -0,0 +1,51 @@
+package local
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestResolveTargetPort(t *testing.T) {
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{Name: "app", Ports: []v1.ContainerPort{{Name: "http", ContainerPort: 8080}}},
+				{Name: "metrics", Ports: []v1.ContainerPort{{Name: "prom", ContainerPort: 9090}}},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name              string
+		port              v1.ServicePort
+		expectedContainer string
+		expectedPort      int
+		expectErr         bool
+	}{
+		{"named port", v1.ServicePort{Port: 80, TargetPort: intstr.FromString("prom")}, "metrics", 9090, false},
+		{"numbered port", v1.ServicePort{Port: 80, TargetPort: intstr.FromInt(8080)}, "app", 8080, false},
+		{"defaults to the service port", v1.ServicePort{Port: 9090}, "metrics", 9090, false},
+		{"undeclared port", v1.ServicePort{Port: 80, TargetPort: intstr.FromInt(3000)}, "app", 3000, false},
+		{"unknown port name", v1.ServicePort{Port: 80, TargetPort: intstr.FromString("grpc")}, "", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			container, port, err := resolveTargetPort(pod, tc.port)
+			if tc.expectErr {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if container != tc.expectedContainer || port != tc.expectedPort {
+				t.Errorf("Expected %s:%d, got %s:%d", tc.expectedContainer, tc.expectedPort, container, port)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package local
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
+)
+
+// SyncRule maps a local path, relative to the application directory, to a path in the container.
+type SyncRule struct {
+	Local  string
+	Remote string
+}
+
+// SyncRules are the rules files are synced into the container by.
+type SyncRules []SyncRule
+
+// NewSyncRules returns the rules for the given mapping of local paths to container paths.
+// The most specific local path takes precedence when several rules match a file.
+func NewSyncRules(paths map[string]string) SyncRules {
+	var rules SyncRules
+	for l, r := range paths {
+		rules = append(rules, SyncRule{Local: path.Clean(filepath.ToSlash(l)), Remote: path.Clean(r)})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if len(rules[i].Local) != len(rules[j].Local) {
+			return len(rules[i].Local) > len(rules[j].Local)
+		}
+		return rules[i].Local < rules[j].Local
+	})
+	return rules
+}
+
+// Target returns the container path of a file given by its path relative to the
+// application directory, and whether any rule matches the file.
+func (rules SyncRules) Target(file string) (string, bool) {
+	file = path.Clean(filepath.ToSlash(file))
+	for _, r := range rules {
+		switch {
+		case r.Local == ".":
+			return path.Join(r.Remote, file), true
+		case file == r.Local:
+			return r.Remote, true
+		case strings.HasPrefix(file, r.Local+"/"):
+			return path.Join(r.Remote, strings.TrimPrefix(file, r.Local+"/")), true
+		}
+	}
+	return "", false
+}
+
+// Resolve maps changed files, relative to the application directory, to their container
+// paths. Files no rule matches are returned in unmatched.
+func (rules SyncRules) Resolve(changed []string) (files map[string]string, unmatched []string) {
+	files = make(map[string]string)
+	for _, f := range changed {
+		if remote, ok := rules.Target(f); ok {
+			files[f] = remote
+		} else {
+			unmatched = append(unmatched, f)
+		}
+	}
+	return files, unmatched
+}
+
+// Files returns every file below the local paths of the rules, relative to appDir, mapped
+// to its container path.
+func (rules SyncRules) Files(appDir string) (map[string]string, error) {
+	files := make(map[string]string)
+	for _, r := range rules {
+		root := filepath.Join(appDir, filepath.FromSlash(r.Local))
+		err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if info.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			rel, err := filepath.Rel(appDir, p)
+			if err != nil {
+				return err
+			}
+			// a more specific rule may map the file elsewhere.
+			if remote, ok := rules.Target(rel); ok {
+				files[filepath.ToSlash(rel)] = remote
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not read sync path %q: %v", r.Local, err)
+		}
+	}
+	return files, nil
+}
+
+// SyncFiles copies files, given by their path relative to appDir and mapped to their
+// container path, into a container of the application pod given by podName. Files that
+// no longer exist locally are removed from the container. The container needs tar.
+func (a *App) SyncFiles(clientset kubernetes.Interface, clientConfig *restclient.Config, podName, container, appDir string, files map[string]string) error {
+	var (
+		copied  = make(map[string]string)
+		removed []string
+	)
+	for local, remote := range files {
+		if _, err := os.Stat(filepath.Join(appDir, filepath.FromSlash(local))); os.IsNotExist(err) {
+			removed = append(removed, remote)
+		} else {
+			copied[local] = remote
+		}
+	}
+
+	if len(copied) > 0 {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(writeSyncArchive(pw, appDir, copied))
+		}()
+		err := a.execSync(clientset, clientConfig, podName, container, pr, "tar", "xmf", "-", "-C", "/")
+		pr.Close()
+		if err != nil {
+			return fmt.Errorf("could not copy files into pod %s: %v", podName, err)
+		}
+	}
+	if len(removed) > 0 {
+		sort.Strings(removed)
+		if err := a.execSync(clientset, clientConfig, podName, container, nil, append([]string{"rm", "-rf"}, removed...)...); err != nil {
+			return fmt.Errorf("could not remove files from pod %s: %v", podName, err)
+		}
+	}
+	return nil
+}
+
+func (a *App) execSync(clientset kubernetes.Interface, clientConfig *restclient.Config, podName, container string, stdin io.Reader, command ...string) error {
+	var stderr bytes.Buffer
+	err := a.Exec(clientset, clientConfig, podName, ExecOptions{
+		Container: container,
+		Command:   command,
+		Stdin:     stdin,
+		Stderr:    &stderr,
+	})
+	if err != nil && stderr.Len() > 0 {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+// writeSyncArchive writes a tar archive of the files, with their container paths as names.
+func writeSyncArchive(w io.Writer, appDir string, files map[string]string) error {
+	locals := make([]string, 0, len(files))
+	for local := range files {
+		locals = append(locals, local)
+	}
+	sort.Strings(locals)
+
+	tw := tar.NewWriter(w)
+	for _, local := range locals {
+		if err := addSyncFile(tw, filepath.Join(appDir, filepath.FromSlash(local)), files[local]); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func addSyncFile(tw *tar.Writer, name, remote string) error {
+	info, err := os.Lstat(name)
+	if err != nil {
+		return err
+	}
+	var link string
+	if info.Mode()&os.ModeSymlink != 0 {
+		if link, err = os.Readlink(name); err != nil {
+			return err
+		}
+	}
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = strings.TrimPrefix(remote, "/")
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
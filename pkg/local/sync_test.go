@@ -0,0 +1,125 @@
+package local
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSyncRulesTarget(t *testing.T) {
+	rules := NewSyncRules(map[string]string{
+		"src":           "/app/src",
+		"src/templates": "/usr/share/templates/",
+		"config.yaml":   "/etc/app/config.yaml",
+	})
+
+	tests := []struct {
+		file     string
+		expected string
+		ok       bool
+	}{
+		{"src/index.js", "/app/src/index.js", true},
+		{"src/lib/util.js", "/app/src/lib/util.js", true},
+		{"src/templates/home.html", "/usr/share/templates/home.html", true},
+		{"config.yaml", "/etc/app/config.yaml", true},
+		{"./src/index.js", "/app/src/index.js", true},
+		{"srcs/index.js", "", false},
+		{"Dockerfile", "", false},
+	}
+	for _, tt := range tests {
+		remote, ok := rules.Target(tt.file)
+		if remote != tt.expected || ok != tt.ok {
+			t.Errorf("Target(%q): expected (%q, %v), got (%q, %v)", tt.file, tt.expected, tt.ok, remote, ok)
+		}
+	}
+
+	all := NewSyncRules(map[string]string{".": "/app"})
+	if remote, ok := all.Target("src/index.js"); !ok || remote != "/app/src/index.js" {
+		t.Errorf("expected the whole application directory to be synced, got (%q, %v)", remote, ok)
+	}
+}
+
+func TestSyncRulesResolve(t *testing.T) {
+	rules := NewSyncRules(map[string]string{"src": "/app/src"})
+	files, unmatched := rules.Resolve([]string{"src/index.js", "Dockerfile", "package.json"})
+
+	if expected := map[string]string{"src/index.js": "/app/src/index.js"}; !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected files %v, got %v", expected, files)
+	}
+	if expected := []string{"Dockerfile", "package.json"}; !reflect.DeepEqual(unmatched, expected) {
+		t.Errorf("expected unmatched files %v, got %v", expected, unmatched)
+	}
+}
+
+func TestSyncArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "draft-sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"src/index.js":      "console.log('hi')",
+		"src/lib/util.js":   "module.exports = {}",
+		"src/.git/HEAD":     "ref: refs/heads/master",
+		"templates/a.html":  "<p>a</p>",
+		"Dockerfile":        "FROM node",
+		"templates/b.html":  "<p>b</p>",
+		"templates/c/d.txt": "d",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules := NewSyncRules(map[string]string{"src": "/app/src", "templates": "/app/views"})
+	files, err := rules.Files(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"src/index.js":      "/app/src/index.js",
+		"src/lib/util.js":   "/app/src/lib/util.js",
+		"templates/a.html":  "/app/views/a.html",
+		"templates/b.html":  "/app/views/b.html",
+		"templates/c/d.txt": "/app/views/c/d.txt",
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("expected files %v, got %v", expected, files)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSyncArchive(&buf, dir, files); err != nil {
+		t.Fatal(err)
+	}
+	archived := make(map[string]string)
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archived[hdr.Name] = string(content)
+	}
+	if archived["app/src/index.js"] != "console.log('hi')" || archived["app/views/c/d.txt"] != "d" {
+		t.Errorf("unexpected archive content %v", archived)
+	}
+	if len(archived) != len(files) {
+		t.Errorf("expected %d archived files, got %d", len(files), len(archived))
+	}
+}
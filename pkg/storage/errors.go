@@ -27,3 +27,9 @@ func NewErrAppBuildNotFound(appName, buildID string) error {
 func NewErrAppBuildExists(appName, buildID string) error {
 	return fmt.Errorf("application %q build storage with ID %q already exists", appName, buildID)
 }
+
+// NewErrAppBuildLogsNotFound returns a formatted error specifying no build logs
+// are stored for the build with buildID.
+func NewErrAppBuildLogsNotFound(appName, buildID string) error {
+	return fmt.Errorf("application %q build logs with ID %q not found", appName, buildID)
+}
@@ -2,23 +2,33 @@ package inprocess
 
 import (
 	"context"
+	"sync"
+
 	"github.com/Azure/draft/pkg/storage"
-	"github.com/golang/protobuf/ptypes"
-	"time"
 )
 
 // Store is an inprocess storage engine for draft.
 type Store struct {
 	// builds is mapping of app name to storage objects.
 	builds map[string][]*storage.Object
+
+	// logs is mapping of app name and build ID to build log chunks.
+	logs   map[string][]*storage.LogChunk
+	logsMu sync.Mutex
 }
 
-// compile-time guarantee that *Store implements storage.Store
-var _ storage.Store = (*Store)(nil)
+// compile-time guarantee that *Store implements storage.Store and storage.LogStore
+var (
+	_ storage.Store    = (*Store)(nil)
+	_ storage.LogStore = (*Store)(nil)
+)
 
 // NewStore returns a new inprocess memory Store for storing draft application context.
 func NewStore() *Store {
-	return &Store{builds: make(map[string][]*storage.Object)}
+	return &Store{
+		builds: make(map[string][]*storage.Object),
+		logs:   make(map[string][]*storage.LogChunk),
+	}
 }
 
 // DeleteBuilds deletes all draft builds for the application specified by appName.
@@ -53,17 +63,16 @@ func (s *Store) DeleteBuild(ctx context.Context, appName, buildID string) (*stor
 // CreateBuild creates new storage for the application specified by appName to include build.
 //
 // If storage already exists for the application, ErrAppStorageExists is returned.
+// The build's creation time is set to now unless it is already set.
 //
 // CreateBuild implements storage.Creater.
 func (s *Store) CreateBuild(ctx context.Context, appName string, build *storage.Object) error {
 	if _, ok := s.builds[appName]; ok {
 		return storage.NewErrAppStorageExists(appName)
 	}
-	now, err := ptypes.TimestampProto(time.Now())
-	if err != nil {
+	if err := storage.SetCreatedAt(build); err != nil {
 		return err
 	}
-	build.CreatedAt = now
 	s.builds[appName] = []*storage.Object{build}
 	return nil
 }
@@ -71,18 +80,23 @@ func (s *Store) CreateBuild(ctx context.Context, appName string, build *storage.
 // UpdateBuild updates the application storage specified by appName to include build.
 //
 // If build does not exist, a new storage entry is created. Otherwise the existing storage
-// is updated.
+// is updated. The build's creation time is set to now unless it is already set.
 //
 // UpdateBuild implements storage.Updater.
 func (s *Store) UpdateBuild(ctx context.Context, appName string, build *storage.Object) (err error) {
-	if _, ok := s.builds[appName]; !ok {
+	h, ok := s.builds[appName]
+	if !ok {
 		return s.CreateBuild(ctx, appName, build)
 	}
-	if build.CreatedAt, err = ptypes.TimestampProto(time.Now()); err != nil {
+	for _, o := range h {
+		if build.BuildID == o.BuildID {
+			return storage.NewErrAppBuildExists(appName, build.BuildID)
+		}
+	}
+	if err = storage.SetCreatedAt(build); err != nil {
 		return err
 	}
-	s.builds[appName] = append(s.builds[appName], build)
-	// TODO(fibonacci1729): deduplication of builds.
+	s.builds[appName] = append(h, build)
 	return nil
 }
 
@@ -112,3 +126,46 @@ func (s *Store) GetBuild(ctx context.Context, appName, buildID string) (*storage
 	}
 	return nil, storage.NewErrAppBuildNotFound(appName, buildID)
 }
+
+// PutLogChunk stores a chunk of the build logs of the build given by buildID,
+// replacing the chunk with the same index if it is stored already.
+//
+// PutLogChunk implements storage.LogStore.
+func (s *Store) PutLogChunk(ctx context.Context, appName, buildID string, chunk *storage.LogChunk) error {
+	s.logsMu.Lock()
+	defer s.logsMu.Unlock()
+	key := appName + "/" + buildID
+	for i, stored := range s.logs[key] {
+		if stored.Index == chunk.Index {
+			s.logs[key][i] = chunk
+			return nil
+		}
+	}
+	s.logs[key] = append(s.logs[key], chunk)
+	return nil
+}
+
+// GetLogChunks returns the chunks of the build logs of the build given by buildID
+// with an index of at least from, ordered by index.
+//
+// GetLogChunks implements storage.LogStore.
+func (s *Store) GetLogChunks(ctx context.Context, appName, buildID string, from int) (chunks []*storage.LogChunk, err error) {
+	s.logsMu.Lock()
+	defer s.logsMu.Unlock()
+	for _, chunk := range s.logs[appName+"/"+buildID] {
+		if chunk.Index >= from {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks, nil
+}
+
+// DeleteLogs deletes the build logs of the build given by buildID.
+//
+// DeleteLogs implements storage.LogStore.
+func (s *Store) DeleteLogs(ctx context.Context, appName, buildID string) error {
+	s.logsMu.Lock()
+	defer s.logsMu.Unlock()
+	delete(s.logs, appName+"/"+buildID)
+	return nil
+}
@@ -3,6 +3,7 @@ package inprocess
 import (
 	"context"
 	"github.com/Azure/draft/pkg/storage"
+	"github.com/Azure/draft/pkg/storage/storagetest"
 	"github.com/golang/protobuf/ptypes"
 	"reflect"
 	"testing"
@@ -143,3 +144,11 @@ func objectStub(buildID, release string, contextID []byte) *storage.Object {
 }
 
 var createdAt = ptypes.TimestampNow()
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store { return NewStore() })
+}
+
+func TestLogStoreConformance(t *testing.T) {
+	storagetest.RunLogStore(t, func(t *testing.T) storage.LogStore { return NewStore() })
+}
@@ -0,0 +1,67 @@
+package configmap
+
+import (
+	"context"
+
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Azure/draft/pkg/storage"
+)
+
+// compile-time guarantee that *ConfigMaps implements storage.LogStore
+var _ storage.LogStore = (*ConfigMaps)(nil)
+
+// PutLogChunk stores a chunk of the build logs of the build given by buildID,
+// replacing the chunk with the same index if it is stored already.
+//
+// Each chunk is stored in its own ConfigMap to stay below the ConfigMap size limit. The ConfigMap
+// of the last chunk of a build in progress is updated in place.
+//
+// PutLogChunk implements storage.LogStore.
+func (s *ConfigMaps) PutLogChunk(ctx context.Context, appName, buildID string, chunk *storage.LogChunk) error {
+	cfgmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        storage.LogObjectName(appName, buildID, chunk.Index),
+			Labels:      storage.LogLabels(appName, buildID),
+			Annotations: storage.LogAnnotations(chunk),
+		},
+		BinaryData: map[string][]byte{storage.LogDataKey: chunk.Data},
+	}
+	_, err := s.impl.Create(ctx, cfgmap, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = s.impl.Update(ctx, cfgmap, metav1.UpdateOptions{})
+	}
+	return err
+}
+
+// GetLogChunks returns the chunks of the build logs of the build given by buildID
+// with an index of at least from, ordered by index.
+//
+// GetLogChunks implements storage.LogStore.
+func (s *ConfigMaps) GetLogChunks(ctx context.Context, appName, buildID string, from int) ([]*storage.LogChunk, error) {
+	ls, err := s.impl.List(ctx, metav1.ListOptions{LabelSelector: storage.LogSelector(appName, buildID)})
+	if err != nil {
+		return nil, err
+	}
+	var chunks []*storage.LogChunk
+	for _, cfgmap := range ls.Items {
+		chunk, err := storage.NewLogChunk(cfgmap.Annotations, cfgmap.BinaryData[storage.LogDataKey])
+		if err != nil {
+			return nil, err
+		}
+		if chunk.Index >= from {
+			chunks = append(chunks, chunk)
+		}
+	}
+	storage.SortLogChunks(chunks)
+	return chunks, nil
+}
+
+// DeleteLogs deletes the build logs of the build given by buildID.
+//
+// DeleteLogs implements storage.LogStore.
+func (s *ConfigMaps) DeleteLogs(ctx context.Context, appName, buildID string) error {
+	return s.impl.DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: storage.LogSelector(appName, buildID)})
+}
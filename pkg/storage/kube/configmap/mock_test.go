@@ -1,6 +1,7 @@
 package configmap
 
 import (
+	"context"
 	"testing"
 
 	"k8s.io/api/core/v1"
@@ -8,6 +9,7 @@ import (
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	"github.com/Azure/draft/pkg/storage"
@@ -61,7 +63,7 @@ func (mock *MockConfigMaps) Init(t *testing.T, entries ...struct {
 }
 
 // Get returns the ConfigMap by name.
-func (mock *MockConfigMaps) Get(name string, options metav1.GetOptions) (*v1.ConfigMap, error) {
+func (mock *MockConfigMaps) Get(ctx context.Context, name string, options metav1.GetOptions) (*v1.ConfigMap, error) {
 	cfgmap, ok := mock.cfgmaps[name]
 	if !ok {
 		return nil, apierrors.NewNotFound(testapigroup.Resource("tests"), name)
@@ -70,7 +72,7 @@ func (mock *MockConfigMaps) Get(name string, options metav1.GetOptions) (*v1.Con
 }
 
 // Create creates a new ConfigMap.
-func (mock *MockConfigMaps) Create(cfgmap *v1.ConfigMap) (*v1.ConfigMap, error) {
+func (mock *MockConfigMaps) Create(ctx context.Context, cfgmap *v1.ConfigMap, opts metav1.CreateOptions) (*v1.ConfigMap, error) {
 	name := cfgmap.ObjectMeta.Name
 	if object, ok := mock.cfgmaps[name]; ok {
 		return object, apierrors.NewAlreadyExists(testapigroup.Resource("tests"), name)
@@ -80,17 +82,46 @@ func (mock *MockConfigMaps) Create(cfgmap *v1.ConfigMap) (*v1.ConfigMap, error)
 }
 
 // Update updates a ConfigMap.
-func (mock *MockConfigMaps) Update(cfgmap *v1.ConfigMap) (*v1.ConfigMap, error) {
+func (mock *MockConfigMaps) Update(ctx context.Context, cfgmap *v1.ConfigMap, opts metav1.UpdateOptions) (*v1.ConfigMap, error) {
 	name := cfgmap.ObjectMeta.Name
 	mock.cfgmaps[name] = cfgmap
 	return cfgmap, nil
 }
 
 // Delete deletes a ConfigMap by name.
-func (mock *MockConfigMaps) Delete(name string, opts *metav1.DeleteOptions) error {
+func (mock *MockConfigMaps) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
 	if _, ok := mock.cfgmaps[name]; !ok {
 		return apierrors.NewNotFound(testapigroup.Resource("tests"), name)
 	}
 	delete(mock.cfgmaps, name)
 	return nil
 }
+
+// List lists the ConfigMaps matching the label selector.
+func (mock *MockConfigMaps) List(ctx context.Context, opts metav1.ListOptions) (*v1.ConfigMapList, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	var ls v1.ConfigMapList
+	for _, cfgmap := range mock.cfgmaps {
+		if selector.Matches(labels.Set(cfgmap.Labels)) {
+			ls.Items = append(ls.Items, *cfgmap)
+		}
+	}
+	return &ls, nil
+}
+
+// DeleteCollection deletes the ConfigMaps matching the label selector.
+func (mock *MockConfigMaps) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	selector, err := labels.Parse(listOpts.LabelSelector)
+	if err != nil {
+		return err
+	}
+	for name, cfgmap := range mock.cfgmaps {
+		if selector.Matches(labels.Set(cfgmap.Labels)) {
+			delete(mock.cfgmaps, name)
+		}
+	}
+	return nil
+}
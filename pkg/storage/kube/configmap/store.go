@@ -2,10 +2,8 @@ package configmap
 
 import (
 	"context"
-	"time"
 
 	"github.com/Azure/draft/pkg/storage"
-	"github.com/golang/protobuf/ptypes"
 	"k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -63,14 +61,13 @@ func (s *ConfigMaps) DeleteBuild(ctx context.Context, appName, buildID string) (
 // CreateBuild creates new storage for the application specified by appName to include build.
 //
 // If the configmap storage already exists for the application, ErrAppStorageExists is returned.
+// The build's creation time is set to now unless it is already set.
 //
 // CreateBuild implements storage.Creater.
 func (s *ConfigMaps) CreateBuild(ctx context.Context, appName string, build *storage.Object) error {
-	now, err := ptypes.TimestampProto(time.Now())
-	if err != nil {
+	if err := storage.SetCreatedAt(build); err != nil {
 		return err
 	}
-	build.CreatedAt = now
 
 	cfgmap, err := newConfigMap(appName, build)
 	if err != nil {
@@ -88,7 +85,7 @@ func (s *ConfigMaps) CreateBuild(ctx context.Context, appName string, build *sto
 // UpdateBuild updates the application configmap storage specified by appName to include build.
 //
 // If build does not exist, a new storage entry is created. Otherwise the existing storage
-// is updated.
+// is updated. The build's creation time is set to now unless it is already set.
 //
 // UpdateBuild implements storage.Updater.
 func (s *ConfigMaps) UpdateBuild(ctx context.Context, appName string, build *storage.Object) (err error) {
@@ -102,7 +99,7 @@ func (s *ConfigMaps) UpdateBuild(ctx context.Context, appName string, build *sto
 	if _, ok := cfgmap.Data[build.BuildID]; ok {
 		return storage.NewErrAppBuildExists(appName, build.BuildID)
 	}
-	if build.CreatedAt, err = ptypes.TimestampProto(time.Now()); err != nil {
+	if err = storage.SetCreatedAt(build); err != nil {
 		return err
 	}
 	content, err := storage.EncodeToString(build)
@@ -155,6 +152,19 @@ func (s *ConfigMaps) GetBuild(ctx context.Context, appName, buildID string) (obj
 	return nil, storage.NewErrAppBuildNotFound(appName, buildID)
 }
 
+// Apps returns the names of the applications with build storage.
+func (s *ConfigMaps) Apps(ctx context.Context) ([]string, error) {
+	ls, err := s.impl.List(ctx, metav1.ListOptions{LabelSelector: storage.HeritageLabel + "=draft"})
+	if err != nil {
+		return nil, err
+	}
+	var apps []string
+	for _, cfgmap := range ls.Items {
+		apps = append(apps, cfgmap.Name)
+	}
+	return apps, nil
+}
+
 // newConfigMap constructs a kubernetes ConfigMap object to store a build.
 //
 // Each configmap data entry is the base64 encoded string of a *storage.Object
@@ -166,11 +176,8 @@ func newConfigMap(appName string, build *storage.Object) (*v1.ConfigMap, error)
 	}
 	cfgmap := &v1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: appName,
-			Labels: map[string]string{
-				"heritage": "draft",
-				"appname":  appName,
-			},
+			Name:   appName,
+			Labels: storage.Labels(appName),
 		},
 		Data: map[string]string{build.BuildID: content},
 	}
@@ -5,7 +5,10 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/golang/protobuf/proto"
+
 	"github.com/Azure/draft/pkg/storage"
+	"github.com/Azure/draft/pkg/storage/storagetest"
 )
 
 func TestStoreDeleteBuilds(t *testing.T) {
@@ -124,7 +127,24 @@ func objectStub(buildID, release string, contextID []byte) *storage.Object {
 }
 
 func assertEqual(t *testing.T, label string, a, b interface{}) {
+	// decoded timestamps carry protobuf internal state, so compare messages with proto.Equal.
+	if ma, ok := a.(proto.Message); ok {
+		if mb, ok := b.(proto.Message); ok {
+			if !proto.Equal(ma, mb) {
+				t.Errorf("failed equality for %s", label)
+			}
+			return
+		}
+	}
 	if !reflect.DeepEqual(a, b) {
 		t.Errorf("failed equality for %s", label)
 	}
 }
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store { return NewConfigMapsWithMocks(t) })
+}
+
+func TestLogStoreConformance(t *testing.T) {
+	storagetest.RunLogStore(t, func(t *testing.T) storage.LogStore { return NewConfigMapsWithMocks(t) })
+}
@@ -0,0 +1,71 @@
+package secret
+
+import (
+	"context"
+
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Azure/draft/pkg/storage"
+)
+
+// LogsSecretType is the type of the kubernetes Secret objects holding build logs.
+const LogsSecretType v1.SecretType = "draft.sh/build-logs"
+
+// compile-time guarantee that *Secrets implements storage.LogStore
+var _ storage.LogStore = (*Secrets)(nil)
+
+// PutLogChunk stores a chunk of the build logs of the build given by buildID,
+// replacing the chunk with the same index if it is stored already.
+//
+// Each chunk is stored in its own Secret to stay below the Secret size limit. The Secret
+// of the last chunk of a build in progress is updated in place.
+//
+// PutLogChunk implements storage.LogStore.
+func (s *Secrets) PutLogChunk(ctx context.Context, appName, buildID string, chunk *storage.LogChunk) error {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        storage.LogObjectName(appName, buildID, chunk.Index),
+			Labels:      storage.LogLabels(appName, buildID),
+			Annotations: storage.LogAnnotations(chunk),
+		},
+		Type: LogsSecretType,
+		Data: map[string][]byte{storage.LogDataKey: chunk.Data},
+	}
+	_, err := s.impl.Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = s.impl.Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	return err
+}
+
+// GetLogChunks returns the chunks of the build logs of the build given by buildID
+// with an index of at least from, ordered by index.
+//
+// GetLogChunks implements storage.LogStore.
+func (s *Secrets) GetLogChunks(ctx context.Context, appName, buildID string, from int) ([]*storage.LogChunk, error) {
+	ls, err := s.impl.List(ctx, metav1.ListOptions{LabelSelector: storage.LogSelector(appName, buildID)})
+	if err != nil {
+		return nil, err
+	}
+	var chunks []*storage.LogChunk
+	for _, secret := range ls.Items {
+		chunk, err := storage.NewLogChunk(secret.Annotations, secret.Data[storage.LogDataKey])
+		if err != nil {
+			return nil, err
+		}
+		if chunk.Index >= from {
+			chunks = append(chunks, chunk)
+		}
+	}
+	storage.SortLogChunks(chunks)
+	return chunks, nil
+}
+
+// DeleteLogs deletes the build logs of the build given by buildID.
+//
+// DeleteLogs implements storage.LogStore.
+func (s *Secrets) DeleteLogs(ctx context.Context, appName, buildID string) error {
+	return s.impl.DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: storage.LogSelector(appName, buildID)})
+}
@@ -0,0 +1,127 @@
+package secret
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/testapigroup"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/Azure/draft/pkg/storage"
+)
+
+// MockSecrets mocks a kubernetes SecretInterface.
+//
+// For use in testing only.
+type MockSecrets struct {
+	corev1.SecretInterface
+	secrets map[string]*v1.Secret
+}
+
+// NewSecretsWithMocks initializes a new Secrets store initialized
+// with kubernetes Secret objects created from the provided entries.
+func NewSecretsWithMocks(t *testing.T, entries ...struct {
+	appName string
+	objects []*storage.Object
+}) *Secrets {
+	var mock MockSecrets
+	mock.Init(t, entries...)
+	return NewSecrets(&mock)
+}
+
+// Init initializes the MockSecrets mock with the set of storage objects.
+func (mock *MockSecrets) Init(t *testing.T, entries ...struct {
+	appName string
+	objects []*storage.Object
+}) {
+	mock.secrets = make(map[string]*v1.Secret)
+	for _, entry := range entries {
+		var secret *v1.Secret
+		for _, object := range entry.objects {
+			if secret != nil {
+				if _, ok := secret.Data[object.BuildID]; !ok {
+					content, err := storage.EncodeToString(object)
+					if err != nil {
+						t.Fatalf("failed to encode storage object: %v", err)
+					}
+					secret.Data[object.BuildID] = []byte(content)
+				}
+			} else {
+				var err error
+				if secret, err = newSecret(entry.appName, object); err != nil {
+					t.Fatalf("failed to create secret: %v", err)
+				}
+			}
+		}
+		mock.secrets[entry.appName] = secret
+	}
+}
+
+// Get returns the Secret by name.
+func (mock *MockSecrets) Get(ctx context.Context, name string, options metav1.GetOptions) (*v1.Secret, error) {
+	secret, ok := mock.secrets[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(testapigroup.Resource("tests"), name)
+	}
+	return secret, nil
+}
+
+// Create creates a new Secret.
+func (mock *MockSecrets) Create(ctx context.Context, secret *v1.Secret, opts metav1.CreateOptions) (*v1.Secret, error) {
+	name := secret.ObjectMeta.Name
+	if object, ok := mock.secrets[name]; ok {
+		return object, apierrors.NewAlreadyExists(testapigroup.Resource("tests"), name)
+	}
+	mock.secrets[name] = secret
+	return secret, nil
+}
+
+// Update updates a Secret.
+func (mock *MockSecrets) Update(ctx context.Context, secret *v1.Secret, opts metav1.UpdateOptions) (*v1.Secret, error) {
+	name := secret.ObjectMeta.Name
+	mock.secrets[name] = secret
+	return secret, nil
+}
+
+// Delete deletes a Secret by name.
+func (mock *MockSecrets) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	if _, ok := mock.secrets[name]; !ok {
+		return apierrors.NewNotFound(testapigroup.Resource("tests"), name)
+	}
+	delete(mock.secrets, name)
+	return nil
+}
+
+// List lists the Secrets matching the label selector.
+func (mock *MockSecrets) List(ctx context.Context, opts metav1.ListOptions) (*v1.SecretList, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	var ls v1.SecretList
+	for _, secret := range mock.secrets {
+		if selector.Matches(labels.Set(secret.Labels)) {
+			ls.Items = append(ls.Items, *secret)
+		}
+	}
+	return &ls, nil
+}
+
+// DeleteCollection deletes the Secrets matching the label selector.
+func (mock *MockSecrets) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	selector, err := labels.Parse(listOpts.LabelSelector)
+	if err != nil {
+		return err
+	}
+	for name, secret := range mock.secrets {
+		if selector.Matches(labels.Set(secret.Labels)) {
+			delete(mock.secrets, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/Azure/draft/pkg/storage"
+)
+
+// openTimeout is how long to wait for another draft process to release the database.
+const openTimeout = 5 * time.Second
+
+// Store is a storage engine keeping draft application builds in an embedded
+// key/value database on the local filesystem.
+//
+// Applications are grouped by the kube context and the namespace they are deployed
+// to, so applications with the same name in different clusters or namespaces keep
+// their own history, as with the cluster storage engines. Each application is a
+// bucket mapping build IDs to the protobuf encoding of a *storage.Object. The
+// database is only held open for the duration of a call so that concurrent draft
+// processes can share it.
+type Store struct {
+	path        string
+	kubeContext string
+	namespace   string
+}
+
+// compile-time guarantee that *Store implements storage.Store
+var _ storage.Store = (*Store)(nil)
+
+// NewStore returns a new Store keeping the draft application builds of a kube context
+// and namespace in the database at path.
+//
+// The database is created on first use. An empty namespace is the "default" namespace.
+func NewStore(path, kubeContext, namespace string) *Store {
+	if namespace == "" {
+		namespace = "default"
+	}
+	return &Store{path: path, kubeContext: kubeContext, namespace: namespace}
+}
+
+// DeleteBuilds deletes all draft builds for the application specified by appName.
+//
+// DeleteBuilds implements storage.Deleter.
+func (s *Store) DeleteBuilds(ctx context.Context, appName string) (builds []*storage.Object, err error) {
+	err = s.update(func(tx *bolt.Tx) error {
+		b := s.app(tx, appName)
+		if b == nil {
+			return storage.NewErrAppStorageNotFound(appName)
+		}
+		if builds, err = decodeAll(b); err != nil {
+			return err
+		}
+		return s.scope(tx).DeleteBucket([]byte(appName))
+	})
+	return builds, err
+}
+
+// DeleteBuild deletes the draft build given by buildID for the application specified by appName.
+//
+// DeleteBuild implements storage.Deleter.
+func (s *Store) DeleteBuild(ctx context.Context, appName, buildID string) (obj *storage.Object, err error) {
+	err = s.update(func(tx *bolt.Tx) error {
+		b := s.app(tx, appName)
+		if b == nil {
+			return storage.NewErrAppStorageNotFound(appName)
+		}
+		if obj, err = get(b, appName, buildID); err != nil {
+			return err
+		}
+		return b.Delete([]byte(buildID))
+	})
+	return obj, err
+}
+
+// CreateBuild creates new storage for the application specified by appName to include build.
+//
+// If storage already exists for the application, ErrAppStorageExists is returned.
+// The build's creation time is set to now unless it is already set.
+//
+// CreateBuild implements storage.Creater.
+func (s *Store) CreateBuild(ctx context.Context, appName string, build *storage.Object) error {
+	return s.update(func(tx *bolt.Tx) error {
+		if s.app(tx, appName) != nil {
+			return storage.NewErrAppStorageExists(appName)
+		}
+		scope, err := s.createScope(tx)
+		if err != nil {
+			return err
+		}
+		b, err := scope.CreateBucket([]byte(appName))
+		if err != nil {
+			return err
+		}
+		return put(b, build)
+	})
+}
+
+// UpdateBuild updates the application storage specified by appName to include build.
+//
+// If build does not exist, a new storage entry is created. Otherwise the existing storage
+// is updated. The build's creation time is set to now unless it is already set.
+//
+// UpdateBuild implements storage.Updater.
+func (s *Store) UpdateBuild(ctx context.Context, appName string, build *storage.Object) error {
+	return s.update(func(tx *bolt.Tx) error {
+		scope, err := s.createScope(tx)
+		if err != nil {
+			return err
+		}
+		b, err := scope.CreateBucketIfNotExists([]byte(appName))
+		if err != nil {
+			return err
+		}
+		if b.Get([]byte(build.BuildID)) != nil {
+			return storage.NewErrAppBuildExists(appName, build.BuildID)
+		}
+		return put(b, build)
+	})
+}
+
+// GetBuilds returns a slice of builds for the given app name.
+//
+// GetBuilds implements storage.Getter.
+func (s *Store) GetBuilds(ctx context.Context, appName string) (builds []*storage.Object, err error) {
+	err = s.view(func(tx *bolt.Tx) error {
+		b := s.app(tx, appName)
+		if b == nil {
+			return storage.NewErrAppStorageNotFound(appName)
+		}
+		builds, err = decodeAll(b)
+		return err
+	})
+	return builds, err
+}
+
+// GetBuild returns the build associated with buildID for the specified app name.
+//
+// GetBuild implements storage.Getter.
+func (s *Store) GetBuild(ctx context.Context, appName, buildID string) (obj *storage.Object, err error) {
+	err = s.view(func(tx *bolt.Tx) error {
+		b := s.app(tx, appName)
+		if b == nil {
+			return storage.NewErrAppStorageNotFound(appName)
+		}
+		obj, err = get(b, appName, buildID)
+		return err
+	})
+	return obj, err
+}
+
+// Apps returns the names of the applications with build storage in the kube context and
+// namespace of the store.
+func (s *Store) Apps(ctx context.Context) (apps []string, err error) {
+	err = s.view(func(tx *bolt.Tx) error {
+		scope := s.scope(tx)
+		if scope == nil {
+			return nil
+		}
+		return scope.ForEach(func(name, _ []byte) error {
+			apps = append(apps, string(name))
+			return nil
+		})
+	})
+	return apps, err
+}
+
+// contextBucket names the bucket of a kube context. The prefix keeps the name of the
+// default context, "", a valid bucket name.
+func (s *Store) contextBucket() []byte {
+	return []byte("context:" + s.kubeContext)
+}
+
+// scope returns the bucket of the applications of the kube context and namespace of the
+// store, or nil if none was stored yet.
+func (s *Store) scope(tx *bolt.Tx) *bolt.Bucket {
+	kctx := tx.Bucket(s.contextBucket())
+	if kctx == nil {
+		return nil
+	}
+	return kctx.Bucket([]byte(s.namespace))
+}
+
+// createScope returns the bucket of the applications of the kube context and namespace of
+// the store, creating it if needed.
+func (s *Store) createScope(tx *bolt.Tx) (*bolt.Bucket, error) {
+	kctx, err := tx.CreateBucketIfNotExists(s.contextBucket())
+	if err != nil {
+		return nil, err
+	}
+	return kctx.CreateBucketIfNotExists([]byte(s.namespace))
+}
+
+// app returns the bucket of an application, or nil if it has no builds stored.
+func (s *Store) app(tx *bolt.Tx, appName string) *bolt.Bucket {
+	scope := s.scope(tx)
+	if scope == nil {
+		return nil
+	}
+	return scope.Bucket([]byte(appName))
+}
+
+func (s *Store) update(fn func(*bolt.Tx) error) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(fn)
+}
+
+func (s *Store) view(fn func(*bolt.Tx) error) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(fn)
+}
+
+func (s *Store) open() (*bolt.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return nil, err
+	}
+	return bolt.Open(s.path, 0644, &bolt.Options{Timeout: openTimeout})
+}
+
+func get(b *bolt.Bucket, appName, buildID string) (*storage.Object, error) {
+	data := b.Get([]byte(buildID))
+	if data == nil {
+		return nil, storage.NewErrAppBuildNotFound(appName, buildID)
+	}
+	var obj storage.Object
+	if err := proto.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	return &obj, nil
+}
+
+func put(b *bolt.Bucket, build *storage.Object) error {
+	if err := storage.SetCreatedAt(build); err != nil {
+		return err
+	}
+	data, err := proto.Marshal(build)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(build.BuildID), data)
+}
+
+func decodeAll(b *bolt.Bucket) (builds []*storage.Object, err error) {
+	err = b.ForEach(func(_, data []byte) error {
+		var obj storage.Object
+		if err := proto.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		builds = append(builds, &obj)
+		return nil
+	})
+	return builds, err
+}
@@ -0,0 +1,94 @@
+package local
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/Azure/draft/pkg/storage"
+	"github.com/Azure/draft/pkg/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		return newTestStore(t)
+	})
+}
+
+func TestStoreApps(t *testing.T) {
+	var (
+		store = newTestStore(t)
+		ctx   = context.Background()
+	)
+	for _, app := range []string{"app2", "app1"} {
+		if err := store.UpdateBuild(ctx, app, &storage.Object{BuildID: "foo"}); err != nil {
+			t.Fatalf("failed to update build: %v", err)
+		}
+	}
+	apps, err := store.Apps(ctx)
+	if err != nil {
+		t.Fatalf("failed to list apps: %v", err)
+	}
+	sort.Strings(apps)
+	if want := []string{"app1", "app2"}; !reflect.DeepEqual(apps, want) {
+		t.Errorf("expected apps %v, got %v", want, apps)
+	}
+}
+
+func TestStorePersists(t *testing.T) {
+	var (
+		store = newTestStore(t)
+		ctx   = context.Background()
+	)
+	if err := store.UpdateBuild(ctx, "app1", &storage.Object{BuildID: "foo"}); err != nil {
+		t.Fatalf("failed to update build: %v", err)
+	}
+	// a second store on the same database sees the build.
+	if _, err := NewStore(store.path, store.kubeContext, store.namespace).GetBuild(ctx, "app1", "foo"); err != nil {
+		t.Fatalf("failed to get build: %v", err)
+	}
+}
+
+func TestStoreScopes(t *testing.T) {
+	var (
+		store = newTestStore(t)
+		ctx   = context.Background()
+	)
+	if err := store.UpdateBuild(ctx, "app1", &storage.Object{BuildID: "foo"}); err != nil {
+		t.Fatalf("failed to update build: %v", err)
+	}
+	// the same application in another namespace or kube context has its own history.
+	for _, other := range []*Store{
+		NewStore(store.path, store.kubeContext, "staging"),
+		NewStore(store.path, "production", store.namespace),
+	} {
+		if _, err := other.GetBuilds(ctx, "app1"); err == nil {
+			t.Errorf("expected no builds for app1 in %s/%s", other.kubeContext, other.namespace)
+		}
+		if err := other.UpdateBuild(ctx, "app1", &storage.Object{BuildID: "foo"}); err != nil {
+			t.Errorf("expected build foo to be stored in %s/%s: %v", other.kubeContext, other.namespace, err)
+		}
+		if apps, err := other.Apps(ctx); err != nil || !reflect.DeepEqual(apps, []string{"app1"}) {
+			t.Errorf("expected apps [app1] in %s/%s, got %v and %v", other.kubeContext, other.namespace, apps, err)
+		}
+	}
+	if _, err := store.DeleteBuilds(ctx, "app1"); err != nil {
+		t.Fatalf("failed to delete builds: %v", err)
+	}
+	if _, err := NewStore(store.path, "production", store.namespace).GetBuild(ctx, "app1", "foo"); err != nil {
+		t.Errorf("expected the build of another kube context to be kept: %v", err)
+	}
+}
+
+func newTestStore(t *testing.T) *Store {
+	dir, err := ioutil.TempDir("", "draft-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return NewStore(filepath.Join(dir, "storage", "builds.db"), "", "default")
+}
@@ -0,0 +1,307 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// LogsHeritage is the heritage label value of cluster objects holding build logs.
+	LogsHeritage = "draft-logs"
+	// BuildIDLabel is the label key holding the build ID of cluster objects holding build logs.
+	BuildIDLabel = "buildid"
+
+	// LogChunkAnnotation is the annotation key holding the chunk index of cluster objects holding build logs.
+	LogChunkAnnotation = "draft.sh/log-chunk"
+	// LogFinalAnnotation is the annotation key marking the cluster object holding the final chunk of build logs.
+	LogFinalAnnotation = "draft.sh/log-final"
+	// LogDataKey is the data key of cluster objects holding build logs.
+	LogDataKey = "log.gz"
+
+	// LogChunkSize is the amount of uncompressed build logs stored in a chunk. Only the
+	// last chunk of a build holds less.
+	LogChunkSize = 256 * 1024
+	// LogFlushInterval is how often the last chunk of a build in progress is updated with
+	// the logs written since, so that the build can be followed.
+	LogFlushInterval = 2 * time.Second
+)
+
+// LogChunk is a gzip compressed piece of the build logs of a build.
+//
+// Every chunk holds LogChunkSize bytes of uncompressed logs but the last one, which is
+// replaced with a longer version of itself as the build goes on.
+type LogChunk struct {
+	// Index orders the chunks of a build, starting at 0.
+	Index int
+	// Data is the gzip compressed content of the chunk.
+	Data []byte
+	// Final is true for the last chunk of a build's logs.
+	Final bool
+}
+
+// LogStore represents a storage engine for build logs, so that the logs of a build
+// can be read from any machine with access to the storage engine.
+type LogStore interface {
+	// PutLogChunk stores a chunk of the build logs of the build given by buildID,
+	// replacing the chunk with the same index if it is stored already.
+	PutLogChunk(ctx context.Context, appName, buildID string, chunk *LogChunk) error
+	// GetLogChunks retrieves the chunks of the build logs of the build given by buildID
+	// with an index of at least from, ordered by index.
+	GetLogChunks(ctx context.Context, appName, buildID string, from int) ([]*LogChunk, error)
+	// DeleteLogs deletes the build logs of the build given by buildID.
+	DeleteLogs(ctx context.Context, appName, buildID string) error
+}
+
+// LogLabels returns the labels attached to cluster objects storing the build logs
+// of the build given by buildID.
+func LogLabels(appName, buildID string) map[string]string {
+	return map[string]string{
+		HeritageLabel: LogsHeritage,
+		AppNameLabel:  appName,
+		BuildIDLabel:  buildID,
+	}
+}
+
+// LogSelector returns the label selector matching the cluster objects storing the
+// build logs of the build given by buildID.
+func LogSelector(appName, buildID string) string {
+	return fmt.Sprintf("%s=%s,%s=%s,%s=%s", HeritageLabel, LogsHeritage, AppNameLabel, appName, BuildIDLabel, buildID)
+}
+
+// LogObjectName returns the name of the cluster object storing a chunk of build logs.
+//
+// Build IDs are ULIDs; they are lowercased to form a valid kubernetes object name.
+func LogObjectName(appName, buildID string, index int) string {
+	return fmt.Sprintf("%s-logs-%s-%d", appName, strings.ToLower(buildID), index)
+}
+
+// LogAnnotations returns the annotations attached to the cluster object storing chunk.
+func LogAnnotations(chunk *LogChunk) map[string]string {
+	return map[string]string{
+		LogChunkAnnotation: strconv.Itoa(chunk.Index),
+		LogFinalAnnotation: strconv.FormatBool(chunk.Final),
+	}
+}
+
+// NewLogChunk returns the chunk of build logs stored in a cluster object with the
+// given annotations and data.
+func NewLogChunk(annotations map[string]string, data []byte) (*LogChunk, error) {
+	index, err := strconv.Atoi(annotations[LogChunkAnnotation])
+	if err != nil {
+		return nil, fmt.Errorf("invalid build log chunk index: %v", err)
+	}
+	final, _ := strconv.ParseBool(annotations[LogFinalAnnotation])
+	return &LogChunk{Index: index, Data: data, Final: final}, nil
+}
+
+// SortLogChunks sorts chunks by index.
+func SortLogChunks(chunks []*LogChunk) {
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].Index < chunks[j].Index })
+}
+
+// LogWriter stores the build logs written to it as compressed chunks in a LogStore.
+//
+// Chunks are stored in the background so that writes never wait for the storage engine:
+// every LogFlushInterval, the last chunk is replaced with the logs written since, and a new
+// chunk is started once it holds LogChunkSize bytes. A build therefore has one stored chunk
+// per LogChunkSize bytes of logs. Close stores the remaining logs and marks the last chunk
+// as final.
+type LogWriter struct {
+	ctx     context.Context
+	store   LogStore
+	appName string
+	buildID string
+
+	mu sync.Mutex
+	// buf holds the logs of the last chunk and the logs written after it.
+	buf bytes.Buffer
+	// index is the index of the last chunk.
+	index int
+	// stored is how many bytes of the last chunk are stored.
+	stored int
+	err    error
+
+	full chan struct{}
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewLogWriter returns a LogWriter storing the build logs of the build given by buildID.
+func NewLogWriter(ctx context.Context, store LogStore, appName, buildID string) *LogWriter {
+	w := &LogWriter{
+		ctx:     ctx,
+		store:   store,
+		appName: appName,
+		buildID: buildID,
+		full:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	w.wg.Add(1)
+	go w.storeChunks()
+	return w
+}
+
+// Write buffers p, to be stored in the background. It fails once storing a chunk failed.
+//
+// Write implements io.Writer.
+func (w *LogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, _ := w.buf.Write(p)
+	if w.buf.Len() >= LogChunkSize {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return n, nil
+}
+
+// Close stores the remaining logs, marking the last chunk as final, and returns the first
+// error encountered while storing chunks.
+//
+// Close implements io.Closer.
+func (w *LogWriter) Close() error {
+	close(w.done)
+	w.wg.Wait()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+func (w *LogWriter) storeChunks() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(LogFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			w.flush(true)
+			return
+		case <-ticker.C:
+		case <-w.full:
+		}
+		w.flush(false)
+	}
+}
+
+// flush stores the chunks full of logs and the logs of the last chunk not stored yet. With
+// final, the last chunk is stored as final, even if it is empty. Only storeChunks calls it,
+// and w.mu is not held while talking to the storage engine.
+func (w *LogWriter) flush(final bool) {
+	for {
+		w.mu.Lock()
+		if w.err != nil {
+			w.mu.Unlock()
+			return
+		}
+		logs := w.buf.Bytes()
+		full := len(logs) >= LogChunkSize
+		if full {
+			logs = logs[:LogChunkSize]
+		}
+		last := final && w.buf.Len() <= LogChunkSize
+		if !full && !last && len(logs) == w.stored {
+			w.mu.Unlock()
+			return
+		}
+		// Write only appends to buf, so the logs of the chunk are left as they are.
+		chunk := &LogChunk{Index: w.index, Final: last}
+		w.mu.Unlock()
+
+		err := w.put(chunk, logs)
+
+		w.mu.Lock()
+		switch {
+		case err != nil:
+			w.err = err
+		case full && !last:
+			w.buf.Next(LogChunkSize)
+			w.index++
+			w.stored = 0
+		default:
+			w.stored = len(logs)
+		}
+		w.mu.Unlock()
+		if err != nil || last {
+			return
+		}
+	}
+}
+
+func (w *LogWriter) put(chunk *LogChunk, logs []byte) error {
+	var data bytes.Buffer
+	zw := gzip.NewWriter(&data)
+	if _, err := zw.Write(logs); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	chunk.Data = data.Bytes()
+	return w.store.PutLogChunk(w.ctx, w.appName, w.buildID, chunk)
+}
+
+// LogPosition is how far the build logs of a build were read: the index of the next chunk
+// to read and how many bytes of it were read already.
+type LogPosition struct {
+	Chunk  int
+	Offset int
+}
+
+// CopyLogs writes the decompressed build logs of the build given by buildID to w,
+// starting at position from.
+//
+// CopyLogs returns the position to read the logs written next from and whether the final
+// chunk has been read. An error is returned if no logs are stored for the build.
+func CopyLogs(ctx context.Context, w io.Writer, store LogStore, appName, buildID string, from LogPosition) (next LogPosition, final bool, err error) {
+	chunks, err := store.GetLogChunks(ctx, appName, buildID, from.Chunk)
+	if err != nil {
+		return from, false, err
+	}
+	if from == (LogPosition{}) && len(chunks) == 0 {
+		return from, false, NewErrAppBuildLogsNotFound(appName, buildID)
+	}
+	next = from
+	for _, chunk := range chunks {
+		// stop at a gap; the missing chunk is still being stored.
+		if chunk.Index != next.Chunk {
+			break
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(chunk.Data))
+		if err != nil {
+			return next, false, err
+		}
+		b, err := ioutil.ReadAll(zr)
+		if err != nil {
+			return next, false, err
+		}
+		if next.Offset < len(b) {
+			if _, err := w.Write(b[next.Offset:]); err != nil {
+				return next, false, err
+			}
+		}
+		if chunk.Final {
+			return LogPosition{Chunk: chunk.Index + 1}, true, nil
+		}
+		if len(b) < LogChunkSize {
+			// the last chunk of a build in progress grows until it is full.
+			next.Offset = len(b)
+			break
+		}
+		next = LogPosition{Chunk: chunk.Index + 1}
+	}
+	return next, false, nil
+}
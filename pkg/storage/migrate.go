@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+)
+
+// Migrate moves the builds of the application specified by appName from one storage
+// engine to another, returning the builds that were copied.
+//
+// Builds already present in the destination are left untouched and keep their original
+// creation time. The source storage is only removed once every build has been copied.
+func Migrate(ctx context.Context, from, to Store, appName string) ([]*Object, error) {
+	builds, err := from.GetBuilds(ctx, appName)
+	if err != nil {
+		return nil, err
+	}
+	var copied []*Object
+	for _, build := range builds {
+		if _, err := to.GetBuild(ctx, appName, build.BuildID); err == nil {
+			continue
+		}
+		if err := to.UpdateBuild(ctx, appName, build); err != nil {
+			return copied, err
+		}
+		copied = append(copied, build)
+	}
+	if _, err := from.DeleteBuilds(ctx, appName); err != nil {
+		return copied, err
+	}
+	return copied, nil
+}
@@ -0,0 +1,52 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/Azure/draft/pkg/storage"
+	"github.com/Azure/draft/pkg/storage/inprocess"
+)
+
+func TestMigrate(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		from      = inprocess.NewStore()
+		to        = inprocess.NewStore()
+		createdAt = ptypes.TimestampNow()
+	)
+	for _, id := range []string{"foo1", "foo2", "foo3"} {
+		if err := from.UpdateBuild(ctx, "app1", &storage.Object{BuildID: id, CreatedAt: createdAt}); err != nil {
+			t.Fatalf("failed to store build: %v", err)
+		}
+	}
+	// foo1 was already migrated.
+	if err := to.UpdateBuild(ctx, "app1", &storage.Object{BuildID: "foo1"}); err != nil {
+		t.Fatalf("failed to store build: %v", err)
+	}
+
+	copied, err := storage.Migrate(ctx, from, to, "app1")
+	if err != nil {
+		t.Fatalf("failed to migrate builds: %v", err)
+	}
+	if len(copied) != 2 {
+		t.Errorf("expected 2 copied builds, got %d", len(copied))
+	}
+	builds, err := to.GetBuilds(ctx, "app1")
+	if err != nil {
+		t.Fatalf("failed to get builds: %v", err)
+	}
+	if len(builds) != 3 {
+		t.Errorf("expected 3 builds in destination, got %d", len(builds))
+	}
+	for _, build := range copied {
+		if build.CreatedAt != createdAt {
+			t.Errorf("expected build %s to keep its creation time", build.BuildID)
+		}
+	}
+	if _, err := from.GetBuilds(ctx, "app1"); err == nil {
+		t.Error("expected source storage to be removed")
+	}
+}
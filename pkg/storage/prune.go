@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+)
+
+// RetentionPolicy describes which builds of an application are kept in storage.
+type RetentionPolicy struct {
+	// Keep is the number of most recent builds that are always kept.
+	Keep int
+	// OlderThan restricts pruning to builds created before now minus OlderThan.
+	// Zero prunes every build beyond the Keep most recent ones.
+	OlderThan time.Duration
+}
+
+// Prunable returns the builds the retention policy removes, oldest first.
+func (p RetentionPolicy) Prunable(builds []*Object, now time.Time) []*Object {
+	sorted := make([]*Object, len(builds))
+	copy(sorted, builds)
+	SortByCreatedAt(sorted)
+
+	var prunable []*Object
+	cutoff := now.Add(-p.OlderThan)
+	for i := 0; i < len(sorted)-p.Keep; i++ {
+		if p.OlderThan > 0 {
+			created, err := ptypes.Timestamp(sorted[i].GetCreatedAt())
+			if err != nil || !created.Before(cutoff) {
+				continue
+			}
+		}
+		prunable = append(prunable, sorted[i])
+	}
+	return prunable
+}
+
+// Prune deletes the builds of the application specified by appName that the retention
+// policy removes, returning the deleted builds.
+func Prune(ctx context.Context, store Store, appName string, policy RetentionPolicy) ([]*Object, error) {
+	builds, err := store.GetBuilds(ctx, appName)
+	if err != nil {
+		return nil, err
+	}
+	var pruned []*Object
+	for _, build := range policy.Prunable(builds, time.Now()) {
+		obj, err := store.DeleteBuild(ctx, appName, build.BuildID)
+		if err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, obj)
+	}
+	return pruned, nil
+}
@@ -0,0 +1,73 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/Azure/draft/pkg/storage"
+	"github.com/Azure/draft/pkg/storage/inprocess"
+)
+
+func TestRetentionPolicyPrunable(t *testing.T) {
+	now := time.Date(2018, time.March, 31, 0, 0, 0, 0, time.UTC)
+	// one build per day in March, passed in reverse order.
+	var builds []*storage.Object
+	for day := 30; day >= 1; day-- {
+		createdAt, _ := ptypes.TimestampProto(time.Date(2018, time.March, day, 0, 0, 0, 0, time.UTC))
+		builds = append(builds, &storage.Object{BuildID: fmt.Sprintf("%02d", day), CreatedAt: createdAt})
+	}
+
+	testCases := []struct {
+		policy   storage.RetentionPolicy
+		expected []string
+	}{
+		{storage.RetentionPolicy{Keep: 30}, nil},
+		{storage.RetentionPolicy{Keep: 27}, []string{"01", "02", "03"}},
+		{storage.RetentionPolicy{OlderThan: 28 * 24 * time.Hour}, []string{"01", "02"}},
+		{storage.RetentionPolicy{Keep: 29, OlderThan: 28 * 24 * time.Hour}, []string{"01"}},
+		{storage.RetentionPolicy{Keep: 10, OlderThan: 100 * 24 * time.Hour}, nil},
+	}
+
+	for _, tc := range testCases {
+		var ids []string
+		for _, b := range tc.policy.Prunable(builds, now) {
+			ids = append(ids, b.BuildID)
+		}
+		if !reflect.DeepEqual(ids, tc.expected) {
+			t.Errorf("%+v: expected %v, got %v", tc.policy, tc.expected, ids)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = inprocess.NewStore()
+	)
+	for i := 0; i < 5; i++ {
+		createdAt, _ := ptypes.TimestampProto(time.Now().Add(time.Duration(i) * time.Minute))
+		if err := store.UpdateBuild(ctx, "app1", &storage.Object{BuildID: fmt.Sprint(i), CreatedAt: createdAt}); err != nil {
+			t.Fatalf("failed to store build: %v", err)
+		}
+	}
+
+	pruned, err := storage.Prune(ctx, store, "app1", storage.RetentionPolicy{Keep: 2})
+	if err != nil {
+		t.Fatalf("failed to prune builds: %v", err)
+	}
+	if len(pruned) != 3 {
+		t.Errorf("expected 3 pruned builds, got %d", len(pruned))
+	}
+	builds, err := store.GetBuilds(ctx, "app1")
+	if err != nil {
+		t.Fatalf("failed to get builds: %v", err)
+	}
+	if len(builds) != 2 || builds[0].BuildID != "3" || builds[1].BuildID != "4" {
+		t.Errorf("expected the 2 most recent builds to be kept, got %v", builds)
+	}
+}
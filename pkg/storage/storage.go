@@ -8,8 +8,17 @@ package storage
 import (
 	"context"
 	b64 "encoding/base64"
+	"time"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+)
+
+const (
+	// HeritageLabel is the label key marking cluster objects managed by Draft storage.
+	HeritageLabel = "heritage"
+	// AppNameLabel is the label key holding the application name of cluster storage objects.
+	AppNameLabel = "appname"
 )
 
 // Deleter represents the delete APIs of the storage engine.
@@ -73,3 +82,26 @@ func DecodeString(str string) (*Object, error) {
 	}
 	return &obj, nil
 }
+
+// SetCreatedAt sets the build's creation time to now unless it is already set,
+// so that builds copied between storage engines keep their original timestamp.
+func SetCreatedAt(build *Object) error {
+	if build.CreatedAt != nil {
+		return nil
+	}
+	now, err := ptypes.TimestampProto(time.Now())
+	if err != nil {
+		return err
+	}
+	build.CreatedAt = now
+	return nil
+}
+
+// Labels returns the labels attached to cluster objects storing builds of the
+// application specified by appName.
+func Labels(appName string) map[string]string {
+	return map[string]string{
+		HeritageLabel: "draft",
+		AppNameLabel:  appName,
+	}
+}
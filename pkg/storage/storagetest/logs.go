@@ -0,0 +1,208 @@
+package storagetest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Azure/draft/pkg/storage"
+)
+
+// LogStoreFactory returns a new, empty storage.LogStore for a single test.
+type LogStoreFactory func(t *testing.T) storage.LogStore
+
+// RunLogStore runs the conformance test suite against the log storage engine returned by newStore.
+func RunLogStore(t *testing.T, newStore LogStoreFactory) {
+	for _, tc := range []struct {
+		name string
+		test func(*testing.T, storage.LogStore)
+	}{
+		{"GetLogChunks", testGetLogChunks},
+		{"GetLogChunksNotFound", testGetLogChunksNotFound},
+		{"DeleteLogs", testDeleteLogs},
+		{"LogIsolation", testLogIsolation},
+		{"ReplaceLogChunk", testReplaceLogChunk},
+		{"CopyLogsTail", testCopyLogsTail},
+		{"LogWriter", testLogWriter},
+		{"LogWriterSmallLogs", testLogWriterSmallLogs},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, newStore(t))
+		})
+	}
+}
+
+func testGetLogChunks(t *testing.T, store storage.LogStore) {
+	ctx := context.Background()
+	for _, chunk := range []*storage.LogChunk{
+		{Index: 0, Data: []byte("a")},
+		{Index: 1, Data: []byte("b")},
+		{Index: 2, Data: []byte("c"), Final: true},
+	} {
+		if err := store.PutLogChunk(ctx, "app", "01ABC", chunk); err != nil {
+			t.Fatalf("failed to store log chunk: %v", err)
+		}
+	}
+	chunks, err := store.GetLogChunks(ctx, "app", "01ABC", 1)
+	if err != nil {
+		t.Fatalf("failed to get log chunks: %v", err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("expected 2 log chunks, got %d", len(chunks))
+	}
+	for i, want := range []string{"b", "c"} {
+		if got := string(chunks[i].Data); got != want {
+			t.Errorf("expected chunk %d to be %q, got %q", i, want, got)
+		}
+	}
+	if chunks[0].Index != 1 || chunks[0].Final || !chunks[1].Final {
+		t.Errorf("unexpected chunk metadata: %+v, %+v", chunks[0], chunks[1])
+	}
+}
+
+func testGetLogChunksNotFound(t *testing.T, store storage.LogStore) {
+	chunks, err := store.GetLogChunks(context.Background(), "app", "01ABC", 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(chunks) != 0 {
+		t.Errorf("expected no log chunks, got %d", len(chunks))
+	}
+}
+
+func testDeleteLogs(t *testing.T, store storage.LogStore) {
+	ctx := context.Background()
+	if err := store.PutLogChunk(ctx, "app", "01ABC", &storage.LogChunk{Data: []byte("a")}); err != nil {
+		t.Fatalf("failed to store log chunk: %v", err)
+	}
+	if err := store.DeleteLogs(ctx, "app", "01ABC"); err != nil {
+		t.Fatalf("failed to delete logs: %v", err)
+	}
+	chunks, err := store.GetLogChunks(ctx, "app", "01ABC", 0)
+	if err != nil {
+		t.Fatalf("failed to get log chunks: %v", err)
+	}
+	if len(chunks) != 0 {
+		t.Errorf("expected no log chunks after delete, got %d", len(chunks))
+	}
+}
+
+func testLogIsolation(t *testing.T, store storage.LogStore) {
+	ctx := context.Background()
+	for _, build := range []string{"01ABC", "01DEF"} {
+		if err := store.PutLogChunk(ctx, "app", build, &storage.LogChunk{Data: []byte(build)}); err != nil {
+			t.Fatalf("failed to store log chunk: %v", err)
+		}
+	}
+	if err := store.DeleteLogs(ctx, "app", "01ABC"); err != nil {
+		t.Fatalf("failed to delete logs: %v", err)
+	}
+	chunks, err := store.GetLogChunks(ctx, "app", "01DEF", 0)
+	if err != nil {
+		t.Fatalf("failed to get log chunks: %v", err)
+	}
+	if len(chunks) != 1 || string(chunks[0].Data) != "01DEF" {
+		t.Errorf("expected the logs of build 01DEF to be kept, got %v", chunks)
+	}
+}
+
+func testReplaceLogChunk(t *testing.T, store storage.LogStore) {
+	ctx := context.Background()
+	for _, data := range []string{"a", "ab"} {
+		if err := store.PutLogChunk(ctx, "app", "01ABC", &storage.LogChunk{Data: []byte(data)}); err != nil {
+			t.Fatalf("failed to store log chunk: %v", err)
+		}
+	}
+	chunks, err := store.GetLogChunks(ctx, "app", "01ABC", 0)
+	if err != nil {
+		t.Fatalf("failed to get log chunks: %v", err)
+	}
+	if len(chunks) != 1 || string(chunks[0].Data) != "ab" {
+		t.Errorf("expected the log chunk to be replaced, got %v", chunks)
+	}
+}
+
+func testCopyLogsTail(t *testing.T, store storage.LogStore) {
+	ctx := context.Background()
+	put := func(logs string, final bool) {
+		var data bytes.Buffer
+		zw := gzip.NewWriter(&data)
+		zw.Write([]byte(logs))
+		zw.Close()
+		if err := store.PutLogChunk(ctx, "app", "01ABC", &storage.LogChunk{Data: data.Bytes(), Final: final}); err != nil {
+			t.Fatalf("failed to store log chunk: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	put("step 1\n", false)
+	next, final, err := storage.CopyLogs(ctx, &buf, store, "app", "01ABC", storage.LogPosition{})
+	if err != nil || final {
+		t.Fatalf("expected the logs of a build in progress, got final %v and %v", final, err)
+	}
+	if want := (storage.LogPosition{Chunk: 0, Offset: 7}); next != want {
+		t.Errorf("expected position %+v, got %+v", want, next)
+	}
+	// the last chunk grows in place; only the logs written since are copied.
+	put("step 1\nstep 2\n", true)
+	if next, final, err = storage.CopyLogs(ctx, &buf, store, "app", "01ABC", next); err != nil || !final {
+		t.Fatalf("expected the final chunk to be read, got final %v and %v", final, err)
+	}
+	if buf.String() != "step 1\nstep 2\n" {
+		t.Errorf("expected each line once, got %q", buf.String())
+	}
+}
+
+func testLogWriter(t *testing.T, store storage.LogStore) {
+	ctx := context.Background()
+	logs := strings.Repeat("building...\n", storage.LogChunkSize*5/2/12)
+
+	w := storage.NewLogWriter(ctx, store, "app", "01ABC")
+	if _, err := w.Write([]byte(logs)); err != nil {
+		t.Fatalf("failed to write logs: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close log writer: %v", err)
+	}
+
+	var buf bytes.Buffer
+	next, final, err := storage.CopyLogs(ctx, &buf, store, "app", "01ABC", storage.LogPosition{})
+	if err != nil {
+		t.Fatalf("failed to copy logs: %v", err)
+	}
+	if !final {
+		t.Error("expected the final chunk to be read")
+	}
+	if next.Chunk != 3 {
+		t.Errorf("expected 3 chunks to be read, got %d", next.Chunk)
+	}
+	if buf.String() != logs {
+		t.Errorf("expected %d bytes of logs, got %d", len(logs), buf.Len())
+	}
+	if _, _, err := storage.CopyLogs(ctx, &buf, store, "app", "01DEF", storage.LogPosition{}); err == nil {
+		t.Error("expected an error copying the logs of an unknown build")
+	}
+}
+
+func testLogWriterSmallLogs(t *testing.T, store storage.LogStore) {
+	ctx := context.Background()
+	w := storage.NewLogWriter(ctx, store, "app", "01ABC")
+	for i := 0; i < 100; i++ {
+		if _, err := w.Write([]byte("building...\n")); err != nil {
+			t.Fatalf("failed to write logs: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close log writer: %v", err)
+	}
+	chunks, err := store.GetLogChunks(ctx, "app", "01ABC", 0)
+	if err != nil {
+		t.Fatalf("failed to get log chunks: %v", err)
+	}
+	if len(chunks) != 1 || !chunks[0].Final {
+		t.Errorf("expected a single final log chunk, got %d", len(chunks))
+	}
+}
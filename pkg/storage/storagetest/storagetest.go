@@ -0,0 +1,255 @@
+// Package storagetest provides a conformance test suite for storage.Store implementations.
+//
+// Every storage engine runs the suite from its own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Store { return NewStore() })
+//	}
+package storagetest
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/Azure/draft/pkg/storage"
+)
+
+// Factory returns a new, empty storage.Store for a single test.
+type Factory func(t *testing.T) storage.Store
+
+// Run runs the conformance test suite against the storage engine returned by newStore.
+func Run(t *testing.T, newStore Factory) {
+	for _, tc := range []struct {
+		name string
+		test func(*testing.T, storage.Store)
+	}{
+		{"CreateBuild", testCreateBuild},
+		{"CreateBuildExists", testCreateBuildExists},
+		{"UpdateBuild", testUpdateBuild},
+		{"UpdateBuildExists", testUpdateBuildExists},
+		{"CreatedAt", testCreatedAt},
+		{"GetBuildsNotFound", testGetBuildsNotFound},
+		{"GetBuildNotFound", testGetBuildNotFound},
+		{"DeleteBuild", testDeleteBuild},
+		{"DeleteBuildNotFound", testDeleteBuildNotFound},
+		{"DeleteBuilds", testDeleteBuilds},
+		{"Isolation", testIsolation},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, newStore(t))
+		})
+	}
+}
+
+func testCreateBuild(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	build := objectStub("foo1")
+	if err := store.CreateBuild(ctx, "app1", build); err != nil {
+		t.Fatalf("failed to create build: %v", err)
+	}
+	got, err := store.GetBuild(ctx, "app1", "foo1")
+	if err != nil {
+		t.Fatalf("failed to get build: %v", err)
+	}
+	assertEqual(t, got, build)
+}
+
+func testCreateBuildExists(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	if err := store.CreateBuild(ctx, "app1", objectStub("foo1")); err != nil {
+		t.Fatalf("failed to create build: %v", err)
+	}
+	if err := store.CreateBuild(ctx, "app1", objectStub("foo2")); err == nil {
+		t.Fatal("expected CreateBuild to fail when storage for the application exists")
+	}
+}
+
+func testUpdateBuild(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	ids := []string{"foo1", "foo2", "foo3"}
+	// the first update creates the application storage.
+	for _, id := range ids {
+		if err := store.UpdateBuild(ctx, "app1", objectStub(id)); err != nil {
+			t.Fatalf("failed to update build %s: %v", id, err)
+		}
+	}
+	builds, err := store.GetBuilds(ctx, "app1")
+	if err != nil {
+		t.Fatalf("failed to get builds: %v", err)
+	}
+	assertBuildIDs(t, builds, ids...)
+}
+
+func testUpdateBuildExists(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	if err := store.UpdateBuild(ctx, "app1", objectStub("foo1")); err != nil {
+		t.Fatalf("failed to update build: %v", err)
+	}
+	if err := store.UpdateBuild(ctx, "app1", objectStub("foo1")); err == nil {
+		t.Fatal("expected UpdateBuild to fail when the build exists")
+	}
+}
+
+func testCreatedAt(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	before := time.Now().Add(-time.Second)
+	fresh := objectStub("foo1")
+	if err := store.UpdateBuild(ctx, "app1", fresh); err != nil {
+		t.Fatalf("failed to update build: %v", err)
+	}
+	got, err := store.GetBuild(ctx, "app1", "foo1")
+	if err != nil {
+		t.Fatalf("failed to get build: %v", err)
+	}
+	if ts, err := ptypes.Timestamp(got.GetCreatedAt()); err != nil || ts.Before(before) {
+		t.Errorf("expected creation time to be set to now, got %v", got.GetCreatedAt())
+	}
+
+	// builds copied from another store keep their creation time.
+	createdAt, _ := ptypes.TimestampProto(time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC))
+	copied := objectStub("foo2")
+	copied.CreatedAt = createdAt
+	if err := store.UpdateBuild(ctx, "app1", copied); err != nil {
+		t.Fatalf("failed to update build: %v", err)
+	}
+	if got, err = store.GetBuild(ctx, "app1", "foo2"); err != nil {
+		t.Fatalf("failed to get build: %v", err)
+	}
+	if !proto.Equal(got.GetCreatedAt(), createdAt) {
+		t.Errorf("expected creation time %v to be kept, got %v", createdAt, got.GetCreatedAt())
+	}
+}
+
+func testGetBuildsNotFound(t *testing.T, store storage.Store) {
+	if builds, err := store.GetBuilds(context.Background(), "missing"); err == nil {
+		t.Fatalf("expected GetBuilds to fail for unknown application, got %v", builds)
+	}
+}
+
+func testGetBuildNotFound(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	if _, err := store.GetBuild(ctx, "missing", "foo1"); err == nil {
+		t.Fatal("expected GetBuild to fail for unknown application")
+	}
+	if err := store.UpdateBuild(ctx, "app1", objectStub("foo1")); err != nil {
+		t.Fatalf("failed to update build: %v", err)
+	}
+	if _, err := store.GetBuild(ctx, "app1", "missing"); err == nil {
+		t.Fatal("expected GetBuild to fail for unknown build")
+	}
+}
+
+func testDeleteBuild(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	for _, id := range []string{"foo1", "foo2"} {
+		if err := store.UpdateBuild(ctx, "app1", objectStub(id)); err != nil {
+			t.Fatalf("failed to update build %s: %v", id, err)
+		}
+	}
+	deleted, err := store.DeleteBuild(ctx, "app1", "foo1")
+	if err != nil {
+		t.Fatalf("failed to delete build: %v", err)
+	}
+	if deleted.GetBuildID() != "foo1" {
+		t.Errorf("expected deleted build foo1, got %q", deleted.GetBuildID())
+	}
+	if _, err := store.GetBuild(ctx, "app1", "foo1"); err == nil {
+		t.Error("expected deleted build to be gone")
+	}
+	builds, err := store.GetBuilds(ctx, "app1")
+	if err != nil {
+		t.Fatalf("failed to get builds: %v", err)
+	}
+	assertBuildIDs(t, builds, "foo2")
+}
+
+func testDeleteBuildNotFound(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	if _, err := store.DeleteBuild(ctx, "missing", "foo1"); err == nil {
+		t.Fatal("expected DeleteBuild to fail for unknown application")
+	}
+	if err := store.UpdateBuild(ctx, "app1", objectStub("foo1")); err != nil {
+		t.Fatalf("failed to update build: %v", err)
+	}
+	if _, err := store.DeleteBuild(ctx, "app1", "missing"); err == nil {
+		t.Fatal("expected DeleteBuild to fail for unknown build")
+	}
+}
+
+func testDeleteBuilds(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	for _, id := range []string{"foo1", "foo2"} {
+		if err := store.UpdateBuild(ctx, "app1", objectStub(id)); err != nil {
+			t.Fatalf("failed to update build %s: %v", id, err)
+		}
+	}
+	deleted, err := store.DeleteBuilds(ctx, "app1")
+	if err != nil {
+		t.Fatalf("failed to delete builds: %v", err)
+	}
+	assertBuildIDs(t, deleted, "foo1", "foo2")
+	if builds, err := store.GetBuilds(ctx, "app1"); err == nil {
+		t.Errorf("expected application storage to be gone, got %v", builds)
+	}
+	if _, err := store.DeleteBuilds(ctx, "app1"); err == nil {
+		t.Error("expected DeleteBuilds to fail for unknown application")
+	}
+}
+
+func testIsolation(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	if err := store.UpdateBuild(ctx, "app1", objectStub("foo1")); err != nil {
+		t.Fatalf("failed to update build: %v", err)
+	}
+	if err := store.UpdateBuild(ctx, "app2", objectStub("foo2")); err != nil {
+		t.Fatalf("failed to update build: %v", err)
+	}
+	if _, err := store.DeleteBuilds(ctx, "app2"); err != nil {
+		t.Fatalf("failed to delete builds: %v", err)
+	}
+	builds, err := store.GetBuilds(ctx, "app1")
+	if err != nil {
+		t.Fatalf("failed to get builds: %v", err)
+	}
+	assertBuildIDs(t, builds, "foo1")
+}
+
+func objectStub(buildID string) *storage.Object {
+	return &storage.Object{
+		BuildID:     buildID,
+		Release:     "release-" + buildID,
+		ContextID:   []byte("context-" + buildID),
+		LogsFileRef: "/logs/" + buildID,
+	}
+}
+
+func assertEqual(t *testing.T, got, want *storage.Object) {
+	t.Helper()
+	if !proto.Equal(got, want) {
+		t.Errorf("expected build %v, got %v", want, got)
+	}
+}
+
+func assertBuildIDs(t *testing.T, builds []*storage.Object, want ...string) {
+	t.Helper()
+	var got []string
+	for _, b := range builds {
+		got = append(got, b.GetBuildID())
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if len(got) != len(want) {
+		t.Fatalf("expected builds %v, got %v", want, got)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("expected builds %v, got %v", want, got)
+		}
+	}
+}
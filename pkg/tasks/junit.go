@@ -0,0 +1,95 @@
+package tasks
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// junitTestSuites is the root element of a JUnit XML report.
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes results as a JUnit XML report, with a test suite by kind of tasks and
+// a test case by task. Post-deploy tasks are reported once per pod.
+func WriteJUnit(w io.Writer, results []Result) error {
+	var (
+		report junitTestSuites
+		suites = make(map[string]int)
+		totals []float64
+	)
+	for _, r := range results {
+		key := KeyOf(r.Kind)
+		i, ok := suites[key]
+		if !ok {
+			i = len(report.Suites)
+			suites[key] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: key})
+			totals = append(totals, 0)
+		}
+		totals[i] += r.Duration.Seconds()
+		suite := &report.Suites[i]
+
+		c := junitTestCase{
+			Name:      r.Name,
+			ClassName: key,
+			Time:      seconds(r.Duration.Seconds()),
+			SystemOut: r.Output,
+		}
+		if r.Pod != "" {
+			c.ClassName = key + "." + r.Pod
+		}
+		switch {
+		case r.Skipped:
+			c.Skipped = &junitMessage{Message: r.Message}
+			suite.Skipped++
+		case !r.Pass:
+			c.Failure = &junitMessage{Message: r.Message}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, c)
+	}
+	for i := range report.Suites {
+		report.Suites[i].Time = seconds(totals[i])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(s float64) string {
+	return fmt.Sprintf("%.3f", s)
+}
@@ -0,0 +1,42 @@
+package tasks
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	results := []Result{
+		{Kind: PostDeploy, Name: "migrate", Pod: "web-1", Pass: true, Duration: 1500 * time.Millisecond, Output: "migrated <3 tables>\n"},
+		{Kind: PostDeploy, Name: "smoke", Pod: "web-1", Message: "command terminated with exit code 1", ExitCode: 1, Duration: 250 * time.Millisecond},
+		{Kind: PostDeploy, Name: "report", Pod: "web-1", Skipped: true, Message: `skipped: task "smoke" failed`},
+		{Kind: PostUp, Name: "notify", Pass: true},
+	}
+	var out strings.Builder
+	if err := WriteJUnit(&out, results); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="post-deploy" tests="3" failures="1" skipped="1" time="1.750">
+    <testcase name="migrate" classname="post-deploy.web-1" time="1.500">
+      <system-out>migrated &lt;3 tables&gt;&#xA;</system-out>
+    </testcase>
+    <testcase name="smoke" classname="post-deploy.web-1" time="0.250">
+      <failure message="command terminated with exit code 1"></failure>
+    </testcase>
+    <testcase name="report" classname="post-deploy.web-1" time="0.000">
+      <skipped message="skipped: task &#34;smoke&#34; failed"></skipped>
+    </testcase>
+  </testsuite>
+  <testsuite name="post-up" tests="1" failures="0" skipped="0" time="0.000">
+    <testcase name="notify" classname="post-up" time="0.000"></testcase>
+  </testsuite>
+</testsuites>
+`
+	if out.String() != expected {
+		t.Errorf("expected report\n%s\ngot\n%s", expected, out.String())
+	}
+}
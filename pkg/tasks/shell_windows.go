@@ -0,0 +1,8 @@
+// +build windows
+
+package tasks
+
+// shellCommand runs command with the system shell.
+func shellCommand(command string) []string {
+	return []string{"cmd", "/C", command}
+}
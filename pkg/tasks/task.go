@@ -0,0 +1,209 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// CommandTask is the type of tasks running a command, the default.
+	CommandTask = "command"
+	// JobTask is the type of tasks running their command as a Kubernetes Job, from the
+	// image of the build. Only pre-release and post-release tasks can be jobs.
+	JobTask = "job"
+)
+
+// Task is a command run at a point of the application lifecycle.
+type Task struct {
+	// Name identifies the task in depends-on and in results.
+	Name string `toml:"name"`
+	// Type is the type of the task, CommandTask if empty.
+	Type string `toml:"type"`
+	// Command is the command to run. Unless Shell is set, it is split into arguments
+	// like a shell would, honoring quotes, and environment variables ($FOO) are expanded.
+	// The commands of job tasks only expand the variables of Env.
+	Command string `toml:"command"`
+	// Shell runs the command with the system shell.
+	Shell bool `toml:"shell"`
+	// Timeout stops the command if it runs longer. Zero means no timeout.
+	Timeout Duration `toml:"timeout"`
+	// Env sets environment variables for the command.
+	Env map[string]string `toml:"env"`
+	// Dir is the working directory of the command.
+	Dir string `toml:"dir"`
+	// Container is the container post-deploy tasks run in. Defaults to the first
+	// container of the pod.
+	Container string `toml:"container"`
+	// DependsOn names tasks of the same kind that must have succeeded before the task runs.
+	DependsOn []string `toml:"depends-on"`
+	// ContinueOnError runs the remaining tasks if the task fails.
+	ContinueOnError bool `toml:"continue-on-error"`
+}
+
+// Duration is a time.Duration read from a string such as "30s" or "5m".
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) (err error) {
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
+
+// order returns the tasks in the order they run: in the order they are declared, except
+// that tasks run after the tasks they depend on.
+func order(tasks []Task) ([]Task, error) {
+	index := make(map[string]int, len(tasks))
+	for i, t := range tasks {
+		if t.Name == "" {
+			return nil, fmt.Errorf("task %d has no name", i+1)
+		}
+		if _, ok := index[t.Name]; ok {
+			return nil, fmt.Errorf("task %q is declared twice", t.Name)
+		}
+		index[t.Name] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	var (
+		state   = make([]int, len(tasks))
+		ordered = make([]Task, 0, len(tasks))
+		visit   func(i int, path []string) error
+	)
+	visit = func(i int, path []string) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("tasks depend on each other: %s", strings.Join(append(path, tasks[i].Name), " -> "))
+		}
+		state[i] = visiting
+		for _, dep := range tasks[i].DependsOn {
+			j, ok := index[dep]
+			if !ok {
+				return fmt.Errorf("task %q depends on unknown task %q", tasks[i].Name, dep)
+			}
+			if err := visit(j, append(path, tasks[i].Name)); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		ordered = append(ordered, tasks[i])
+		return nil
+	}
+	for i := range tasks {
+		if err := visit(i, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// checkTypes reports tasks of an unknown type, or of a type that tasks of the given
+// kind cannot have.
+func checkTypes(kind string, tasks []Task) error {
+	for _, t := range tasks {
+		switch t.Type {
+		case "", CommandTask:
+		case JobTask:
+			if kind != PreRelease && kind != PostRelease {
+				return fmt.Errorf("task %q is a job; only pre-release and post-release tasks can be jobs", t.Name)
+			}
+		default:
+			return fmt.Errorf("task %q has unknown type %q", t.Name, t.Type)
+		}
+	}
+	return nil
+}
+
+// splitCommand splits a command into arguments like a shell would, honoring single and
+// double quotes and backslash escapes. Environment variables ($FOO) are replaced by their
+// value given by lookup, except within single quotes; $$FOO and \$FOO stand for $FOO.
+func splitCommand(command string, lookup func(name string) string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+		runes   = []rune(command)
+	)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' && r != '$' {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case r == '$' && quote != '\'':
+			inArg = true
+			if i+2 < len(runes) && runes[i+1] == '$' && isNameStart(runes[i+2]) {
+				// $$FOO is kept as $FOO.
+				current.WriteRune('$')
+				i++
+				continue
+			}
+			end := i + 1
+			for end < len(runes) && (isNameStart(runes[end]) || end > i+1 && unicode.IsDigit(runes[end])) {
+				end++
+			}
+			if end == i+1 {
+				current.WriteRune('$')
+				continue
+			}
+			current.WriteString(lookup(string(runes[i+1 : end])))
+			i = end - 1
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, command)
+	}
+	if escaped {
+		return nil, errors.New("command ends with a backslash")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// isNameStart returns whether r may start the name of an environment variable.
+func isNameStart(r rune) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
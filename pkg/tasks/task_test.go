@@ -0,0 +1,124 @@
+package tasks
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected []string
+	}{
+		{"echo hello world", []string{"echo", "hello", "world"}},
+		{"  echo   hello  ", []string{"echo", "hello"}},
+		{`echo "hello world"`, []string{"echo", "hello world"}},
+		{`echo 'it''s' "a \"quote\""`, []string{"echo", "its", `a "quote"`}},
+		{`grep -e 'a b' -e "c\d"`, []string{"grep", "-e", "a b", "-e", `c\d`}},
+		{`echo hello\ world`, []string{"echo", "hello world"}},
+		{`echo \$HOME "\$HOME" $$HOME`, []string{"echo", "$HOME", "$HOME", "$HOME"}},
+		{`echo $HOME "$HOME/bin" '$HOME' $_X1-$`, []string{"echo", "/home/app", "/home/app/bin", "$HOME", "x-$"}},
+		{`sh -c 'echo "$HOME"'`, []string{"sh", "-c", `echo "$HOME"`}},
+		{`echo "a $UNSET b"`, []string{"echo", "a  b"}},
+		{`echo ""`, []string{"echo", ""}},
+		{"", nil},
+	}
+	env := map[string]string{"HOME": "/home/app", "_X1": "x"}
+	lookup := func(name string) string { return env[name] }
+	for _, tt := range tests {
+		actual, err := splitCommand(tt.command, lookup)
+		if err != nil {
+			t.Errorf("splitCommand(%q): %v", tt.command, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("splitCommand(%q): expected %q, got %q", tt.command, tt.expected, actual)
+		}
+	}
+
+	for _, command := range []string{`echo "hello`, `echo 'hello`, `echo \`} {
+		if _, err := splitCommand(command, lookup); err == nil {
+			t.Errorf("splitCommand(%q): expected an error", command)
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	tests := []struct {
+		description string
+		tasks       []Task
+		expected    []string
+		err         bool
+	}{
+		{
+			description: "declaration order",
+			tasks:       []Task{{Name: "c"}, {Name: "a"}, {Name: "b"}},
+			expected:    []string{"c", "a", "b"},
+		},
+		{
+			description: "dependencies first",
+			tasks:       []Task{{Name: "migrate", DependsOn: []string{"db"}}, {Name: "lint"}, {Name: "db", DependsOn: []string{"network"}}, {Name: "network"}},
+			expected:    []string{"network", "db", "migrate", "lint"},
+		},
+		{
+			description: "unknown dependency",
+			tasks:       []Task{{Name: "migrate", DependsOn: []string{"db"}}},
+			err:         true,
+		},
+		{
+			description: "cycle",
+			tasks:       []Task{{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}},
+			err:         true,
+		},
+		{
+			description: "duplicate name",
+			tasks:       []Task{{Name: "a"}, {Name: "a"}},
+			err:         true,
+		},
+		{
+			description: "missing name",
+			tasks:       []Task{{Command: "echo"}},
+			err:         true,
+		},
+	}
+	for _, tt := range tests {
+		ordered, err := order(tt.tasks)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.description)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.description, err)
+			continue
+		}
+		var names []string
+		for _, task := range ordered {
+			names = append(names, task.Name)
+		}
+		if !reflect.DeepEqual(names, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.description, tt.expected, names)
+		}
+	}
+}
+
+func TestTaskArgs(t *testing.T) {
+	os.Setenv("DRAFT_TASK_TEST", "local")
+	defer os.Unsetenv("DRAFT_TASK_TEST")
+	task := Task{Name: "greet", Command: "echo $DRAFT_TASK_TEST $APP", Env: map[string]string{"APP": "web"}}
+
+	for localEnv, expected := range map[bool][]string{
+		false: {"echo", "$DRAFT_TASK_TEST", "web"},
+		true:  {"echo", "local", "web"},
+	} {
+		actual, err := taskArgs(task, true, localEnv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("taskArgs(local env: %v): expected %q, got %q", localEnv, expected, actual)
+		}
+	}
+}
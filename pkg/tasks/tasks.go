@@ -1,12 +1,18 @@
 package tasks
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"regexp"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
@@ -25,13 +31,16 @@ const (
 	PostDeploy = "PostDeploy"
 	// PostDelete are the kind of tasks to be executed after a delete command
 	PostDelete = "PostDelete"
-)
-
-var (
-	// reEnvironmentVariable matches environment variables embedded in
-	// strings. Only simple expressions ($FOO) are supported. Variables
-	// may escaped to avoid interpolation, in the form $$FOO or \$FOO
-	reEnvironmentVariable = regexp.MustCompile(`([\\\$]?\$[a-zA-Z_][a-zA-Z0-9_]*)`)
+	// PreBuild are the kind of tasks to be executed by the builder before the image is built
+	PreBuild = "PreBuild"
+	// PostBuild are the kind of tasks to be executed by the builder after the image is built, before it is pushed
+	PostBuild = "PostBuild"
+	// PreRelease are the kind of tasks to be executed by the builder before the application is released
+	PreRelease = "PreRelease"
+	// PostRelease are the kind of tasks to be executed by the builder after the application is released
+	PostRelease = "PostRelease"
+	// OnFailure are the kind of tasks to be executed by the builder when a build fails
+	OnFailure = "OnFailure"
 )
 
 // Runner runs the given command. An alternative to DefaultRunner can
@@ -41,23 +50,115 @@ type Runner func(c *exec.Cmd) error
 // DefaultRunner runs the given command
 var DefaultRunner = func(c *exec.Cmd) error { return c.Run() }
 
+// JobRunner runs the command of a job task as a Kubernetes Job and writes its logs to
+// out. The environment of the task includes the environment of the run options.
+type JobRunner func(task Task, command []string, out io.Writer) error
+
+// PodExecutor runs a command in a container of a pod, for post-deploy tasks. An empty
+// container stands for the first container of the pod.
+type PodExecutor func(pod, container string, command []string, stdout, stderr io.Writer) error
+
 // Tasks represents the different kinds of tasks read from Tasks' file
 type Tasks struct {
-	PreUp      map[string]string `toml:"pre-up"`
-	PostUp     map[string]string `toml:"post-up"`
-	PostDeploy map[string]string `toml:"post-deploy"`
-	PostDelete map[string]string `toml:"cleanup"`
+	PreUp       []Task
+	PostUp      []Task
+	PostDeploy  []Task
+	PostDelete  []Task
+	PreBuild    []Task
+	PostBuild   []Task
+	PreRelease  []Task
+	PostRelease []Task
+	OnFailure   []Task
+}
+
+// kinds maps the kinds of tasks to their key in the tasks file.
+var kinds = []struct{ kind, key string }{
+	{PreUp, "pre-up"},
+	{PostUp, "post-up"},
+	{PostDeploy, "post-deploy"},
+	{PostDelete, "cleanup"},
+	{PreBuild, "pre-build"},
+	{PostBuild, "post-build"},
+	{PreRelease, "pre-release"},
+	{PostRelease, "post-release"},
+	{OnFailure, "on-failure"},
+}
+
+// Keys returns the keys of the kinds of tasks in a tasks file, such as "pre-up".
+func Keys() []string {
+	keys := make([]string, len(kinds))
+	for i, k := range kinds {
+		keys[i] = k.key
+	}
+	return keys
+}
+
+// KindOf returns the kind of the tasks declared under key in a tasks file.
+func KindOf(key string) (string, bool) {
+	for _, k := range kinds {
+		if k.key == key {
+			return k.kind, true
+		}
+	}
+	return "", false
+}
+
+// KeyOf returns the key in a tasks file of a kind of tasks.
+func KeyOf(kind string) string {
+	for _, k := range kinds {
+		if k.kind == kind {
+			return k.key
+		}
+	}
+	return ""
 }
 
 // Result represents the result of a Task's execution
 type Result struct {
-	Kind    string
+	Kind string
+	Name string
+	// Pod is the pod a post-deploy task ran in.
+	Pod     string
 	Command []string
 	Pass    bool
+	// Skipped is set if the task did not run because a task it depends on failed.
+	Skipped bool
 	Message string
+	// ExitCode is the exit status of the command, or -1 if the command did not exit,
+	// e.g. because it timed out.
+	ExitCode int
+	// Duration is how long the task ran.
+	Duration time.Duration
+	// Output is the output of the command, stdout and stderr combined.
+	Output string
+}
+
+// Options customize how tasks run.
+type Options struct {
+	// Out receives the output of the tasks. If nil, the output goes to the standard
+	// output and error.
+	Out io.Writer
+	// Env is set for every task. The environment of a task takes precedence.
+	Env map[string]string
+	// Exec runs post-deploy tasks in the pod. Post-deploy tasks fail if it is nil.
+	Exec PodExecutor
+	// Job runs job tasks. Job tasks fail if it is nil.
+	Job JobRunner
 }
 
-// Load takes a path to file where tasks are defined and loads them in tasks
+// Load takes a path to file where tasks are defined and loads them in tasks.
+//
+// Each kind of tasks is either an ordered list of tasks:
+//
+//  [[pre-up]]
+//  name = "install database"
+//  command = "helm install mysql --name mysql-service"
+//
+// or, as in earlier versions of Draft, a table of commands by task name, run in the
+// order they are declared and ignoring failures:
+//
+//  [pre-up]
+//  "install database" = "helm install mysql --name mysql-service"
 func Load(path string) (*Tasks, error) {
 	if _, err := os.Stat(path); err != nil {
 		if os.IsNotExist(err) {
@@ -66,60 +167,234 @@ func Load(path string) (*Tasks, error) {
 		return nil, err
 	}
 
-	t := Tasks{}
-	if _, err := toml.DecodeFile(path, &t); err != nil {
+	var raw map[string]toml.Primitive
+	md, err := toml.DecodeFile(path, &raw)
+	if err != nil {
 		return nil, err
 	}
 
+	t := Tasks{}
+	for _, k := range kinds {
+		p, ok := raw[k.key]
+		if !ok {
+			continue
+		}
+		var list []Task
+		switch md.Type(k.key) {
+		case "ArrayHash":
+			if err := md.PrimitiveDecode(p, &list); err != nil {
+				return nil, fmt.Errorf("could not read %s tasks: %v", k.key, err)
+			}
+		case "Hash":
+			var commands map[string]string
+			if err := md.PrimitiveDecode(p, &commands); err != nil {
+				return nil, fmt.Errorf("could not read %s tasks: %v", k.key, err)
+			}
+			// keep the order of declaration.
+			for _, key := range md.Keys() {
+				if len(key) == 2 && key[0] == k.key {
+					list = append(list, Task{Name: key[1], Command: commands[key[1]], ContinueOnError: true})
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%s must be a list of tasks", k.key)
+		}
+		if _, err := order(list); err != nil {
+			return nil, fmt.Errorf("invalid %s tasks: %v", k.key, err)
+		}
+		if err := checkTypes(k.kind, list); err != nil {
+			return nil, fmt.Errorf("invalid %s tasks: %v", k.key, err)
+		}
+		*t.list(k.kind) = list
+	}
+
 	return &t, nil
 }
 
-// Run executes a series of tasks of a given kind and returns the list of results
-func (t *Tasks) Run(runner Runner, kind, podName string) ([]Result, error) {
-	results := []Result{}
+// List returns the tasks of a given kind, in the order they are declared.
+func (t *Tasks) List(kind string) []Task {
+	if t == nil {
+		return nil
+	}
+	if list := t.list(kind); list != nil {
+		return *list
+	}
+	return nil
+}
+
+// InDir makes the working directories of the tasks run on the local machine relative to
+// dir, for a tasks file of an application in another directory than the current one.
+// Post-deploy tasks and job tasks, which run in the cluster, are left as they are.
+func (t *Tasks) InDir(dir string) {
+	if t == nil {
+		return
+	}
+	for _, k := range kinds {
+		if k.kind == PostDeploy {
+			continue
+		}
+		list := *t.list(k.kind)
+		for i := range list {
+			if list[i].Type != JobTask && !filepath.IsAbs(list[i].Dir) {
+				list[i].Dir = filepath.Join(dir, list[i].Dir)
+			}
+		}
+	}
+}
 
+func (t *Tasks) list(kind string) *[]Task {
 	switch kind {
 	case PreUp:
-		for _, task := range t.PreUp {
-			result := executeTask(runner, task, kind)
-			results = append(results, result)
-		}
+		return &t.PreUp
 	case PostUp:
-		for _, task := range t.PostUp {
-			result := executeTask(runner, task, kind)
-			results = append(results, result)
-		}
+		return &t.PostUp
 	case PostDeploy:
-		for _, task := range t.PostDeploy {
-			cmd := preparePostDeployTask(evaluateArgs(task), podName)
-			result := runTask(runner, cmd, kind)
-			results = append(results, result)
-		}
+		return &t.PostDeploy
 	case PostDelete:
-		for _, task := range t.PostDelete {
-			result := executeTask(runner, task, kind)
-			results = append(results, result)
-		}
-	default:
+		return &t.PostDelete
+	case PreBuild:
+		return &t.PreBuild
+	case PostBuild:
+		return &t.PostBuild
+	case PreRelease:
+		return &t.PreRelease
+	case PostRelease:
+		return &t.PostRelease
+	case OnFailure:
+		return &t.OnFailure
+	}
+	return nil
+}
+
+// Run executes a series of tasks of a given kind and returns the list of results.
+//
+// Tasks run in the order they are declared, after the tasks they depend on. If a task fails
+// and does not continue on error, the remaining tasks are skipped and an error is returned.
+// Tasks depending on a failed task are skipped.
+func (t *Tasks) Run(runner Runner, kind, podName string) ([]Result, error) {
+	return t.RunWithOptions(runner, kind, podName, Options{})
+}
+
+// RunWithOptions executes a series of tasks of a given kind like Run does, with their
+// output and environment customized by opts.
+func (t *Tasks) RunWithOptions(runner Runner, kind, podName string, opts Options) ([]Result, error) {
+	results := []Result{}
+	if t == nil {
+		return results, nil
+	}
+
+	list := t.list(kind)
+	if list == nil {
 		return results, fmt.Errorf("Task kind: %s not supported", kind)
 	}
+	ordered, err := order(*list)
+	if err != nil {
+		return results, err
+	}
+
+	failed := make(map[string]bool)
+	for i, task := range ordered {
+		if dep := failedDependency(task, failed); dep != "" {
+			failed[task.Name] = true
+			results = append(results, Result{Kind: kind, Name: task.Name, Skipped: true, Message: fmt.Sprintf("skipped: task %q failed", dep)})
+			continue
+		}
+
+		task.Env = mergeEnv(opts.Env, task.Env)
+		output := &syncBuffer{}
+		stdout, stderr := io.MultiWriter(os.Stdout, output), io.MultiWriter(os.Stderr, output)
+		if opts.Out != nil {
+			// a single writer keeps the order of stdout and stderr.
+			stdout = io.MultiWriter(opts.Out, output)
+			stderr = stdout
+		}
 
+		start := time.Now()
+		var result Result
+		switch {
+		case task.Type == JobTask:
+			result = runJobTask(opts.Job, task, kind, stdout)
+		case kind == PostDeploy:
+			result = runPodTask(opts.Exec, task, podName, stdout, stderr)
+		default:
+			result = runTask(runner, task, kind, stdout, stderr)
+		}
+		result.Duration = time.Since(start)
+		result.Output = output.String()
+		results = append(results, result)
+		if result.Pass {
+			continue
+		}
+		failed[task.Name] = true
+		if !task.ContinueOnError {
+			for _, skipped := range ordered[i+1:] {
+				results = append(results, Result{Kind: kind, Name: skipped.Name, Skipped: true, Message: fmt.Sprintf("skipped: task %q failed", task.Name)})
+			}
+			return results, fmt.Errorf("task %q failed: %s", task.Name, result.Message)
+		}
+	}
+
+	return results, nil
+}
+
+// RunInPods runs the post-deploy tasks in every pod, with opts.Exec, and returns the
+// results of all pods. A failure in a pod does not stop the tasks of the other pods;
+// the returned error lists the pods where tasks failed.
+func (t *Tasks) RunInPods(pods []string, opts Options) ([]Result, error) {
+	results := []Result{}
+	var failures []string
+	for _, pod := range pods {
+		r, err := t.RunWithOptions(nil, PostDeploy, pod, opts)
+		for i := range r {
+			r[i].Pod = pod
+		}
+		results = append(results, r...)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", pod, err))
+		}
+	}
+	if len(failures) > 0 {
+		return results, fmt.Errorf("post-deploy tasks failed in %d of %d pods:\n%s", len(failures), len(pods), strings.Join(failures, "\n"))
+	}
 	return results, nil
 }
 
-func executeTask(runner Runner, task, kind string) Result {
-	args := evaluateArgs(task)
-	cmd := prepareTask(args)
-	return runTask(runner, cmd, kind)
+func failedDependency(task Task, failed map[string]bool) string {
+	for _, dep := range task.DependsOn {
+		if failed[dep] {
+			return dep
+		}
+	}
+	return ""
 }
 
-func runTask(runner Runner, cmd *exec.Cmd, kind string) Result {
-	result := Result{Kind: kind, Pass: false}
+func runTask(runner Runner, task Task, kind string, stdout, stderr io.Writer) Result {
+	result := Result{Kind: kind, Name: task.Name, Pass: false, ExitCode: -1}
+
+	args, err := taskArgs(task, false, true)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	ctx := context.Background()
+	if task.Timeout.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, task.Timeout.Duration)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = task.Dir
+	cmd.Env = taskEnv(task.Env)
 	result.Command = append([]string{cmd.Path}, cmd.Args[0:]...)
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	err := runner(cmd)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	err = runner(cmd)
+	if ctx.Err() == context.DeadlineExceeded {
+		result.Message = fmt.Sprintf("timed out after %s", task.Timeout.Duration)
+		return result
+	}
+	result.ExitCode = exitCode(err)
 	if err != nil {
 		result.Pass = false
 		result.Message = err.Error()
@@ -130,32 +405,181 @@ func runTask(runner Runner, cmd *exec.Cmd, kind string) Result {
 	return result
 }
 
-func prepareTask(args []string) *exec.Cmd {
-	var cmd *exec.Cmd
-	if len(args) < 2 {
-		cmd = exec.Command(args[0])
-	} else {
-		cmd = exec.Command(args[0], args[1:]...)
+// runPodTask runs a post-deploy task in a container of the given pod.
+func runPodTask(podExec PodExecutor, task Task, podName string, stdout, stderr io.Writer) Result {
+	result := Result{Kind: PostDeploy, Name: task.Name, Pod: podName, Pass: false, ExitCode: -1}
+	if podExec == nil {
+		result.Message = "post-deploy tasks cannot run without a pod executor"
+		return result
+	}
+
+	args, err := taskArgs(task, true, true)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.Command = containerArgs(task, args)
+
+	// the exec session cannot be cancelled, so on timeout the command is left to finish
+	// in the container.
+	done := make(chan error, 1)
+	go func() {
+		done <- podExec(podName, task.Container, result.Command, stdout, stderr)
+	}()
+	var timeout <-chan time.Time
+	if task.Timeout.Duration > 0 {
+		timer := time.NewTimer(task.Timeout.Duration)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case err = <-done:
+	case <-timeout:
+		result.Message = fmt.Sprintf("timed out after %s", task.Timeout.Duration)
+		return result
+	}
+	result.ExitCode = exitCode(err)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.Pass = true
+
+	return result
+}
+
+// runJobTask runs a job task with runJob.
+func runJobTask(runJob JobRunner, task Task, kind string, out io.Writer) Result {
+	result := Result{Kind: kind, Name: task.Name, Pass: false, ExitCode: -1}
+	if runJob == nil {
+		result.Message = "job tasks can only run during draft up"
+		return result
+	}
+
+	args, err := taskArgs(task, true, false)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.Command = args
+
+	if err := runJob(task, args, out); err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.Pass = true
+	result.ExitCode = 0
+
+	return result
+}
+
+// exitCode returns the exit status of a command from the error it returned: 0 if there is
+// no error, -1 if the error carries no exit status.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
 	}
-	return cmd
+	var status interface{ ExitStatus() int }
+	if errors.As(err, &status) {
+		return status.ExitStatus()
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
 }
 
-func preparePostDeployTask(args []string, podName string) *exec.Cmd {
-	args = append([]string{"exec", podName, "--"}, args[0:]...)
-	return exec.Command("kubectl", args[0:]...)
+// syncBuffer is a bytes.Buffer safe for concurrent writes, capturing the stdout and
+// stderr of a command.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
 }
 
-func evaluateArgs(task string) []string {
-	args := strings.Split(task, " ")
-	for i, arg := range args {
-		args[i] = reEnvironmentVariable.ReplaceAllStringFunc(arg, func(expr string) string {
-			// $$FOO and \$FOO are kept as-is
-			if strings.HasPrefix(expr, "$$") || strings.HasPrefix(expr, "\\$") {
-				return expr[1:]
-			}
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// taskArgs returns the command of a task and its arguments. Post-deploy and job tasks run
+// in a container, where the shell is sh.
+//
+// The variables of the command are looked up in the env of the task, then, if localEnv is
+// set, in the environment of draft. Otherwise they are left as they are: job tasks run in
+// a Job of their own, whose environment draft does not know, and use shell to expand its
+// variables. Post-deploy tasks have always expanded the environment of draft.
+func taskArgs(task Task, inContainer, localEnv bool) ([]string, error) {
+	if task.Shell && inContainer {
+		return []string{"sh", "-c", task.Command}, nil
+	}
+	if task.Shell {
+		return shellCommand(task.Command), nil
+	}
+	args, err := splitCommand(task.Command, func(name string) string {
+		if v, ok := task.Env[name]; ok {
+			return v
+		}
+		if !localEnv {
+			return "$" + name
+		}
+		return os.Getenv(name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("task %q has no command", task.Name)
+	}
+	return args, nil
+}
 
-			return os.Getenv(expr[1:])
-		})
+// containerArgs sets up the environment and working directory of the task in the
+// container before running its command.
+func containerArgs(task Task, args []string) []string {
+	if len(task.Env) > 0 {
+		args = append(append([]string{"env"}, envList(task.Env)...), args...)
+	}
+	if task.Dir != "" {
+		args = append([]string{"sh", "-c", `cd "$0" && exec "$@"`, task.Dir}, args...)
 	}
 	return args
 }
+
+func taskEnv(env map[string]string) []string {
+	if len(env) == 0 {
+		return nil
+	}
+	return append(os.Environ(), envList(env)...)
+}
+
+// mergeEnv returns the variables of base overridden by those of env.
+func mergeEnv(base, env map[string]string) map[string]string {
+	if len(base) == 0 {
+		return env
+	}
+	merged := make(map[string]string, len(base)+len(env))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range env {
+		merged[k] = v
+	}
+	return merged
+}
+
+func envList(env map[string]string) []string {
+	list := make([]string, 0, len(env))
+	for k, v := range env {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -1,11 +1,18 @@
 package tasks
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -24,6 +31,115 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestLoadOrderedTasks(t *testing.T) {
+	tasksFile, err := Load(filepath.Join("testdata", "orderedTasks.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Task{
+		{Name: "migrate", Command: "rake db:migrate", DependsOn: []string{"install database"}, Timeout: Duration{5 * time.Minute}},
+		{Name: "install database", Command: "helm install mysql --name mysql-service", Env: map[string]string{"MYSQL_ROOT_PASSWORD": "secret"}, Dir: "deploy"},
+		{Name: "notify", Command: `curl -s -X POST "$WEBHOOK" -d 'deploying'`, Shell: true, ContinueOnError: true},
+	}
+	if !reflect.DeepEqual(tasksFile.PreUp, expected) {
+		t.Errorf("expected pre-up tasks %+v, got %+v", expected, tasksFile.PreUp)
+	}
+
+	// tasks in the earlier format keep their order and continue on error.
+	expected = []Task{
+		{Name: "second", Command: "echo second", ContinueOnError: true},
+		{Name: "first", Command: "echo first", ContinueOnError: true},
+	}
+	if !reflect.DeepEqual(tasksFile.PostDeploy, expected) {
+		t.Errorf("expected post-deploy tasks %+v, got %+v", expected, tasksFile.PostDeploy)
+	}
+
+	expected = []Task{{Name: "smoke test", Command: "./smoke-test.sh $DRAFT_IMAGE"}}
+	if !reflect.DeepEqual(tasksFile.List(PostRelease), expected) {
+		t.Errorf("expected post-release tasks %+v, got %+v", expected, tasksFile.List(PostRelease))
+	}
+	if len(tasksFile.OnFailure) != 1 || len(tasksFile.PreBuild) != 0 {
+		t.Errorf("expected 1 on-failure task and no pre-build tasks, got %+v and %+v", tasksFile.OnFailure, tasksFile.PreBuild)
+	}
+}
+
+func TestLoadInvalidDependencies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "draft-tasks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tasks.toml")
+	content := `[[pre-up]]
+name = "a"
+command = "echo a"
+depends-on = ["b"]
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected an error for a dependency on an unknown task")
+	}
+}
+
+func TestLoadTaskTypes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "draft-tasks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		content string
+		valid   bool
+	}{
+		{"[[pre-release]]\nname = \"migrate\"\ntype = \"job\"\ncommand = \"rake db:migrate\"\n", true},
+		{"[[post-release]]\nname = \"smoke\"\ntype = \"command\"\ncommand = \"./smoke.sh\"\n", true},
+		{"[[post-deploy]]\nname = \"migrate\"\ntype = \"job\"\ncommand = \"rake db:migrate\"\n", false},
+		{"[[pre-release]]\nname = \"migrate\"\ntype = \"cronjob\"\ncommand = \"rake db:migrate\"\n", false},
+	}
+	for i, tc := range cases {
+		path := filepath.Join(dir, fmt.Sprintf("tasks%d.toml", i))
+		if err := ioutil.WriteFile(path, []byte(tc.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := Load(path)
+		if tc.valid && err != nil {
+			t.Errorf("expected %q to be valid, got %v", tc.content, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("expected an error for %q", tc.content)
+		}
+	}
+}
+
+func TestInDir(t *testing.T) {
+	abs, err := filepath.Abs("bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskList := &Tasks{
+		PreUp:       []Task{{Name: "deps", Command: "npm ci"}, {Name: "gen", Command: "make", Dir: "gen"}, {Name: "abs", Command: "ls", Dir: abs}},
+		PostDeploy:  []Task{{Name: "check", Command: "ls", Dir: "/app"}},
+		PostRelease: []Task{{Name: "smoke", Type: JobTask, Command: "./smoke.sh", Dir: "test"}},
+	}
+	taskList.InDir(filepath.Join("services", "api"))
+
+	expected := []string{filepath.Join("services", "api"), filepath.Join("services", "api", "gen"), abs}
+	for i, task := range taskList.PreUp {
+		if task.Dir != expected[i] {
+			t.Errorf("expected task %s to run in %q, got %q", task.Name, expected[i], task.Dir)
+		}
+	}
+	if dir := taskList.PostDeploy[0].Dir; dir != "/app" {
+		t.Errorf("expected post-deploy tasks to be left as they are, got %q", dir)
+	}
+	if dir := taskList.PostRelease[0].Dir; dir != "test" {
+		t.Errorf("expected job tasks to be left as they are, got %q", dir)
+	}
+}
+
 func TestLoadError(t *testing.T) {
 	_, err := Load(filepath.Join("testdata", "nonexistent.yaml"))
 	if err == nil {
@@ -74,8 +190,8 @@ func TestRun_Interpolation(t *testing.T) {
 		{
 			description: "PreUp with environment variable",
 			tasks: &Tasks{
-				PreUp: map[string]string{
-					"echo": "echo $DRAFT_HELLO",
+				PreUp: []Task{
+					{Name: "echo", Command: "echo $DRAFT_HELLO"},
 				},
 			},
 			kind:        PreUp,
@@ -84,19 +200,19 @@ func TestRun_Interpolation(t *testing.T) {
 		{
 			description: "PostDeploy with environment variable",
 			tasks: &Tasks{
-				PostDeploy: map[string]string{
-					"echo": "echo $DRAFT_HELLO",
+				PostDeploy: []Task{
+					{Name: "echo", Command: "echo $DRAFT_HELLO"},
 				},
 			},
 			kind:        PostDeploy,
 			podName:     "pod-1234",
-			expectedCmd: []string{"kubectl", "exec", "pod-1234", "--", "echo", "hello"},
+			expectedCmd: []string{"echo", "hello"},
 		},
 		{
 			description: "PostDelete with environment variable",
 			tasks: &Tasks{
-				PostDelete: map[string]string{
-					"echo": "echo $DRAFT_HELLO",
+				PostDelete: []Task{
+					{Name: "echo", Command: "echo $DRAFT_HELLO"},
 				},
 			},
 			kind:        PostDelete,
@@ -105,8 +221,8 @@ func TestRun_Interpolation(t *testing.T) {
 		{
 			description: "PreUp with complicated interpolation",
 			tasks: &Tasks{
-				PreUp: map[string]string{
-					"echo": "echo $DRAFT_HELLO/$DRAFT_HELLO",
+				PreUp: []Task{
+					{Name: "echo", Command: "echo $DRAFT_HELLO/$DRAFT_HELLO"},
 				},
 			},
 			kind:        PreUp,
@@ -115,8 +231,8 @@ func TestRun_Interpolation(t *testing.T) {
 		{
 			description: "PreUp with escaped variables",
 			tasks: &Tasks{
-				PreUp: map[string]string{
-					"echo": "echo $DRAFT_HELLO/$$DRAFT_HELLO/\\$DRAFT_HELLO",
+				PreUp: []Task{
+					{Name: "echo", Command: "echo $DRAFT_HELLO/$$DRAFT_HELLO/\\$DRAFT_HELLO"},
 				},
 			},
 			kind:        PreUp,
@@ -131,8 +247,12 @@ func TestRun_Interpolation(t *testing.T) {
 				got = cmd.Args
 				return nil
 			}
+			podExec := func(pod, container string, command []string, stdout, stderr io.Writer) error {
+				got = command
+				return nil
+			}
 
-			_, err := tc.tasks.Run(runner, tc.kind, tc.podName)
+			_, err := tc.tasks.RunWithOptions(runner, tc.kind, tc.podName, Options{Exec: podExec})
 			if err != nil {
 				t.Fatal(err)
 			} else if !reflect.DeepEqual(got, tc.expectedCmd) {
@@ -141,3 +261,266 @@ func TestRun_Interpolation(t *testing.T) {
 		})
 	}
 }
+
+func TestRunOrderAndFailures(t *testing.T) {
+	taskList := &Tasks{
+		PreUp: []Task{
+			{Name: "migrate", Command: "migrate", DependsOn: []string{"database"}},
+			{Name: "lint", Command: "lint", ContinueOnError: true},
+			{Name: "database", Command: "database", ContinueOnError: true},
+			{Name: "build", Command: "build"},
+			{Name: "test", Command: "test"},
+		},
+	}
+
+	var ran []string
+	runner := func(cmd *exec.Cmd) error {
+		ran = append(ran, cmd.Args[0])
+		switch cmd.Args[0] {
+		case "lint", "database", "build":
+			return errors.New("exit status 1")
+		}
+		return nil
+	}
+
+	results, err := taskList.Run(runner, PreUp, "")
+	if err == nil || !strings.Contains(err.Error(), `"build"`) {
+		t.Errorf("expected the failed build to stop the tasks, got %v", err)
+	}
+	if expected := []string{"database", "lint", "build"}; !reflect.DeepEqual(ran, expected) {
+		t.Errorf("expected tasks %v to run, got %v", expected, ran)
+	}
+
+	expected := []struct {
+		name    string
+		pass    bool
+		skipped bool
+	}{
+		{"database", false, false},
+		{"migrate", false, true},
+		{"lint", false, false},
+		{"build", false, false},
+		{"test", false, true},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %+v", len(expected), results)
+	}
+	for i, e := range expected {
+		r := results[i]
+		if r.Name != e.name || r.Pass != e.pass || r.Skipped != e.skipped {
+			t.Errorf("result %d: expected %+v, got %+v", i, e, r)
+		}
+	}
+}
+
+func TestRunEnvDirAndShell(t *testing.T) {
+	os.Setenv("DRAFT_HELLO", "hello")
+	defer os.Unsetenv("DRAFT_HELLO")
+
+	var got *exec.Cmd
+	runner := func(cmd *exec.Cmd) error {
+		got = cmd
+		return nil
+	}
+
+	task := Task{Name: "greet", Command: `echo "$GREETING, $DRAFT_HELLO"`, Env: map[string]string{"GREETING": "hi there"}, Dir: "scripts"}
+	if _, err := (&Tasks{PreUp: []Task{task}}).Run(runner, PreUp, ""); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"echo", "hi there, hello"}; !reflect.DeepEqual(got.Args, expected) {
+		t.Errorf("expected args %q, got %q", expected, got.Args)
+	}
+	if got.Dir != "scripts" {
+		t.Errorf("expected the task to run in scripts, got %q", got.Dir)
+	}
+	if !contains(got.Env, "GREETING=hi there") || !contains(got.Env, "DRAFT_HELLO=hello") {
+		t.Errorf("expected the task environment to be set, got %v", got.Env)
+	}
+
+	task = Task{Name: "pipe", Command: "ls | wc -l", Shell: true}
+	if _, err := (&Tasks{PreUp: []Task{task}}).Run(runner, PreUp, ""); err != nil {
+		t.Fatal(err)
+	}
+	if expected := shellCommand("ls | wc -l"); !reflect.DeepEqual(got.Args, expected) {
+		t.Errorf("expected args %q, got %q", expected, got.Args)
+	}
+
+	var gotPod, gotContainer string
+	var gotCommand []string
+	podExec := func(pod, container string, command []string, stdout, stderr io.Writer) error {
+		gotPod, gotContainer, gotCommand = pod, container, command
+		return nil
+	}
+	task = Task{Name: "seed", Command: "rake db:seed", Env: map[string]string{"RAILS_ENV": "development"}, Dir: "/app", Container: "web"}
+	if _, err := (&Tasks{PostDeploy: []Task{task}}).RunWithOptions(runner, PostDeploy, "pod-1234", Options{Exec: podExec}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"sh", "-c", `cd "$0" && exec "$@"`, "/app", "env", "RAILS_ENV=development", "rake", "db:seed"}
+	if !reflect.DeepEqual(gotCommand, expected) {
+		t.Errorf("expected command %q, got %q", expected, gotCommand)
+	}
+	if gotPod != "pod-1234" || gotContainer != "web" {
+		t.Errorf("expected the task to run in container web of pod-1234, got %q of %q", gotContainer, gotPod)
+	}
+}
+
+func TestRunInPods(t *testing.T) {
+	podExec := func(pod, container string, command []string, stdout, stderr io.Writer) error {
+		if pod == "pod-2" && command[0] == "migrate" {
+			return errors.New("command terminated with exit code 1")
+		}
+		fmt.Fprintf(stdout, "%s in %s\n", command[0], pod)
+		return nil
+	}
+	taskList := &Tasks{
+		PostDeploy: []Task{
+			{Name: "migrate", Command: "migrate"},
+			{Name: "seed", Command: "seed"},
+		},
+	}
+	var out strings.Builder
+	results, err := taskList.RunInPods([]string{"pod-1", "pod-2", "pod-3"}, Options{Out: &out, Exec: podExec})
+	if err == nil || !strings.Contains(err.Error(), "failed in 1 of 3 pods") || !strings.Contains(err.Error(), "pod-2: task \"migrate\" failed") {
+		t.Errorf("expected the failure in pod-2 to be reported, got %v", err)
+	}
+
+	type outcome struct {
+		pod, name     string
+		pass, skipped bool
+	}
+	var got []outcome
+	for _, r := range results {
+		got = append(got, outcome{r.Pod, r.Name, r.Pass, r.Skipped})
+	}
+	expected := []outcome{
+		{"pod-1", "migrate", true, false},
+		{"pod-1", "seed", true, false},
+		{"pod-2", "migrate", false, false},
+		{"pod-2", "seed", false, true},
+		{"pod-3", "migrate", true, false},
+		{"pod-3", "seed", true, false},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected results %+v, got %+v", expected, got)
+	}
+	if !strings.Contains(out.String(), "seed in pod-3") {
+		t.Errorf("expected the output of the tasks, got %q", out.String())
+	}
+}
+
+func TestRunWithOptions(t *testing.T) {
+	var got *exec.Cmd
+	runner := func(cmd *exec.Cmd) error {
+		got = cmd
+		_, err := cmd.Stdout.Write([]byte("done\n"))
+		return err
+	}
+
+	taskList := &Tasks{
+		PostRelease: []Task{{Name: "smoke test", Command: "./smoke-test.sh $DRAFT_IMAGE $DRAFT_ENV", Env: map[string]string{"DRAFT_ENV": "staging"}}},
+	}
+	var out strings.Builder
+	opts := Options{Out: &out, Env: map[string]string{"DRAFT_IMAGE": "app:1234", "DRAFT_ENV": "development"}}
+	if _, err := taskList.RunWithOptions(runner, PostRelease, "", opts); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"./smoke-test.sh", "app:1234", "staging"}; !reflect.DeepEqual(got.Args, expected) {
+		t.Errorf("expected args %q, got %q", expected, got.Args)
+	}
+	if !contains(got.Env, "DRAFT_IMAGE=app:1234") || !contains(got.Env, "DRAFT_ENV=staging") {
+		t.Errorf("expected the task environment to override the options, got %v", got.Env)
+	}
+	if out.String() != "done\n" {
+		t.Errorf("expected the output to be written to the options writer, got %q", out.String())
+	}
+	if taskList.PostRelease[0].Env["DRAFT_IMAGE"] != "" {
+		t.Errorf("expected the task not to be modified, got %v", taskList.PostRelease[0].Env)
+	}
+}
+
+func TestRunJobTasks(t *testing.T) {
+	taskList := &Tasks{
+		PreRelease: []Task{
+			{Name: "migrate", Type: JobTask, Command: "rake db:migrate", Env: map[string]string{"RAILS_ENV": "production"}},
+			{Name: "seed", Type: JobTask, Command: "rake db:seed", DependsOn: []string{"migrate"}},
+		},
+	}
+	var got []Task
+	runJob := func(task Task, command []string, out io.Writer) error {
+		got = append(got, task)
+		if task.Name == "seed" {
+			return errors.New("job myapp-seed-01abc failed: BackoffLimitExceeded")
+		}
+		return nil
+	}
+	opts := Options{Env: map[string]string{"DRAFT_BUILD_ID": "01ABC"}, Job: runJob}
+	results, err := taskList.RunWithOptions(DefaultRunner, PreRelease, "", opts)
+	if err == nil || !strings.Contains(err.Error(), "BackoffLimitExceeded") {
+		t.Errorf("expected the failing job to fail the tasks, got %v", err)
+	}
+	if len(got) != 2 || got[0].Env["DRAFT_BUILD_ID"] != "01ABC" || got[0].Env["RAILS_ENV"] != "production" {
+		t.Errorf("expected the jobs to run with the task and build environment, got %+v", got)
+	}
+	if !results[0].Pass || results[1].Pass {
+		t.Errorf("expected migrate to pass and seed to fail, got %+v", results)
+	}
+
+	// job tasks cannot run outside of a build.
+	results, err = taskList.Run(DefaultRunner, PreRelease, "")
+	if err == nil || results[0].Pass {
+		t.Errorf("expected job tasks to fail without a job runner, got %+v", results)
+	}
+}
+
+func TestRunCapturesResults(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the task uses sh")
+	}
+	taskList := &Tasks{
+		PostUp: []Task{{Name: "check", Command: "echo out; echo err >&2; exit 3", Shell: true, ContinueOnError: true}},
+	}
+	var out strings.Builder
+	results, err := taskList.RunWithOptions(DefaultRunner, PostUp, "", Options{Out: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results[0]
+	if r.Pass || r.ExitCode != 3 {
+		t.Errorf("expected the task to fail with exit code 3, got %+v", r)
+	}
+	if r.Output != "out\nerr\n" || out.String() != r.Output {
+		t.Errorf("expected the output to be captured and written, got %q and %q", r.Output, out.String())
+	}
+	if r.Duration <= 0 {
+		t.Errorf("expected the duration of the task, got %s", r.Duration)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sleep is not available on windows")
+	}
+	taskList := &Tasks{
+		PreUp: []Task{{Name: "slow", Command: "sleep 5", Timeout: Duration{100 * time.Millisecond}}},
+	}
+	start := time.Now()
+	results, err := taskList.Run(DefaultRunner, PreUp, "")
+	if err == nil {
+		t.Fatal("expected the task to time out")
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("expected the task to be stopped after its timeout")
+	}
+	if !strings.Contains(results[0].Message, "timed out") {
+		t.Errorf("expected a timeout message, got %q", results[0].Message)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}